@@ -0,0 +1,118 @@
+// jwtsign выпускает JWT локальным ключом, чтобы проверить JWT-аутентификацию без SSO:
+//
+//	openssl ecparam -name prime256v1 -genkey -noout | openssl pkcs8 -topk8 -nocrypt -out jwt.key
+//	openssl ec -in jwt.key -pubout -out jwt.pub
+//	JWT_KEY_FILE=jwt.pub ./server ...
+//	go run ./cmd/jwtsign -key jwt.key -role user -user u1
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func main() {
+	keyFile := flag.String("key", "", "private key (PEM, RSA or P-256) or HMAC secret file")
+	kid := flag.String("kid", "", "kid header, required if the server uses a JWKS with several keys")
+	role := flag.String("role", "admin", "role claim: admin, user, service")
+	userID := flag.String("user", "", "user ID claim (required for role user)")
+	subject := flag.String("sub", "", "sub claim (defaults to -user)")
+	issuer := flag.String("iss", "", "iss claim")
+	audience := flag.String("aud", "", "aud claim")
+	userClaim := flag.String("user-claim", "sub", "claim that carries the user ID")
+	roleClaim := flag.String("role-claim", "role", "claim that carries the role")
+	ttl := flag.Duration("ttl", time.Hour, "token lifetime")
+	flag.Parse()
+
+	token, err := sign(*keyFile, *kid, claims(*role, *userID, *subject, *issuer, *audience, *userClaim, *roleClaim, *ttl))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "jwtsign:", err)
+		os.Exit(1)
+	}
+	fmt.Println(token)
+}
+
+func claims(role, userID, subject, issuer, audience, userClaim, roleClaim string, ttl time.Duration) jwt.MapClaims {
+	now := time.Now()
+	if subject == "" {
+		subject = userID
+	}
+	if subject == "" {
+		subject = "jwtsign"
+	}
+
+	result := jwt.MapClaims{
+		"sub":     subject,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+		roleClaim: role,
+	}
+	if userID != "" {
+		result[userClaim] = userID
+	}
+	if issuer != "" {
+		result["iss"] = issuer
+	}
+	if audience != "" {
+		result["aud"] = audience
+	}
+	return result
+}
+
+func sign(keyFile, kid string, claims jwt.MapClaims) (string, error) {
+	if keyFile == "" {
+		return "", errors.New("-key is required")
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return "", err
+	}
+	data = bytes.TrimSpace(data)
+
+	var method jwt.SigningMethod
+	var key any
+	if block, _ := pem.Decode(data); block != nil {
+		key, err = parsePrivateKey(block)
+		if err != nil {
+			return "", err
+		}
+		switch key.(type) {
+		case *rsa.PrivateKey:
+			method = jwt.SigningMethodRS256
+		case *ecdsa.PrivateKey:
+			method = jwt.SigningMethodES256
+		default:
+			return "", fmt.Errorf("unsupported private key type %T", key)
+		}
+	} else {
+		method, key = jwt.SigningMethodHS256, data
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	return token.SignedString(key)
+}
+
+func parsePrivateKey(block *pem.Block) (any, error) {
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q, expected a private key", block.Type)
+}
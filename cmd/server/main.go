@@ -1,17 +1,195 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
+	"antonvedaet/internship_task/internal/config"
 	routes "antonvedaet/internship_task/internal/http"
+	"antonvedaet/internship_task/internal/logging"
+	"antonvedaet/internship_task/internal/notify"
+	"antonvedaet/internship_task/internal/outbox"
+	"antonvedaet/internship_task/internal/service"
+	"antonvedaet/internship_task/internal/store"
+	"antonvedaet/internship_task/internal/stream"
+	"antonvedaet/internship_task/internal/tracing"
+	"antonvedaet/internship_task/internal/webhook"
 )
 
 func main() {
+	if err := run(); err != nil {
+		slog.Error("server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger := logging.New(os.Stdout, cfg.Log.SlogLevel())
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("set up tracing: %w", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	db, err := store.New(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("connect to database: %w", err)
+	}
+	defer db.Close()
+
+	healthService := service.NewHealthService(db)
+	tokenService := service.NewTokenService(db, logger)
+
+	if cfg.Auth.Enabled && cfg.Auth.BootstrapToken != "" {
+		if err := tokenService.EnsureBootstrapToken(context.Background(), cfg.Auth.BootstrapToken); err != nil {
+			return fmt.Errorf("register bootstrap token: %w", err)
+		}
+	}
+	if !cfg.Auth.Enabled {
+		logger.Warn("authentication is disabled")
+	}
+
+	var hub *stream.Hub
+	var streamService service.StreamService
+	if cfg.Stream.Enabled {
+		listener, err := store.NewOutboxListener(cfg.Database, func(event pq.ListenerEventType, err error) {
+			switch event {
+			case pq.ListenerEventDisconnected:
+				logger.Warn("outbox listener disconnected", "error", err)
+			case pq.ListenerEventReconnected:
+				logger.Info("outbox listener reconnected")
+			case pq.ListenerEventConnectionAttemptFailed:
+				logger.Warn("outbox listener reconnect failed", "error", err)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("listen for outbox events: %w", err)
+		}
+		hub = stream.NewHub(db, listener, logger)
+		streamService = service.NewStreamService(db, hub)
+	}
+
+	handler, err := routes.MakeMux(cfg, db, healthService, tokenService, streamService, logger)
+	if err != nil {
+		return fmt.Errorf("build routes: %w", err)
+	}
+
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	if hub != nil {
+		// Shutdown не ждёт открытых SSE-потоков: хаб закрывает их, клиенты переподключаются к другой реплике
+		server.RegisterOnShutdown(hub.Close)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// фоновые воркеры живут дольше HTTP-сервера: доставки продолжаются, пока сервер дожидается запросов
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	// и на выходе с ошибкой воркеры останавливаются до закрытия БД
+	defer func() {
+		stopWorkers()
+		workers.Wait()
+	}()
+	if cfg.Webhooks.Enabled {
+		dispatcher := webhook.NewDispatcher(db, cfg.Webhooks, logger)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			dispatcher.Run(workersCtx)
+		}()
+	}
+	if hub != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			hub.Run(workersCtx)
+		}()
+	}
+	if cfg.Notifications.Enabled {
+		dispatcher := notify.NewDispatcher(db, cfg.Notifications, logger)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			dispatcher.Run(workersCtx)
+		}()
+	}
+	if cfg.Notifications.OverdueAfter > 0 {
+		checker := notify.NewOverdueChecker(db, cfg.Notifications, logger)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			checker.Run(workersCtx)
+		}()
+	}
+	if cfg.Outbox.Enabled {
+		dispatcher := outbox.NewDispatcher(db, outbox.NewSinks(cfg.Outbox, cfg.Integrations, db, logger), cfg.Outbox, logger)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			dispatcher.Run(workersCtx)
+		}()
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("server starting", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// сначала /ready начинает отвечать 503, и только после паузы сервер перестаёт принимать соединения,
+	// чтобы балансировщик успел убрать инстанс из ротации
+	logger.Info("shutting down", "drain_delay", cfg.Server.ShutdownDrainDelay, "shutdown_timeout", cfg.Server.ShutdownTimeout)
+	healthService.SetShuttingDown()
+	time.Sleep(cfg.Server.ShutdownDrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown server: %w", err)
+	}
+	if err := <-serverErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	stopWorkers()
+	workers.Wait()
 
-	mux := routes.MakeMux()
-	log.Println("Server starting on :8080")
-	log.Fatal(http.ListenAndServe(":8080", mux))
+	logger.Info("server stopped")
+	return nil
 }
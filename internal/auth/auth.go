@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+const (
+	RoleAdmin   = "admin"
+	RoleUser    = "user"
+	RoleService = "service"
+)
+
+// tokenPrefix помогает распознать токен сервиса в логах и сканерах секретов
+const tokenPrefix = "prr_"
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *models.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom возвращает вызывающего, nil - если аутентификация выключена
+func PrincipalFrom(ctx context.Context) *models.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*models.Principal)
+	return principal
+}
+
+func ValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleUser, RoleService:
+		return true
+	}
+	return false
+}
+
+// GenerateToken создаёт новый токен и его хеш для хранения в базе
+func GenerateToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = tokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken - токены случайные и длинные, поэтому медленный KDF не нужен
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewTokenID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "tok_" + hex.EncodeToString(buf), nil
+}
+
+// Actor - идентификатор вызывающего для логов: пользователь, если токен к нему привязан
+func Actor(ctx context.Context) string {
+	principal := PrincipalFrom(ctx)
+	switch {
+	case principal == nil:
+		return ""
+	case principal.UserID != "":
+		return principal.UserID
+	case principal.TokenID != "":
+		return principal.TokenID
+	}
+	return principal.Name
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/models"
+)
+
+// SupportedJWTAlgorithms - алгоритмы, которые подписывает внутренний SSO
+var SupportedJWTAlgorithms = []string{"HS256", "RS256", "ES256"}
+
+// rolePriority - если SSO передаёт список ролей, берётся самая сильная
+var rolePriority = []string{RoleAdmin, RoleService, RoleUser}
+
+// JWTVerifier проверяет подпись и стандартные claims JWT и переводит их в Principal
+type JWTVerifier struct {
+	keys      KeySource
+	parser    *jwt.Parser
+	userClaim string
+	roleClaim string
+}
+
+func NewJWTVerifier(cfg config.JWTConfig) (*JWTVerifier, error) {
+	var keys KeySource
+	if cfg.JWKSURL != "" {
+		keys = NewJWKSSource(cfg.JWKSURL, cfg.JWKSRefresh)
+	} else {
+		var err error
+		if keys, err = LoadKeyFile(cfg.KeyFile); err != nil {
+			return nil, err
+		}
+	}
+	return NewJWTVerifierWithKeys(keys, cfg), nil
+}
+
+func NewJWTVerifierWithKeys(keys KeySource, cfg config.JWTConfig) *JWTVerifier {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(SupportedJWTAlgorithms),
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &JWTVerifier{
+		keys:      keys,
+		parser:    jwt.NewParser(options...),
+		userClaim: cfg.UserClaim,
+		roleClaim: cfg.RoleClaim,
+	}
+}
+
+// LooksLikeJWT отличает JWT (header.payload.signature) от статических токенов
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*models.Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid, t.Method.Alg())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := claimRole(claims[v.roleClaim])
+	if err != nil {
+		return nil, fmt.Errorf("claim %q: %w", v.roleClaim, err)
+	}
+	userID, _ := claims[v.userClaim].(string)
+	if role == RoleUser && userID == "" {
+		return nil, fmt.Errorf("claim %q is required for role user", v.userClaim)
+	}
+
+	subject, _ := claims.GetSubject()
+	tokenID, _ := claims["jti"].(string)
+	return &models.Principal{
+		TokenID: tokenID,
+		Name:    subject,
+		Role:    role,
+		UserID:  userID,
+	}, nil
+}
+
+func claimRole(value any) (string, error) {
+	switch value := value.(type) {
+	case string:
+		if ValidRole(value) {
+			return value, nil
+		}
+		return "", fmt.Errorf("unknown role %q", value)
+	case []any:
+		for _, role := range rolePriority {
+			for _, item := range value {
+				if item == role {
+					return role, nil
+				}
+			}
+		}
+		return "", errors.New("no known role")
+	}
+	return "", errors.New("missing role")
+}
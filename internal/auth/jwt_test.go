@@ -0,0 +1,381 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"antonvedaet/internship_task/internal/config"
+)
+
+var testJWTConfig = config.JWTConfig{
+	JWKSRefresh: 10 * time.Minute,
+	Issuer:      "https://sso.example.com",
+	Audience:    "pr-reviewer",
+	UserClaim:   "sub",
+	RoleClaim:   "role",
+	Leeway:      5 * time.Second,
+}
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func generateECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func encodeBigInt(n *big.Int, size int) string {
+	return base64.RawURLEncoding.EncodeToString(n.FillBytes(make([]byte, size)))
+}
+
+func publicJWK(t *testing.T, kid string, key crypto.Signer) map[string]string {
+	t.Helper()
+
+	switch pub := key.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA", "kid": kid, "alg": "RS256", "use": "sig",
+			"n": encodeBigInt(pub.N, (pub.N.BitLen()+7)/8),
+			"e": encodeBigInt(big.NewInt(int64(pub.E)), 3),
+		}
+	case *ecdsa.PublicKey:
+		return map[string]string{
+			"kty": "EC", "kid": kid, "alg": "ES256", "crv": "P-256",
+			"x": encodeBigInt(pub.X, 32),
+			"y": encodeBigInt(pub.Y, 32),
+		}
+	}
+	t.Fatalf("unsupported key %T", key)
+	return nil
+}
+
+// validClaims - claims, которые проходят проверку с testJWTConfig
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":  testJWTConfig.Issuer,
+		"aud":  testJWTConfig.Audience,
+		"sub":  "u1",
+		"role": RoleUser,
+		"jti":  "tok-1",
+		"iat":  now.Unix(),
+		"exp":  now.Add(time.Hour).Unix(),
+	}
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// jwksServer отдаёт текущий набор ключей SSO и считает, сколько раз его скачали
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    []map[string]string
+	status  int
+	fetches int
+}
+
+func newJWKSServer(t *testing.T, keys ...map[string]string) *jwksServer {
+	s := &jwksServer{keys: keys, status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.fetches++
+		w.WriteHeader(s.status)
+		json.NewEncoder(w).Encode(map[string]any{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) rotate(status int, keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.keys = status, keys
+}
+
+func (s *jwksServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func TestJWTVerifierJWKS(t *testing.T) {
+	rsaKey := generateRSAKey(t)
+	ecKey := generateECKey(t)
+	otherKey := generateRSAKey(t)
+
+	server := newJWKSServer(t, publicJWK(t, "rsa-1", rsaKey), publicJWK(t, "ec-1", ecKey))
+	cfg := testJWTConfig
+	cfg.JWKSURL = server.URL
+	verifier, err := NewJWTVerifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		claims := validClaims()
+		change(claims)
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"RS256", signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", validClaims()), nil},
+		{"ES256", signToken(t, jwt.SigningMethodES256, ecKey, "ec-1", validClaims()), nil},
+		{"audience list", signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", with(func(c jwt.MapClaims) {
+			c["aud"] = []string{"other-service", testJWTConfig.Audience}
+		})), nil},
+		{"expired", signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", with(func(c jwt.MapClaims) {
+			c["exp"] = time.Now().Add(-time.Minute).Unix()
+		})), jwt.ErrTokenExpired},
+		{"expired within leeway", signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", with(func(c jwt.MapClaims) {
+			c["exp"] = time.Now().Add(-2 * time.Second).Unix()
+		})), nil},
+		{"no expiration", signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", with(func(c jwt.MapClaims) {
+			delete(c, "exp")
+		})), jwt.ErrTokenRequiredClaimMissing},
+		{"not yet valid", signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", with(func(c jwt.MapClaims) {
+			c["nbf"] = time.Now().Add(time.Hour).Unix()
+		})), jwt.ErrTokenNotValidYet},
+		{"wrong issuer", signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", with(func(c jwt.MapClaims) {
+			c["iss"] = "https://evil.example.com"
+		})), jwt.ErrTokenInvalidIssuer},
+		{"wrong audience", signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", with(func(c jwt.MapClaims) {
+			c["aud"] = "other-service"
+		})), jwt.ErrTokenInvalidAudience},
+		{"unknown kid", signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-404", validClaims()), jwt.ErrTokenUnverifiable},
+		{"signed by another key", signToken(t, jwt.SigningMethodRS256, otherKey, "rsa-1", validClaims()), jwt.ErrTokenSignatureInvalid},
+		{"key of another algorithm", signToken(t, jwt.SigningMethodES256, ecKey, "rsa-1", validClaims()), jwt.ErrTokenUnverifiable},
+		// открытый RSA-ключ не должен приниматься как секрет HS256
+		{"HS256 with public key as secret", signToken(t, jwt.SigningMethodHS256, x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey), "rsa-1", validClaims()), jwt.ErrTokenUnverifiable},
+		{"alg none", signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "rsa-1", validClaims()), jwt.ErrTokenSignatureInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.Verify(context.Background(), tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if principal.UserID != "u1" || principal.Role != RoleUser || principal.TokenID != "tok-1" {
+				t.Errorf("principal = %+v", principal)
+			}
+		})
+	}
+
+	// ключи скачиваются один раз, а не на каждый токен
+	if fetches := server.fetchCount(); fetches != 1 {
+		t.Errorf("jwks fetched %d times, want 1", fetches)
+	}
+}
+
+func TestJWTVerifierRoleClaims(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	verifier := NewJWTVerifierWithKeys(staticKey{key: secret}, testJWTConfig)
+
+	tests := []struct {
+		name     string
+		role     any
+		sub      string
+		wantRole string
+	}{
+		{"single role", RoleAdmin, "", RoleAdmin},
+		{"strongest of several", []string{RoleUser, "viewer", RoleService}, "ci-bot", RoleService},
+		{"user requires user claim", RoleUser, "", ""},
+		{"unknown role", "superuser", "u1", ""},
+		{"no known role in list", []string{"viewer"}, "u1", ""},
+		{"missing role", nil, "u1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			claims["sub"] = tt.sub
+			claims["role"] = tt.role
+			if tt.role == nil {
+				delete(claims, "role")
+			}
+
+			principal, err := verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodHS256, secret, "", claims))
+			if tt.wantRole == "" {
+				if err == nil {
+					t.Fatalf("principal = %+v, want error", principal)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if principal.Role != tt.wantRole {
+				t.Errorf("role = %q, want %q", principal.Role, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	ecKey := generateECKey(t)
+
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemPath := filepath.Join(dir, "sso.pem")
+	if err := os.WriteFile(pemPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	secretPath := filepath.Join(dir, "sso.secret")
+	if err := os.WriteFile(secretPath, append(secret, '\n'), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	shortPath := filepath.Join(dir, "short.secret")
+	if err := os.WriteFile(shortPath, []byte("too-short"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testJWTConfig
+	cfg.KeyFile = pemPath
+	verifier, err := NewJWTVerifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodES256, ecKey, "", validClaims())); err != nil {
+		t.Errorf("ES256 with PEM key: %v", err)
+	}
+	if _, err := verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodHS256, secret, "", validClaims())); err == nil {
+		t.Error("HS256 token accepted by a verifier with an EC key")
+	}
+
+	cfg.KeyFile = secretPath
+	verifier, err = NewJWTVerifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodHS256, secret, "", validClaims())); err != nil {
+		t.Errorf("HS256 with secret file: %v", err)
+	}
+
+	cfg.KeyFile = shortPath
+	if _, err := NewJWTVerifier(cfg); err == nil {
+		t.Error("short HMAC secret accepted")
+	}
+}
+
+func TestJWKSSourceRotation(t *testing.T) {
+	oldKey := generateRSAKey(t)
+	newKey := generateRSAKey(t)
+
+	server := newJWKSServer(t, publicJWK(t, "2025-09", oldKey))
+	source := NewJWKSSource(server.URL, 10*time.Minute)
+	now := time.Now()
+	source.now = func() time.Time { return now }
+	verifier := NewJWTVerifierWithKeys(source, testJWTConfig)
+	ctx := context.Background()
+
+	if _, err := verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, oldKey, "2025-09", validClaims())); err != nil {
+		t.Fatal(err)
+	}
+
+	// SSO перешёл на новый ключ, но старый ещё публикует
+	server.rotate(http.StatusOK, publicJWK(t, "2025-10", newKey), publicJWK(t, "2025-09", oldKey))
+	newToken := signToken(t, jwt.SigningMethodRS256, newKey, "2025-10", validClaims())
+
+	// неизвестный kid сразу после скачивания не вызывает повторного запроса: поддельные kid не нагружают SSO
+	now = now.Add(time.Second)
+	if _, err := verifier.Verify(ctx, newToken); !errors.Is(err, jwt.ErrTokenUnverifiable) {
+		t.Fatalf("err = %v, want unknown key", err)
+	}
+	if fetches := server.fetchCount(); fetches != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", fetches)
+	}
+
+	now = now.Add(jwksMinRefetch)
+	if _, err := verifier.Verify(ctx, newToken); err != nil {
+		t.Fatalf("token signed by rotated key: %v", err)
+	}
+	if _, err := verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, oldKey, "2025-09", validClaims())); err != nil {
+		t.Errorf("token signed by previous key: %v", err)
+	}
+	if fetches := server.fetchCount(); fetches != 2 {
+		t.Errorf("jwks fetched %d times, want 2", fetches)
+	}
+
+	// SSO недоступен при плановом обновлении: проверка продолжается по скачанным ключам
+	server.rotate(http.StatusServiceUnavailable)
+	now = now.Add(10 * time.Minute)
+	if _, err := verifier.Verify(ctx, newToken); err != nil {
+		t.Errorf("token rejected while jwks endpoint is down: %v", err)
+	}
+	if fetches := server.fetchCount(); fetches != 3 {
+		t.Errorf("jwks fetched %d times, want 3", fetches)
+	}
+}
+
+func TestJWKSSourceUnavailable(t *testing.T) {
+	server := newJWKSServer(t)
+	server.rotate(http.StatusInternalServerError)
+	source := NewJWKSSource(server.URL, 10*time.Minute)
+
+	_, err := source.Key(context.Background(), "rsa-1", "RS256")
+	if !errors.Is(err, ErrKeyUnavailable) {
+		t.Fatalf("err = %v, want ErrKeyUnavailable", err)
+	}
+}
@@ -0,0 +1,318 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrKeyUnavailable - ключ не удалось получить (JWKS недоступен), а не подпись неверна
+var ErrKeyUnavailable = errors.New("signing key unavailable")
+
+// minHMACSecretLength - меньше 256 бит для HS256 не принимаем
+const minHMACSecretLength = 32
+
+// KeySource отдаёт ключ для проверки подписи по kid и alg из заголовка JWT
+type KeySource interface {
+	Key(ctx context.Context, kid, alg string) (any, error)
+}
+
+// LoadKeyFile читает ключ из файла: JWKS в JSON, PEM с публичным ключом
+// или сертификатом, иначе всё содержимое считается секретом HMAC
+func LoadKeyFile(path string) (KeySource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(data, []byte("{")):
+		return parseJWKS(data)
+	case bytes.HasPrefix(data, []byte("-----BEGIN")):
+		key, err := parsePublicKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return staticKey{key: key}, nil
+	default:
+		if len(data) < minHMACSecretLength {
+			return nil, fmt.Errorf("HMAC secret must be at least %d bytes", minHMACSecretLength)
+		}
+		return staticKey{key: data}, nil
+	}
+}
+
+// staticKey - единственный ключ, kid не проверяется
+type staticKey struct {
+	key any
+}
+
+func (s staticKey) Key(_ context.Context, _, alg string) (any, error) {
+	if !keyMatchesAlg(s.key, alg) {
+		return nil, fmt.Errorf("key does not support %s", alg)
+	}
+	return s.key, nil
+}
+
+type jwk struct {
+	alg string
+	key any
+}
+
+// keySet - набор ключей из JWKS, индексированный по kid
+type keySet map[string]jwk
+
+func (s keySet) Key(_ context.Context, kid, alg string) (any, error) {
+	k, ok := s[kid]
+	if !ok && kid == "" && len(s) == 1 {
+		for _, only := range s {
+			k, ok = only, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if (k.alg != "" && k.alg != alg) || !keyMatchesAlg(k.key, alg) {
+		return nil, fmt.Errorf("key %q does not support %s", kid, alg)
+	}
+	return k.key, nil
+}
+
+// JWKSSource скачивает ключи SSO и обновляет их раз в refresh.
+// Неизвестный kid (ротация ключей) вызывает внеплановое обновление, но не чаще jwksMinRefetch.
+type JWKSSource struct {
+	url     string
+	client  *http.Client
+	refresh time.Duration
+	// now подменяется в тестах, чтобы проверить ограничение частоты обновлений
+	now func() time.Time
+
+	mu          sync.Mutex
+	keys        keySet
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+const (
+	jwksFetchTimeout = 5 * time.Second
+	jwksMinRefetch   = 30 * time.Second
+	jwksMaxBodySize  = 1 << 20
+)
+
+func NewJWKSSource(url string, refresh time.Duration) *JWKSSource {
+	return &JWKSSource{
+		url:     url,
+		client:  &http.Client{Timeout: jwksFetchTimeout},
+		refresh: refresh,
+		now:     time.Now,
+	}
+}
+
+func (s *JWKSSource) Key(ctx context.Context, kid, alg string) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	_, known := s.keys[kid]
+	stale := now.Sub(s.fetchedAt) >= s.refresh
+	if (stale || !known) && now.Sub(s.attemptedAt) >= jwksMinRefetch {
+		s.attemptedAt = now
+		keys, err := s.fetch(ctx)
+		if err != nil && s.keys == nil {
+			return nil, fmt.Errorf("%w: %v", ErrKeyUnavailable, err)
+		}
+		// при ошибке обновления продолжаем работать со старыми ключами
+		if err == nil {
+			s.keys, s.fetchedAt = keys, now
+		}
+	}
+	if s.keys == nil {
+		return nil, ErrKeyUnavailable
+	}
+
+	return s.keys.Key(ctx, kid, alg)
+}
+
+func (s *JWKSSource) fetch(ctx context.Context) (keySet, error) {
+	ctx, cancel := context.WithTimeout(ctx, jwksFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks endpoint returned %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, jwksMaxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKS(data)
+}
+
+type rawJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// parseJWKS разбирает RSA, EC (P-256) и oct ключи; ключи других типов
+// и ключи шифрования пропускаются, чтобы один неизвестный ключ не ломал весь набор
+func parseJWKS(data []byte) (keySet, error) {
+	var doc struct {
+		Keys []rawJWK `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+
+	keys := make(keySet, len(doc.Keys))
+	for _, raw := range doc.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+		key, err := raw.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %q: %w", raw.Kid, err)
+		}
+		if key == nil {
+			continue
+		}
+		keys[raw.Kid] = jwk{alg: raw.Alg, key: key}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks contains no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k rawJWK) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return key, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, fmt.Errorf("k: %w", err)
+		}
+		if len(secret) < minHMACSecretLength {
+			return nil, fmt.Errorf("HMAC secret must be at least %d bytes", minHMACSecretLength)
+		}
+		return secret, nil
+	}
+	return nil, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func parsePublicKeyPEM(data []byte) (any, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q, expected a public key", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", key)
+}
+
+// keyMatchesAlg не даёт подменить алгоритм: RSA-ключ нельзя использовать как секрет HMAC и наоборот
+func keyMatchesAlg(key any, alg string) bool {
+	switch key := key.(type) {
+	case []byte:
+		return alg == "HS256"
+	case *rsa.PublicKey:
+		return alg == "RS256"
+	case *ecdsa.PublicKey:
+		return alg == "ES256" && key.Curve == elliptic.P256()
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+// Package codehost - REST-клиенты хостингов кода, которые запрашивают и снимают ревьюеров PR
+package codehost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"antonvedaet/internship_task/internal/delivery"
+	"antonvedaet/internship_task/internal/models"
+)
+
+const (
+	userAgent = "pr-reviewer-service"
+	// maxErrorBodySize - сколько тела ответа с ошибкой попадает в текст ошибки
+	maxErrorBodySize = 1 << 10
+)
+
+// PullRequestRef - PR на хостинге: репозиторий (owner/repo, group/project) и номер в нём
+type PullRequestRef struct {
+	Provider string
+	Repo     string
+	Number   int
+}
+
+// ParsePullRequestID разбирает id, которые дают PR вебхуки хостингов: "owner/repo#number"
+// для GitHub и "group/project!iid" для GitLab. PR, созданные через API с другими id, не разбираются.
+func ParsePullRequestID(id string) (PullRequestRef, bool) {
+	for _, p := range []struct{ sep, provider string }{
+		{"#", models.ProviderGitHub},
+		{"!", models.ProviderGitLab},
+	} {
+		i := strings.LastIndex(id, p.sep)
+		if i < 0 {
+			continue
+		}
+		repo, number := id[:i], id[i+1:]
+		n, err := strconv.Atoi(number)
+		if err != nil || n <= 0 || !strings.Contains(repo, "/") || strings.HasPrefix(repo, "/") || strings.HasSuffix(repo, "/") {
+			return PullRequestRef{}, false
+		}
+		return PullRequestRef{Provider: p.provider, Repo: repo, Number: n}, true
+	}
+	return PullRequestRef{}, false
+}
+
+// Error - ответ API не из 2xx
+type Error struct {
+	Provider    string
+	StatusCode  int
+	Message     string
+	RateLimited bool
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s api returned %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// Retryable - есть ли смысл повторять запрос: сбой хостинга или лимит запросов.
+// Остальные 4xx (нет доступа, пользователь не может быть ревьюером) повтор не исправит.
+func (e *Error) Retryable() bool {
+	return delivery.Retryable(e.StatusCode) || e.RateLimited
+}
+
+// NewHTTPClient - клиент для API хостингов; редиректы не выполняются, чтобы токен не ушёл на другой адрес
+func NewHTTPClient() *http.Client {
+	return &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// doJSON отправляет body в JSON и, если out != nil, разбирает ответ в out
+func doJSON(ctx context.Context, client *http.Client, provider, method, url string, header http.Header, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return &Error{
+			Provider:   provider,
+			StatusCode: resp.StatusCode,
+			Message:    string(bytes.TrimSpace(message)),
+			// GitHub отвечает 403 при исчерпании лимита запросов
+			RateLimited: resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != "",
+		}
+	}
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
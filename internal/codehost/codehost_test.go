@@ -0,0 +1,82 @@
+package codehost
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+func TestParsePullRequestID(t *testing.T) {
+	tests := []struct {
+		id     string
+		want   PullRequestRef
+		wantOK bool
+	}{
+		{"acme/api#42", PullRequestRef{Provider: models.ProviderGitHub, Repo: "acme/api", Number: 42}, true},
+		{"platform/billing!17", PullRequestRef{Provider: models.ProviderGitLab, Repo: "platform/billing", Number: 17}, true},
+		{"acme/platform/billing!17", PullRequestRef{Provider: models.ProviderGitLab, Repo: "acme/platform/billing", Number: 17}, true},
+		{"pr-1001", PullRequestRef{}, false},
+		{"api#42", PullRequestRef{}, false},
+		{"acme/api#0", PullRequestRef{}, false},
+		{"acme/api#x", PullRequestRef{}, false},
+		{"/api#42", PullRequestRef{}, false},
+		{"acme/!17", PullRequestRef{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParsePullRequestID(tt.id)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("ParsePullRequestID(%q) = %+v, %v; want %+v, %v", tt.id, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestErrorRetryable(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		header        http.Header
+		wantRetryable bool
+	}{
+		{"server error", http.StatusInternalServerError, nil, true},
+		{"bad gateway", http.StatusBadGateway, nil, true},
+		{"too many requests", http.StatusTooManyRequests, nil, true},
+		{"request timeout", http.StatusRequestTimeout, nil, true},
+		{"github primary rate limit", http.StatusForbidden, http.Header{"X-Ratelimit-Remaining": {"0"}}, true},
+		{"github secondary rate limit", http.StatusForbidden, http.Header{"Retry-After": {"60"}}, true},
+		{"forbidden", http.StatusForbidden, http.Header{"X-Ratelimit-Remaining": {"4999"}}, false},
+		{"unauthorized", http.StatusUnauthorized, nil, false},
+		{"not found", http.StatusNotFound, nil, false},
+		{"unprocessable", http.StatusUnprocessableEntity, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for name, values := range tt.header {
+					w.Header()[name] = values
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"message": "nope"}`))
+			}))
+			defer server.Close()
+
+			err := doJSON(context.Background(), server.Client(), models.ProviderGitHub, http.MethodGet, server.URL, nil, nil, nil)
+
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want *Error", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Message != `{"message": "nope"}` {
+				t.Errorf("err = %+v", apiErr)
+			}
+			if apiErr.Retryable() != tt.wantRetryable {
+				t.Errorf("Retryable() = %v, want %v", apiErr.Retryable(), tt.wantRetryable)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package codehost
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+// GitHubClient запрашивает ревью через POST/DELETE /repos/{owner}/{repo}/pulls/{number}/requested_reviewers
+type GitHubClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func NewGitHubClient(baseURL, token string, client *http.Client) *GitHubClient {
+	return &GitHubClient{baseURL: strings.TrimSuffix(baseURL, "/"), token: token, client: client}
+}
+
+func (c *GitHubClient) RequestReviewers(ctx context.Context, ref PullRequestRef, logins []string) error {
+	if len(logins) == 0 {
+		return nil
+	}
+	return c.do(ctx, http.MethodPost, ref, logins)
+}
+
+// RemoveReviewers отменяет запрос ревью; оставленные ревью при этом сохраняются
+func (c *GitHubClient) RemoveReviewers(ctx context.Context, ref PullRequestRef, logins []string) error {
+	if len(logins) == 0 {
+		return nil
+	}
+	return c.do(ctx, http.MethodDelete, ref, logins)
+}
+
+func (c *GitHubClient) do(ctx context.Context, method string, ref PullRequestRef, logins []string) error {
+	url := fmt.Sprintf("%s/repos/%s/pulls/%d/requested_reviewers", c.baseURL, ref.Repo, ref.Number)
+	header := http.Header{
+		"Accept":               {"application/vnd.github+json"},
+		"Authorization":        {"Bearer " + c.token},
+		"X-Github-Api-Version": {"2022-11-28"},
+	}
+	return doJSON(ctx, c.client, models.ProviderGitHub, method, url, header, map[string][]string{"reviewers": logins}, nil)
+}
@@ -0,0 +1,97 @@
+package codehost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+type recordedRequest struct {
+	method string
+	uri    string
+	header http.Header
+	body   map[string][]string
+}
+
+func TestGitHubClientRequestedReviewers(t *testing.T) {
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := recordedRequest{method: r.Method, uri: r.RequestURI, header: r.Header}
+		if err := json.NewDecoder(r.Body).Decode(&req.body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		requests = append(requests, req)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"number": 42}`))
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient(server.URL+"/", "ghp_test", server.Client())
+	ref := PullRequestRef{Provider: models.ProviderGitHub, Repo: "acme/api", Number: 42}
+
+	if err := client.RequestReviewers(context.Background(), ref, []string{"alice-dev", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.RemoveReviewers(context.Background(), ref, []string{"carol"}); err != nil {
+		t.Fatal(err)
+	}
+	// пустой список не превращается в запрос
+	if err := client.RequestReviewers(context.Background(), ref, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		method    string
+		reviewers []string
+	}{
+		{http.MethodPost, []string{"alice-dev", "bob"}},
+		{http.MethodDelete, []string{"carol"}},
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("got %d requests, want %d", len(requests), len(want))
+	}
+	for i, req := range requests {
+		if req.method != want[i].method {
+			t.Errorf("request %d: method = %s, want %s", i, req.method, want[i].method)
+		}
+		if req.uri != "/repos/acme/api/pulls/42/requested_reviewers" {
+			t.Errorf("request %d: uri = %s", i, req.uri)
+		}
+		if !slices.Equal(req.body["reviewers"], want[i].reviewers) {
+			t.Errorf("request %d: reviewers = %v, want %v", i, req.body["reviewers"], want[i].reviewers)
+		}
+		for name, value := range map[string]string{
+			"Authorization":        "Bearer ghp_test",
+			"Accept":               "application/vnd.github+json",
+			"X-Github-Api-Version": "2022-11-28",
+			"Content-Type":         "application/json",
+			"User-Agent":           userAgent,
+		} {
+			if got := req.header.Get(name); got != value {
+				t.Errorf("request %d: %s = %q, want %q", i, name, got, value)
+			}
+		}
+	}
+}
+
+func TestGitHubClientRejectedReviewer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message": "Reviews may only be requested from collaborators."}`))
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient(server.URL, "ghp_test", server.Client())
+	err := client.RequestReviewers(context.Background(), PullRequestRef{Provider: models.ProviderGitHub, Repo: "acme/api", Number: 42}, []string{"outsider"})
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Retryable() {
+		t.Fatalf("err = %v, want non-retryable 422", err)
+	}
+}
@@ -0,0 +1,168 @@
+package codehost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+// GitLabClient меняет reviewer_ids merge request. API принимает только полный список id,
+// поэтому клиент читает текущих ревьюеров, добавляет или убирает нужных и записывает список обратно.
+// Условной записи у API нет: изменения одного MR упорядочивает вызывающий (приёмник outbox берёт
+// блокировку PR в БД). Ревьюера, которого человек добавит в интерфейсе GitLab между чтением и записью,
+// запись всё равно перетрёт.
+type GitLabClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+
+	mu sync.Mutex
+	// userIDs - id пользователей по username; id пользователя GitLab не меняется
+	userIDs map[string]int64
+}
+
+func NewGitLabClient(baseURL, token string, client *http.Client) *GitLabClient {
+	return &GitLabClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  client,
+		userIDs: make(map[string]int64),
+	}
+}
+
+type gitlabUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// RequestReviewers добавляет ревьюеров; username, которого нет на GitLab, пропускается
+func (c *GitLabClient) RequestReviewers(ctx context.Context, ref PullRequestRef, usernames []string) error {
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	current, err := c.reviewers(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	ids := slices.Clone(current)
+	for _, username := range usernames {
+		id, ok, err := c.userID(ctx, username)
+		if err != nil {
+			return err
+		}
+		if ok && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == len(current) {
+		return nil
+	}
+	return c.setReviewers(ctx, ref, ids)
+}
+
+func (c *GitLabClient) RemoveReviewers(ctx context.Context, ref PullRequestRef, usernames []string) error {
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	current, err := c.reviewers(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	ids := slices.Clone(current)
+	for _, username := range usernames {
+		id, ok, err := c.userID(ctx, username)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ids = slices.DeleteFunc(ids, func(reviewer int64) bool { return reviewer == id })
+		}
+	}
+	if len(ids) == len(current) {
+		return nil
+	}
+	return c.setReviewers(ctx, ref, ids)
+}
+
+func (c *GitLabClient) reviewers(ctx context.Context, ref PullRequestRef) ([]int64, error) {
+	var mr struct {
+		Reviewers []gitlabUser `json:"reviewers"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.mergeRequestURL(ref), nil, &mr); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(mr.Reviewers))
+	for _, reviewer := range mr.Reviewers {
+		ids = append(ids, reviewer.ID)
+	}
+	return ids, nil
+}
+
+func (c *GitLabClient) setReviewers(ctx context.Context, ref PullRequestRef, ids []int64) error {
+	return c.do(ctx, http.MethodPut, c.mergeRequestURL(ref), map[string][]int64{"reviewer_ids": ids}, nil)
+}
+
+func (c *GitLabClient) userID(ctx context.Context, username string) (int64, bool, error) {
+	username = strings.ToLower(username)
+
+	c.mu.Lock()
+	id, ok := c.userIDs[username]
+	c.mu.Unlock()
+	if ok {
+		return id, true, nil
+	}
+
+	var users []gitlabUser
+	if err := c.do(ctx, http.MethodGet, c.baseURL+"/users?username="+url.QueryEscape(username), nil, &users); err != nil {
+		return 0, false, err
+	}
+	if len(users) == 0 {
+		return 0, false, nil
+	}
+
+	c.mu.Lock()
+	c.userIDs[username] = users[0].ID
+	c.mu.Unlock()
+	return users[0].ID, true, nil
+}
+
+// Username возвращает текущий username пользователя по id. Username на GitLab можно сменить,
+// поэтому ответ не кэшируется; пользователь, которого нет, - не ошибка.
+func (c *GitLabClient) Username(ctx context.Context, id int64) (string, bool, error) {
+	var user gitlabUser
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s/users/%d", c.baseURL, id), nil, &user)
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return user.Username, true, nil
+}
+
+// mergeRequestURL - путь проекта передаётся целиком, со слешами в виде %2F
+func (c *GitLabClient) mergeRequestURL(ref PullRequestRef) string {
+	return fmt.Sprintf("%s/projects/%s/merge_requests/%d", c.baseURL, url.PathEscape(ref.Repo), ref.Number)
+}
+
+func (c *GitLabClient) do(ctx context.Context, method, url string, body, out any) error {
+	header := http.Header{}
+	// без токена доступны только публичные данные, например пользователи gitlab.com
+	if c.token != "" {
+		header.Set("Private-Token", c.token)
+	}
+	return doJSON(ctx, c.client, models.ProviderGitLab, method, url, header, body, out)
+}
@@ -0,0 +1,168 @@
+package codehost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+// fakeGitLab хранит ревьюеров одного MR platform/billing!17 и считает запросы
+type fakeGitLab struct {
+	t *testing.T
+
+	mu        sync.Mutex
+	users     map[string]int64
+	reviewers []int64
+	lookups   int
+	puts      int
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if got := r.Header.Get("Private-Token"); got != "glpat-test" {
+		f.t.Errorf("Private-Token = %q", got)
+	}
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v4/users/"):
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for username, id := range f.users {
+			if r.URL.Path == fmt.Sprintf("/api/v4/users/%d", id) {
+				json.NewEncoder(w).Encode(gitlabUser{ID: id, Username: username})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v4/users":
+		f.mu.Lock()
+		f.lookups++
+		id, ok := f.users[r.URL.Query().Get("username")]
+		f.mu.Unlock()
+
+		users := []gitlabUser{}
+		if ok {
+			users = append(users, gitlabUser{ID: id, Username: r.URL.Query().Get("username")})
+		}
+		json.NewEncoder(w).Encode(users)
+
+	case r.RequestURI == "/api/v4/projects/platform%2Fbilling/merge_requests/17":
+		switch r.Method {
+		case http.MethodGet:
+			f.mu.Lock()
+			reviewers := make([]gitlabUser, 0, len(f.reviewers))
+			for _, id := range f.reviewers {
+				reviewers = append(reviewers, gitlabUser{ID: id})
+			}
+			f.mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]any{"iid": 17, "reviewers": reviewers})
+		case http.MethodPut:
+			var body struct {
+				ReviewerIDs []int64 `json:"reviewer_ids"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				f.t.Errorf("decode body: %v", err)
+			}
+			f.mu.Lock()
+			f.puts++
+			f.reviewers = body.ReviewerIDs
+			f.mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]any{"iid": 17})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.RequestURI)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeGitLab) state() (reviewers []int64, lookups, puts int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.reviewers), f.lookups, f.puts
+}
+
+func newFakeGitLab(t *testing.T, reviewers ...int64) (*fakeGitLab, *GitLabClient) {
+	fake := &fakeGitLab{
+		t:         t,
+		users:     map[string]int64{"alice.dev": 31, "bob.lead": 44, "carol": 57},
+		reviewers: reviewers,
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return fake, NewGitLabClient(server.URL+"/api/v4", "glpat-test", server.Client())
+}
+
+var billingMR = PullRequestRef{Provider: models.ProviderGitLab, Repo: "platform/billing", Number: 17}
+
+func TestGitLabClientRequestReviewers(t *testing.T) {
+	fake, client := newFakeGitLab(t, 57)
+	ctx := context.Background()
+
+	// существующий ревьюер сохраняется, неизвестный на GitLab пропускается, регистр логина не важен
+	if err := client.RequestReviewers(ctx, billingMR, []string{"Alice.Dev", "ghost"}); err != nil {
+		t.Fatal(err)
+	}
+	reviewers, lookups, puts := fake.state()
+	if !slices.Equal(reviewers, []int64{57, 31}) {
+		t.Errorf("reviewers = %v, want [57 31]", reviewers)
+	}
+	if lookups != 2 || puts != 1 {
+		t.Errorf("lookups = %d, puts = %d; want 2, 1", lookups, puts)
+	}
+
+	// id уже известен, а ревьюер уже назначен: ни поиска пользователя, ни записи
+	if err := client.RequestReviewers(ctx, billingMR, []string{"alice.dev"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, lookups, puts = fake.state(); lookups != 2 || puts != 1 {
+		t.Errorf("lookups = %d, puts = %d; want 2, 1", lookups, puts)
+	}
+}
+
+func TestGitLabClientUsername(t *testing.T) {
+	_, client := newFakeGitLab(t)
+	ctx := context.Background()
+
+	username, found, err := client.Username(ctx, 44)
+	if err != nil || !found || username != "bob.lead" {
+		t.Errorf("Username(44) = %q, %v, %v; want bob.lead", username, found, err)
+	}
+
+	// удалённый пользователь - не ошибка
+	if _, found, err := client.Username(ctx, 99); err != nil || found {
+		t.Errorf("Username(99) found = %v, err = %v; want not found", found, err)
+	}
+}
+
+func TestGitLabClientRemoveReviewers(t *testing.T) {
+	fake, client := newFakeGitLab(t, 31, 44, 57)
+	ctx := context.Background()
+
+	if err := client.RemoveReviewers(ctx, billingMR, []string{"bob.lead"}); err != nil {
+		t.Fatal(err)
+	}
+	reviewers, _, puts := fake.state()
+	if !slices.Equal(reviewers, []int64{31, 57}) || puts != 1 {
+		t.Errorf("reviewers = %v, puts = %d; want [31 57], 1", reviewers, puts)
+	}
+
+	// снятие уже снятого ревьюера ничего не записывает: повтор события из outbox безопасен
+	if err := client.RemoveReviewers(ctx, billingMR, []string{"bob.lead"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, puts = fake.state(); puts != 1 {
+		t.Errorf("puts = %d, want 1", puts)
+	}
+}
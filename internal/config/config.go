@@ -0,0 +1,749 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server        ServerConfig        `yaml:"server"`
+	Database      DatabaseConfig      `yaml:"database"`
+	Log           LogConfig           `yaml:"log"`
+	Tracing       TracingConfig       `yaml:"tracing"`
+	Auth          AuthConfig          `yaml:"auth"`
+	RateLimit     RateLimitConfig     `yaml:"rate_limit"`
+	Webhooks      WebhooksConfig      `yaml:"webhooks"`
+	Outbox        OutboxConfig        `yaml:"outbox"`
+	Stream        StreamConfig        `yaml:"stream"`
+	Integrations  IntegrationsConfig  `yaml:"integrations"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Features      FeaturesConfig      `yaml:"features"`
+}
+
+type ServerConfig struct {
+	Addr               string        `yaml:"addr"`
+	ReadTimeout        time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout  time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout       time.Duration `yaml:"write_timeout"`
+	IdleTimeout        time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout    time.Duration `yaml:"shutdown_timeout"`
+	ShutdownDrainDelay time.Duration `yaml:"shutdown_drain_delay"`
+	MaxBodyBytes       int           `yaml:"max_body_bytes"`
+}
+
+type DatabaseConfig struct {
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	SSLMode         string        `yaml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout"`
+	QueryTimeout    time.Duration `yaml:"query_timeout"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level"`
+}
+
+type TracingConfig struct {
+	Exporter     string `yaml:"exporter"`
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+}
+
+type AuthConfig struct {
+	Enabled        bool      `yaml:"enabled"`
+	BootstrapToken string    `yaml:"bootstrap_token"`
+	JWT            JWTConfig `yaml:"jwt"`
+}
+
+// JWTConfig включает приём JWT, если задан key_file или jwks_url
+type JWTConfig struct {
+	KeyFile     string        `yaml:"key_file"`
+	JWKSURL     string        `yaml:"jwks_url"`
+	JWKSRefresh time.Duration `yaml:"jwks_refresh"`
+	Issuer      string        `yaml:"issuer"`
+	Audience    string        `yaml:"audience"`
+	UserClaim   string        `yaml:"user_claim"`
+	RoleClaim   string        `yaml:"role_claim"`
+	Leeway      time.Duration `yaml:"leeway"`
+}
+
+func (c *JWTConfig) Enabled() bool {
+	return c.KeyFile != "" || c.JWKSURL != ""
+}
+
+// RateLimitConfig задаёт token bucket на клиента (токен или IP) для каждой группы маршрутов
+type RateLimitConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Read    RateLimitRule `yaml:"read"`
+	Write   RateLimitRule `yaml:"write"`
+	Admin   RateLimitRule `yaml:"admin"`
+	// IP ограничивает запросы с одного адреса до аутентификации, в том числе с неверным токеном
+	IP RateLimitRule `yaml:"ip"`
+	// TrustedProxies - адреса и подсети (CIDR) обратных прокси. X-Forwarded-For учитывается,
+	// только если запрос пришёл от них: иначе клиент подставил бы в заголовок любой адрес.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// TrustedProxyPrefixes разбирает TrustedProxies; неверные значения отклоняет Validate
+func (c RateLimitConfig) TrustedProxyPrefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, proxy := range c.TrustedProxies {
+		if prefix, err := parseProxy(proxy); err == nil {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// parseProxy принимает подсеть "10.0.0.0/8" или отдельный адрес "10.0.0.1"
+func parseProxy(proxy string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(proxy); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// RateLimitRule - RPS 0 отключает ограничение для группы
+type RateLimitRule struct {
+	RPS   float64 `yaml:"rps"`
+	Burst int     `yaml:"burst"`
+}
+
+// WebhooksConfig управляет фоновой доставкой вебхуков; подписки и очередь хранятся в БД
+type WebhooksConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	Timeout      time.Duration `yaml:"timeout"`
+	MaxAttempts  int           `yaml:"max_attempts"`
+	BackoffBase  time.Duration `yaml:"backoff_base"`
+	BackoffMax   time.Duration `yaml:"backoff_max"`
+}
+
+// Приёмники, в которые диспетчер outbox публикует события
+const (
+	SinkWebhook = "webhook"
+	SinkStdout  = "stdout"
+	SinkFile    = "file"
+	// SinkCodeHost запрашивает назначенных ревьюеров на GitHub и GitLab
+	SinkCodeHost = "codehost"
+	// SinkChat ставит в очередь чат-уведомления ревьюерам
+	SinkChat = "chat"
+)
+
+// OutboxConfig управляет публикацией доменных событий из таблицы outbox_events
+type OutboxConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	Sinks        []string      `yaml:"sinks"`
+	FilePath     string        `yaml:"file_path"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	MaxAttempts  int           `yaml:"max_attempts"`
+	BackoffBase  time.Duration `yaml:"backoff_base"`
+	BackoffMax   time.Duration `yaml:"backoff_max"`
+	Retention    time.Duration `yaml:"retention"`
+}
+
+// StreamConfig управляет SSE-потоком событий ревьюера GET /users/events
+type StreamConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Heartbeat time.Duration `yaml:"heartbeat"`
+}
+
+// NotificationsConfig управляет отправкой чат-уведомлений в Slack и Mattermost и поиском
+// просроченных ревью; каналы и очередь сообщений хранятся в БД
+type NotificationsConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	Timeout      time.Duration `yaml:"timeout"`
+	MaxAttempts  int           `yaml:"max_attempts"`
+	BackoffBase  time.Duration `yaml:"backoff_base"`
+	BackoffMax   time.Duration `yaml:"backoff_max"`
+	// OverdueAfter - через сколько после назначения ревью считается просроченным; 0 отключает проверку
+	OverdueAfter         time.Duration `yaml:"overdue_after"`
+	OverdueCheckInterval time.Duration `yaml:"overdue_check_interval"`
+}
+
+// IntegrationsConfig - приём вебхуков хостингов кода; хостинг без секрета выключен.
+// Token нужен приёмнику outbox codehost, который запрашивает ревьюеров через REST API хостинга.
+type IntegrationsConfig struct {
+	GitHub GitHubConfig `yaml:"github"`
+	GitLab GitLabConfig `yaml:"gitlab"`
+}
+
+type GitHubConfig struct {
+	WebhookSecret string `yaml:"webhook_secret"`
+	APIURL        string `yaml:"api_url"`
+	Token         string `yaml:"token"`
+}
+
+func (c *GitHubConfig) Enabled() bool {
+	return c.WebhookSecret != ""
+}
+
+type GitLabConfig struct {
+	WebhookToken string `yaml:"webhook_token"`
+	APIURL       string `yaml:"api_url"`
+	Token        string `yaml:"token"`
+}
+
+func (c *GitLabConfig) Enabled() bool {
+	return c.WebhookToken != ""
+}
+
+type FeaturesConfig struct {
+	Metrics bool `yaml:"metrics"`
+}
+
+const (
+	minBootstrapTokenLength = 16
+	minWebhookSecretLength  = 16
+)
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:               ":8080",
+			ReadTimeout:        10 * time.Second,
+			ReadHeaderTimeout:  5 * time.Second,
+			WriteTimeout:       15 * time.Second,
+			IdleTimeout:        60 * time.Second,
+			ShutdownTimeout:    20 * time.Second,
+			ShutdownDrainDelay: 5 * time.Second,
+			MaxBodyBytes:       1 << 20,
+		},
+		Database: DatabaseConfig{
+			Port:            5432,
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout:  5 * time.Second,
+			QueryTimeout:    5 * time.Second,
+		},
+		Log: LogConfig{
+			Level: "INFO",
+		},
+		Tracing: TracingConfig{
+			Exporter: "none",
+		},
+		Auth: AuthConfig{
+			Enabled: true,
+			JWT: JWTConfig{
+				JWKSRefresh: 10 * time.Minute,
+				UserClaim:   "sub",
+				RoleClaim:   "role",
+				Leeway:      30 * time.Second,
+			},
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Read:    RateLimitRule{RPS: 20, Burst: 40},
+			Write:   RateLimitRule{RPS: 5, Burst: 10},
+			Admin:   RateLimitRule{RPS: 2, Burst: 10},
+			IP:      RateLimitRule{RPS: 50, Burst: 100},
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:      true,
+			PollInterval: 2 * time.Second,
+			BatchSize:    20,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  10,
+			BackoffBase:  10 * time.Second,
+			BackoffMax:   time.Hour,
+		},
+		Outbox: OutboxConfig{
+			Enabled:      true,
+			Sinks:        []string{SinkWebhook, SinkChat},
+			PollInterval: time.Second,
+			BatchSize:    100,
+			MaxAttempts:  10,
+			BackoffBase:  5 * time.Second,
+			BackoffMax:   10 * time.Minute,
+			Retention:    7 * 24 * time.Hour,
+		},
+		Stream: StreamConfig{
+			Enabled:   true,
+			Heartbeat: 15 * time.Second,
+		},
+		Integrations: IntegrationsConfig{
+			GitHub: GitHubConfig{APIURL: "https://api.github.com"},
+			GitLab: GitLabConfig{APIURL: "https://gitlab.com/api/v4"},
+		},
+		Notifications: NotificationsConfig{
+			Enabled:              true,
+			PollInterval:         2 * time.Second,
+			BatchSize:            20,
+			Timeout:              10 * time.Second,
+			MaxAttempts:          5,
+			BackoffBase:          10 * time.Second,
+			BackoffMax:           30 * time.Minute,
+			OverdueCheckInterval: time.Minute,
+		},
+		Features: FeaturesConfig{
+			Metrics: true,
+		},
+	}
+}
+
+// Load собирает конфигурацию по возрастанию приоритета:
+// значения по умолчанию, YAML-файл (-config или CONFIG_FILE), переменные окружения, флаги.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	options := cfg.options()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to YAML config file (env CONFIG_FILE)")
+	flagValues := make(map[string]*flagValue, len(options))
+	for _, opt := range options {
+		flagValues[opt.flag] = &flagValue{isBool: opt.isBool}
+		fs.Var(flagValues[opt.flag], opt.flag, fmt.Sprintf("%s (env %s)", opt.usage, opt.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, opt := range options {
+		if value, ok := os.LookupEnv(opt.env); ok && value != "" {
+			if err := opt.set(value); err != nil {
+				return nil, fmt.Errorf("env %s: %w", opt.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, opt := range options {
+			if opt.flag == f.Name && flagErr == nil {
+				if err := opt.set(flagValues[f.Name].value); err != nil {
+					flagErr = fmt.Errorf("flag -%s: %w", f.Name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	required := func(value, name, env string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is required (env %s)", name, env))
+		}
+	}
+	positive := func(value time.Duration, name string) {
+		if value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", name, value))
+		}
+	}
+
+	required(c.Server.Addr, "server.addr", "HTTP_ADDR")
+	positive(c.Server.ReadTimeout, "server.read_timeout")
+	positive(c.Server.ReadHeaderTimeout, "server.read_header_timeout")
+	positive(c.Server.WriteTimeout, "server.write_timeout")
+	positive(c.Server.IdleTimeout, "server.idle_timeout")
+	positive(c.Server.ShutdownTimeout, "server.shutdown_timeout")
+	if c.Server.ShutdownDrainDelay < 0 {
+		errs = append(errs, fmt.Errorf("server.shutdown_drain_delay must not be negative"))
+	}
+	if c.Server.MaxBodyBytes < 1 {
+		errs = append(errs, fmt.Errorf("server.max_body_bytes must be positive, got %d", c.Server.MaxBodyBytes))
+	}
+
+	required(c.Database.Host, "database.host", "DB_HOST")
+	required(c.Database.User, "database.user", "DB_USER")
+	required(c.Database.Name, "database.name", "DB_NAME")
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		errs = append(errs, fmt.Errorf("database.port must be between 1 and 65535, got %d", c.Database.Port))
+	}
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		errs = append(errs, fmt.Errorf("database.sslmode %q is not supported", c.Database.SSLMode))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, fmt.Errorf("database pool sizes must not be negative"))
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, fmt.Errorf("database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)",
+			c.Database.MaxIdleConns, c.Database.MaxOpenConns))
+	}
+	positive(c.Database.ConnectTimeout, "database.connect_timeout")
+	positive(c.Database.QueryTimeout, "database.query_timeout")
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		errs = append(errs, fmt.Errorf("log.level %q is not one of DEBUG, INFO, WARN, ERROR", c.Log.Level))
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter %q is not one of none, stdout, otlp", c.Tracing.Exporter))
+	}
+
+	if c.Auth.BootstrapToken != "" && len(c.Auth.BootstrapToken) < minBootstrapTokenLength {
+		errs = append(errs, fmt.Errorf("auth.bootstrap_token must be at least %d characters", minBootstrapTokenLength))
+	}
+	if c.Auth.JWT.KeyFile != "" && c.Auth.JWT.JWKSURL != "" {
+		errs = append(errs, fmt.Errorf("auth.jwt.key_file and auth.jwt.jwks_url are mutually exclusive"))
+	}
+	if c.Auth.JWT.JWKSURL != "" {
+		if u, err := url.Parse(c.Auth.JWT.JWKSURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("auth.jwt.jwks_url %q is not an http(s) URL", c.Auth.JWT.JWKSURL))
+		}
+	}
+	positive(c.Auth.JWT.JWKSRefresh, "auth.jwt.jwks_refresh")
+	required(c.Auth.JWT.UserClaim, "auth.jwt.user_claim", "JWT_USER_CLAIM")
+	required(c.Auth.JWT.RoleClaim, "auth.jwt.role_claim", "JWT_ROLE_CLAIM")
+	if c.Auth.JWT.Leeway < 0 {
+		errs = append(errs, fmt.Errorf("auth.jwt.leeway must not be negative"))
+	}
+
+	rateRule := func(rule RateLimitRule, name string) {
+		if rule.RPS < 0 {
+			errs = append(errs, fmt.Errorf("rate_limit.%s.rps must not be negative", name))
+		}
+		if rule.RPS > 0 && rule.Burst < 1 {
+			errs = append(errs, fmt.Errorf("rate_limit.%s.burst must be at least 1", name))
+		}
+	}
+	rateRule(c.RateLimit.Read, "read")
+	rateRule(c.RateLimit.Write, "write")
+	rateRule(c.RateLimit.Admin, "admin")
+	rateRule(c.RateLimit.IP, "ip")
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		if _, err := parseProxy(proxy); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limit.trusted_proxies: %q is not an IP address or CIDR", proxy))
+		}
+	}
+
+	positive(c.Webhooks.PollInterval, "webhooks.poll_interval")
+	positive(c.Webhooks.Timeout, "webhooks.timeout")
+	positive(c.Webhooks.BackoffBase, "webhooks.backoff_base")
+	positive(c.Webhooks.BackoffMax, "webhooks.backoff_max")
+	if c.Webhooks.BatchSize < 1 {
+		errs = append(errs, fmt.Errorf("webhooks.batch_size must be positive, got %d", c.Webhooks.BatchSize))
+	}
+	if c.Webhooks.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("webhooks.max_attempts must be positive, got %d", c.Webhooks.MaxAttempts))
+	}
+
+	positive(c.Outbox.PollInterval, "outbox.poll_interval")
+	positive(c.Outbox.BackoffBase, "outbox.backoff_base")
+	positive(c.Outbox.BackoffMax, "outbox.backoff_max")
+	if c.Outbox.BatchSize < 1 {
+		errs = append(errs, fmt.Errorf("outbox.batch_size must be positive, got %d", c.Outbox.BatchSize))
+	}
+	if c.Outbox.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("outbox.max_attempts must be positive, got %d", c.Outbox.MaxAttempts))
+	}
+	if c.Outbox.Retention < 0 {
+		errs = append(errs, fmt.Errorf("outbox.retention must not be negative, got %s", c.Outbox.Retention))
+	}
+	seenSinks := map[string]bool{}
+	for _, sink := range c.Outbox.Sinks {
+		switch sink {
+		case SinkWebhook, SinkStdout, SinkFile, SinkCodeHost, SinkChat:
+		default:
+			errs = append(errs, fmt.Errorf("outbox.sinks: unknown sink %q, expected %s, %s, %s, %s or %s", sink, SinkWebhook, SinkStdout, SinkFile, SinkCodeHost, SinkChat))
+		}
+		if seenSinks[sink] {
+			errs = append(errs, fmt.Errorf("outbox.sinks: duplicate sink %q", sink))
+		}
+		seenSinks[sink] = true
+	}
+	if seenSinks[SinkFile] && c.Outbox.FilePath == "" {
+		errs = append(errs, errors.New("outbox.file_path is required for the file sink"))
+	}
+	if seenSinks[SinkCodeHost] && c.Integrations.GitHub.Token == "" && c.Integrations.GitLab.Token == "" {
+		errs = append(errs, errors.New("integrations.github.token or integrations.gitlab.token is required for the codehost sink"))
+	}
+	// без приёмника chat очередь уведомлений никто не пополняет, и воркер молча простаивает
+	if c.Notifications.Enabled && c.Outbox.Enabled && !seenSinks[SinkChat] {
+		errs = append(errs, errors.New("outbox.sinks must include chat when notifications.enabled is true"))
+	}
+
+	positive(c.Stream.Heartbeat, "stream.heartbeat")
+
+	positive(c.Notifications.PollInterval, "notifications.poll_interval")
+	positive(c.Notifications.Timeout, "notifications.timeout")
+	positive(c.Notifications.BackoffBase, "notifications.backoff_base")
+	positive(c.Notifications.BackoffMax, "notifications.backoff_max")
+	positive(c.Notifications.OverdueCheckInterval, "notifications.overdue_check_interval")
+	if c.Notifications.BatchSize < 1 {
+		errs = append(errs, fmt.Errorf("notifications.batch_size must be positive, got %d", c.Notifications.BatchSize))
+	}
+	if c.Notifications.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("notifications.max_attempts must be positive, got %d", c.Notifications.MaxAttempts))
+	}
+	if c.Notifications.OverdueAfter < 0 {
+		errs = append(errs, fmt.Errorf("notifications.overdue_after must not be negative, got %s", c.Notifications.OverdueAfter))
+	}
+
+	if c.Integrations.GitHub.Enabled() && len(c.Integrations.GitHub.WebhookSecret) < minWebhookSecretLength {
+		errs = append(errs, fmt.Errorf("integrations.github.webhook_secret must be at least %d characters", minWebhookSecretLength))
+	}
+	if c.Integrations.GitLab.Enabled() && len(c.Integrations.GitLab.WebhookToken) < minWebhookSecretLength {
+		errs = append(errs, fmt.Errorf("integrations.gitlab.webhook_token must be at least %d characters", minWebhookSecretLength))
+	}
+	apiURL := func(value, name string) {
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s must be an absolute http(s) URL, got %q", name, value))
+		}
+	}
+	apiURL(c.Integrations.GitHub.APIURL, "integrations.github.api_url")
+	apiURL(c.Integrations.GitLab.APIURL, "integrations.gitlab.api_url")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (c *LogConfig) SlogLevel() slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(c.Level))
+	return level
+}
+
+type option struct {
+	env   string
+	flag  string
+	usage string
+	set   func(string) error
+	// isBool разрешает флаг без значения: -auth означает -auth=true
+	isBool bool
+}
+
+// flagValue хранит строку флага до разбора в option.set, чтобы флаги применялись после
+// файла и переменных окружения
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+func (v *flagValue) Set(value string) error {
+	v.value = value
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+func (c *Config) options() []option {
+	return []option{
+		stringOption("HTTP_ADDR", "addr", "HTTP listen address", &c.Server.Addr),
+		durationOption("HTTP_READ_TIMEOUT", "read-timeout", "time to read the whole request", &c.Server.ReadTimeout),
+		durationOption("HTTP_READ_HEADER_TIMEOUT", "read-header-timeout", "time to read request headers", &c.Server.ReadHeaderTimeout),
+		durationOption("HTTP_WRITE_TIMEOUT", "write-timeout", "time to write the response", &c.Server.WriteTimeout),
+		durationOption("HTTP_IDLE_TIMEOUT", "idle-timeout", "keep-alive idle timeout", &c.Server.IdleTimeout),
+		durationOption("SHUTDOWN_TIMEOUT", "shutdown-timeout", "time to wait for in-flight requests on shutdown", &c.Server.ShutdownTimeout),
+		durationOption("SHUTDOWN_DRAIN_DELAY", "shutdown-drain-delay", "delay between failing readiness and stopping the server", &c.Server.ShutdownDrainDelay),
+		intOption("HTTP_MAX_BODY_BYTES", "max-body-bytes", "maximum request body size", &c.Server.MaxBodyBytes),
+
+		stringOption("DB_HOST", "db-host", "database host", &c.Database.Host),
+		intOption("DB_PORT", "db-port", "database port", &c.Database.Port),
+		stringOption("DB_USER", "db-user", "database user", &c.Database.User),
+		stringOption("DB_PASSWORD", "db-password", "database password", &c.Database.Password),
+		stringOption("DB_NAME", "db-name", "database name", &c.Database.Name),
+		stringOption("DB_SSLMODE", "db-sslmode", "database sslmode", &c.Database.SSLMode),
+		intOption("DB_MAX_OPEN_CONNS", "db-max-open-conns", "maximum open connections (0 - unlimited)", &c.Database.MaxOpenConns),
+		intOption("DB_MAX_IDLE_CONNS", "db-max-idle-conns", "maximum idle connections", &c.Database.MaxIdleConns),
+		durationOption("DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "maximum connection lifetime", &c.Database.ConnMaxLifetime),
+		durationOption("DB_CONN_MAX_IDLE_TIME", "db-conn-max-idle-time", "maximum connection idle time", &c.Database.ConnMaxIdleTime),
+		durationOption("DB_CONNECT_TIMEOUT", "db-connect-timeout", "database connect timeout", &c.Database.ConnectTimeout),
+		durationOption("DB_QUERY_TIMEOUT", "db-query-timeout", "deadline for a single store operation", &c.Database.QueryTimeout),
+
+		stringOption("LOG_LEVEL", "log-level", "log level: DEBUG, INFO, WARN, ERROR", &c.Log.Level),
+		stringOption("OTEL_TRACES_EXPORTER", "tracing-exporter", "trace exporter: none, stdout, otlp", &c.Tracing.Exporter),
+		stringOption("OTEL_EXPORTER_OTLP_ENDPOINT", "otlp-endpoint", "OTLP/HTTP collector endpoint", &c.Tracing.OTLPEndpoint),
+
+		boolOption("AUTH_ENABLED", "auth", "require bearer tokens", &c.Auth.Enabled),
+		stringOption("AUTH_BOOTSTRAP_TOKEN", "auth-bootstrap-token", "admin token registered on startup", &c.Auth.BootstrapToken),
+		stringOption("JWT_KEY_FILE", "jwt-key-file", "JWT verification key: PEM public key, JWKS JSON or HMAC secret", &c.Auth.JWT.KeyFile),
+		stringOption("JWT_JWKS_URL", "jwt-jwks-url", "JWKS endpoint of the SSO", &c.Auth.JWT.JWKSURL),
+		durationOption("JWT_JWKS_REFRESH", "jwt-jwks-refresh", "JWKS refresh interval", &c.Auth.JWT.JWKSRefresh),
+		stringOption("JWT_ISSUER", "jwt-issuer", "expected iss claim", &c.Auth.JWT.Issuer),
+		stringOption("JWT_AUDIENCE", "jwt-audience", "expected aud claim", &c.Auth.JWT.Audience),
+		stringOption("JWT_USER_CLAIM", "jwt-user-claim", "claim with the user ID", &c.Auth.JWT.UserClaim),
+		stringOption("JWT_ROLE_CLAIM", "jwt-role-claim", "claim with the role", &c.Auth.JWT.RoleClaim),
+		durationOption("JWT_LEEWAY", "jwt-leeway", "allowed clock skew for exp/nbf", &c.Auth.JWT.Leeway),
+
+		boolOption("RATE_LIMIT_ENABLED", "rate-limit", "limit requests per client", &c.RateLimit.Enabled),
+		floatOption("RATE_LIMIT_READ_RPS", "rate-limit-read-rps", "read routes: requests per second", &c.RateLimit.Read.RPS),
+		intOption("RATE_LIMIT_READ_BURST", "rate-limit-read-burst", "read routes: burst size", &c.RateLimit.Read.Burst),
+		floatOption("RATE_LIMIT_WRITE_RPS", "rate-limit-write-rps", "pull request routes: requests per second", &c.RateLimit.Write.RPS),
+		intOption("RATE_LIMIT_WRITE_BURST", "rate-limit-write-burst", "pull request routes: burst size", &c.RateLimit.Write.Burst),
+		floatOption("RATE_LIMIT_ADMIN_RPS", "rate-limit-admin-rps", "admin routes: requests per second", &c.RateLimit.Admin.RPS),
+		intOption("RATE_LIMIT_ADMIN_BURST", "rate-limit-admin-burst", "admin routes: burst size", &c.RateLimit.Admin.Burst),
+		floatOption("RATE_LIMIT_IP_RPS", "rate-limit-ip-rps", "requests per second from one IP before authentication", &c.RateLimit.IP.RPS),
+		intOption("RATE_LIMIT_IP_BURST", "rate-limit-ip-burst", "burst size per IP before authentication", &c.RateLimit.IP.Burst),
+		listOption("RATE_LIMIT_TRUSTED_PROXIES", "rate-limit-trusted-proxies", "comma-separated proxy addresses or CIDRs whose X-Forwarded-For is trusted", &c.RateLimit.TrustedProxies),
+
+		boolOption("WEBHOOKS_ENABLED", "webhooks", "run the webhook delivery worker", &c.Webhooks.Enabled),
+		durationOption("WEBHOOK_POLL_INTERVAL", "webhook-poll-interval", "how often to poll the delivery queue", &c.Webhooks.PollInterval),
+		intOption("WEBHOOK_BATCH_SIZE", "webhook-batch-size", "deliveries taken per poll", &c.Webhooks.BatchSize),
+		durationOption("WEBHOOK_TIMEOUT", "webhook-timeout", "timeout of a single delivery attempt", &c.Webhooks.Timeout),
+		intOption("WEBHOOK_MAX_ATTEMPTS", "webhook-max-attempts", "attempts before a delivery is marked failed", &c.Webhooks.MaxAttempts),
+		durationOption("WEBHOOK_BACKOFF_BASE", "webhook-backoff-base", "delay before the first retry", &c.Webhooks.BackoffBase),
+		durationOption("WEBHOOK_BACKOFF_MAX", "webhook-backoff-max", "maximum delay between retries", &c.Webhooks.BackoffMax),
+
+		boolOption("OUTBOX_ENABLED", "outbox", "run the outbox dispatcher", &c.Outbox.Enabled),
+		listOption("OUTBOX_SINKS", "outbox-sinks", "comma-separated sinks: webhook, stdout, file, codehost, chat", &c.Outbox.Sinks),
+		stringOption("OUTBOX_FILE_PATH", "outbox-file-path", "JSON Lines file for the file sink", &c.Outbox.FilePath),
+		durationOption("OUTBOX_POLL_INTERVAL", "outbox-poll-interval", "how often to poll the outbox", &c.Outbox.PollInterval),
+		intOption("OUTBOX_BATCH_SIZE", "outbox-batch-size", "events taken per poll", &c.Outbox.BatchSize),
+		intOption("OUTBOX_MAX_ATTEMPTS", "outbox-max-attempts", "attempts before an event is moved to dead", &c.Outbox.MaxAttempts),
+		durationOption("OUTBOX_BACKOFF_BASE", "outbox-backoff-base", "delay before the first retry", &c.Outbox.BackoffBase),
+		durationOption("OUTBOX_BACKOFF_MAX", "outbox-backoff-max", "maximum delay between retries", &c.Outbox.BackoffMax),
+		durationOption("OUTBOX_RETENTION", "outbox-retention", "how long published events are kept, 0 keeps them forever", &c.Outbox.Retention),
+
+		boolOption("STREAM_ENABLED", "stream", "serve the /users/events SSE stream", &c.Stream.Enabled),
+		durationOption("STREAM_HEARTBEAT", "stream-heartbeat", "interval of SSE keep-alive comments", &c.Stream.Heartbeat),
+
+		stringOption("GITHUB_WEBHOOK_SECRET", "github-webhook-secret", "secret of the GitHub webhook, enables /integrations/github/webhook", &c.Integrations.GitHub.WebhookSecret),
+		stringOption("GITLAB_WEBHOOK_TOKEN", "gitlab-webhook-token", "secret token of the GitLab webhook, enables /integrations/gitlab/webhook", &c.Integrations.GitLab.WebhookToken),
+		stringOption("GITHUB_API_URL", "github-api-url", "GitHub REST API base URL", &c.Integrations.GitHub.APIURL),
+		stringOption("GITHUB_TOKEN", "github-token", "GitHub token used by the codehost sink to request reviewers", &c.Integrations.GitHub.Token),
+		stringOption("GITLAB_API_URL", "gitlab-api-url", "GitLab REST API base URL", &c.Integrations.GitLab.APIURL),
+		stringOption("GITLAB_TOKEN", "gitlab-token", "GitLab token used by the codehost sink to set reviewers and to resolve merge request authors", &c.Integrations.GitLab.Token),
+		boolOption("NOTIFICATIONS_ENABLED", "notifications", "run the chat notification worker", &c.Notifications.Enabled),
+		durationOption("NOTIFICATION_POLL_INTERVAL", "notification-poll-interval", "how often to poll the notification queue", &c.Notifications.PollInterval),
+		intOption("NOTIFICATION_BATCH_SIZE", "notification-batch-size", "messages taken per poll", &c.Notifications.BatchSize),
+		durationOption("NOTIFICATION_TIMEOUT", "notification-timeout", "timeout of a single message delivery", &c.Notifications.Timeout),
+		intOption("NOTIFICATION_MAX_ATTEMPTS", "notification-max-attempts", "attempts before a message is marked failed", &c.Notifications.MaxAttempts),
+		durationOption("NOTIFICATION_BACKOFF_BASE", "notification-backoff-base", "delay before the first retry", &c.Notifications.BackoffBase),
+		durationOption("NOTIFICATION_BACKOFF_MAX", "notification-backoff-max", "maximum delay between retries", &c.Notifications.BackoffMax),
+		durationOption("REVIEW_OVERDUE_AFTER", "review-overdue-after", "time after assignment when a review is overdue, 0 disables the check", &c.Notifications.OverdueAfter),
+		durationOption("REVIEW_OVERDUE_CHECK_INTERVAL", "review-overdue-check-interval", "how often to look for overdue reviews", &c.Notifications.OverdueCheckInterval),
+
+		boolOption("METRICS_ENABLED", "metrics", "expose /metrics", &c.Features.Metrics),
+	}
+}
+
+func stringOption(env, flag, usage string, target *string) option {
+	return option{env: env, flag: flag, usage: usage, set: func(value string) error {
+		*target = value
+		return nil
+	}}
+}
+
+func intOption(env, flag, usage string, target *int) option {
+	return option{env: env, flag: flag, usage: usage, set: func(value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		*target = parsed
+		return nil
+	}}
+}
+
+func floatOption(env, flag, usage string, target *float64) option {
+	return option{env: env, flag: flag, usage: usage, set: func(value string) error {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*target = parsed
+		return nil
+	}}
+}
+
+func boolOption(env, flag, usage string, target *bool) option {
+	return option{env: env, flag: flag, usage: usage, isBool: true, set: func(value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		*target = parsed
+		return nil
+	}}
+}
+
+// listOption разбирает список через запятую; пустое значение означает пустой список
+func listOption(env, flag, usage string, target *[]string) option {
+	return option{env: env, flag: flag, usage: usage, set: func(value string) error {
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*target = items
+		return nil
+	}}
+}
+
+func durationOption(env, flag, usage string, target *time.Duration) option {
+	return option{env: env, flag: flag, usage: usage, set: func(value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration (e.g. 5s, 1m)", value)
+		}
+		*target = parsed
+		return nil
+	}}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// validConfig - значения по умолчанию с обязательными параметрами базы
+func validConfig() *Config {
+	cfg := Default()
+	cfg.Database.Host = "localhost"
+	cfg.Database.User = "postgres"
+	cfg.Database.Name = "reviewer"
+	return cfg
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateNotificationsNeedChatSink(t *testing.T) {
+	tests := []struct {
+		name          string
+		sinks         []string
+		notifications bool
+		outbox        bool
+		wantErr       bool
+	}{
+		{"default sinks", []string{SinkWebhook, SinkChat}, true, true, false},
+		{"chat sink missing", []string{SinkWebhook}, true, true, true},
+		{"notifications disabled", []string{SinkWebhook}, false, true, false},
+		{"outbox disabled", []string{SinkWebhook}, true, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Outbox.Sinks = tt.sinks
+			cfg.Outbox.Enabled = tt.outbox
+			cfg.Notifications.Enabled = tt.notifications
+
+			err := cfg.Validate()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "outbox.sinks must include chat") {
+					t.Fatalf("err = %v, want chat sink error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		wantErr bool
+	}{
+		{"cidr and address", []string{"10.0.0.0/8", "192.0.2.10", "2001:db8::/32"}, false},
+		{"hostname", []string{"proxy.internal"}, true},
+		{"bad prefix length", []string{"10.0.0.0/33"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.RateLimit.TrustedProxies = tt.proxies
+
+			err := cfg.Validate()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "rate_limit.trusted_proxies") {
+					t.Fatalf("err = %v, want trusted_proxies error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.RateLimit.TrustedProxyPrefixes(); len(got) != len(tt.proxies) {
+				t.Errorf("prefixes = %v, want %d", got, len(tt.proxies))
+			}
+		})
+	}
+}
+
+func TestLoadFlags(t *testing.T) {
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "postgres")
+	t.Setenv("DB_NAME", "reviewer")
+	t.Setenv("AUTH_ENABLED", "false")
+	t.Setenv("METRICS_ENABLED", "true")
+
+	tests := []struct {
+		name    string
+		args    []string
+		auth    bool
+		metrics bool
+	}{
+		{"env only", nil, false, true},
+		{"bare bool flag", []string{"-auth"}, true, true},
+		{"bool flag with value", []string{"-auth=true", "-metrics=false"}, true, false},
+		{"bare bool flag before other flags", []string{"-auth", "-outbox-batch-size", "10"}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Auth.Enabled != tt.auth || cfg.Features.Metrics != tt.metrics {
+				t.Errorf("auth = %v, metrics = %v, want %v, %v", cfg.Auth.Enabled, cfg.Features.Metrics, tt.auth, tt.metrics)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsInvalidBoolFlag(t *testing.T) {
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "postgres")
+	t.Setenv("DB_NAME", "reviewer")
+
+	_, err := Load([]string{"-auth=maybe"})
+	if err == nil || !strings.Contains(err.Error(), "flag -auth") {
+		t.Fatalf("err = %v, want flag -auth error", err)
+	}
+}
@@ -0,0 +1,214 @@
+// Package delivery - воркер очередей HTTP-доставок в БД (вебхуки подписчиков, чат-уведомления).
+// Доставки арендуются на время попытки, отправляются пачкой параллельно, а неудачные
+// повторяются с экспоненциальной задержкой.
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"antonvedaet/internship_task/internal/tracing"
+)
+
+// leaseMargin добавляется к таймауту попытки, чтобы доставку не взяла другая реплика, пока идёт запрос
+const leaseMargin = 30 * time.Second
+
+// maxErrorBodySize - сколько байт ответа получателя сохраняется в last_error
+const maxErrorBodySize = 512
+
+// Backoff - base, 2*base, 4*base... но не больше max; attempts - число неудачных попыток, от 1
+func Backoff(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	return min(delay, max)
+}
+
+// Retryable - может ли повтор исправить неудачную попытку: сетевая ошибка (statusCode 0), 5xx,
+// 408 или 429. Остальные 4xx означают удалённый или неверный адрес, и повтор их не исправит.
+func Retryable(statusCode int) bool {
+	return statusCode == 0 || statusCode >= 500 ||
+		statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests
+}
+
+// Config - параметры очереди
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	Timeout      time.Duration
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+}
+
+// Task - общие поля доставки
+type Task struct {
+	ID       int64
+	Attempts int
+	// Kind - тип события или вид уведомления: метка метрик, логов и трассировки
+	Kind string
+}
+
+// Queue - очередь доставок одного вида; T - доставка со всем, что нужно для запроса
+type Queue[T any] interface {
+	// Claim берёт до limit доставок, срок которых наступил, и арендует их на lease
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]T, error)
+	Task(delivery T) Task
+	NewRequest(ctx context.Context, delivery T) (*http.Request, error)
+	MarkDelivered(ctx context.Context, id int64, statusCode int) error
+	// MarkAttemptFailed с nextAttemptAt == nil завершает доставку статусом failed
+	MarkAttemptFailed(ctx context.Context, id int64, statusCode int, lastError string, nextAttemptAt *time.Time) error
+	// Observe считает попытку в метриках; result - delivered, retry или failed
+	Observe(task Task, result string)
+}
+
+// Worker разбирает очередь: отправляет запросы и планирует повторы
+type Worker[T any] struct {
+	name   string
+	queue  Queue[T]
+	client *http.Client
+	cfg    Config
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// NewWorker - name попадает в логи и трассировку: "webhook", "notification"
+func NewWorker[T any](name string, queue Queue[T], cfg Config, logger *slog.Logger) *Worker[T] {
+	return &Worker[T]{
+		name:  name,
+		queue: queue,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// редирект считается ошибкой: получатель должен указать итоговый адрес
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg:    cfg,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Run работает до отмены ctx. Недоставленные на момент остановки доставки
+// вернутся в очередь по истечении аренды и будут отправлены повторно.
+func (w *Worker[T]) Run(ctx context.Context) {
+	w.logger.Info(w.name+" dispatcher started", "poll_interval", w.cfg.PollInterval)
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.Drain(ctx)
+		select {
+		case <-ctx.Done():
+			w.logger.Info(w.name + " dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain отправляет пачки, пока очередь не опустеет
+func (w *Worker[T]) Drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := w.queue.Claim(ctx, w.cfg.BatchSize, w.cfg.Timeout+leaseMargin)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.logger.ErrorContext(ctx, "claim "+w.name+" deliveries", "error", err)
+			}
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w.deliver(ctx, delivery)
+			}()
+		}
+		wg.Wait()
+
+		if len(deliveries) < w.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (w *Worker[T]) deliver(ctx context.Context, delivery T) {
+	task := w.queue.Task(delivery)
+	ctx, span := tracing.Start(ctx, "Delivery.Deliver")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("delivery.queue", w.name),
+		attribute.Int64("delivery.id", task.ID),
+		attribute.String("delivery.kind", task.Kind),
+	)
+
+	statusCode, err := w.send(ctx, delivery)
+	if ctx.Err() != nil {
+		// остановка сервиса: попытка не засчитывается, доставка вернётся в очередь после аренды
+		return
+	}
+
+	if err == nil {
+		w.queue.Observe(task, "delivered")
+		if err := w.queue.MarkDelivered(ctx, task.ID, statusCode); err != nil {
+			w.logger.ErrorContext(ctx, "mark "+w.name+" delivered", "delivery_id", task.ID, "error", err)
+		}
+		return
+	}
+
+	span.SetStatus(codes.Error, err.Error())
+	attempts := task.Attempts + 1
+	var nextAttemptAt *time.Time
+	result := "failed"
+	if Retryable(statusCode) && attempts < w.cfg.MaxAttempts {
+		next := w.now().Add(Backoff(w.cfg.BackoffBase, w.cfg.BackoffMax, attempts))
+		nextAttemptAt, result = &next, "retry"
+	}
+	w.queue.Observe(task, result)
+
+	w.logger.WarnContext(ctx, w.name+" delivery failed",
+		"delivery_id", task.ID,
+		"kind", task.Kind,
+		"attempts", attempts,
+		"status_code", statusCode,
+		"error", err,
+		"next_attempt_at", nextAttemptAt,
+	)
+	if err := w.queue.MarkAttemptFailed(ctx, task.ID, statusCode, err.Error(), nextAttemptAt); err != nil {
+		w.logger.ErrorContext(ctx, "mark "+w.name+" attempt failed", "delivery_id", task.ID, "error", err)
+	}
+}
+
+func (w *Worker[T]) send(ctx context.Context, delivery T) (int, error) {
+	req, err := w.queue.NewRequest(ctx, delivery)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+		return resp.StatusCode, nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	return resp.StatusCode, fmt.Errorf("receiver returned %s: %s", resp.Status, bytes.TrimSpace(body))
+}
@@ -0,0 +1,229 @@
+package delivery
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{10, 5 * time.Minute},
+		// удвоение останавливается на max и не переполняется
+		{200, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(10*time.Second, 5*time.Minute, tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		want       bool
+	}{
+		{0, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusBadGateway, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusRequestTimeout, true},
+		{http.StatusBadRequest, false},
+		{http.StatusNotFound, false},
+		{http.StatusGone, false},
+		{http.StatusFound, false},
+	}
+
+	for _, tt := range tests {
+		if got := Retryable(tt.statusCode); got != tt.want {
+			t.Errorf("Retryable(%d) = %v, want %v", tt.statusCode, got, tt.want)
+		}
+	}
+}
+
+type testDelivery struct {
+	id       int64
+	attempts int
+	url      string
+}
+
+type failedAttempt struct {
+	statusCode    int
+	nextAttemptAt *time.Time
+}
+
+// fakeQueue отдаёт доставки одной пачкой и записывает результаты попыток
+type fakeQueue struct {
+	deliveries []testDelivery
+	claims     []time.Duration
+
+	mu        sync.Mutex
+	delivered map[int64]int
+	failed    map[int64]failedAttempt
+	observed  map[string]int
+}
+
+func (q *fakeQueue) Claim(_ context.Context, _ int, lease time.Duration) ([]testDelivery, error) {
+	q.claims = append(q.claims, lease)
+	deliveries := q.deliveries
+	q.deliveries = nil
+	return deliveries, nil
+}
+
+func (q *fakeQueue) Task(d testDelivery) Task {
+	return Task{ID: d.id, Attempts: d.attempts, Kind: "test"}
+}
+
+func (q *fakeQueue) NewRequest(ctx context.Context, d testDelivery) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, http.MethodPost, d.url, strings.NewReader(`{}`))
+}
+
+func (q *fakeQueue) MarkDelivered(_ context.Context, id int64, statusCode int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.delivered[id] = statusCode
+	return nil
+}
+
+func (q *fakeQueue) MarkAttemptFailed(_ context.Context, id int64, statusCode int, _ string, nextAttemptAt *time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.failed[id] = failedAttempt{statusCode: statusCode, nextAttemptAt: nextAttemptAt}
+	return nil
+}
+
+func (q *fakeQueue) Observe(_ Task, result string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.observed[result]++
+}
+
+func TestWorkerDrain(t *testing.T) {
+	// путь запроса - код ответа получателя
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusNoContent)
+		case "/down":
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+		case "/gone":
+			http.Error(w, "no such hook", http.StatusGone)
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 10, 24, 12, 0, 0, 0, time.UTC)
+	queue := &fakeQueue{
+		deliveries: []testDelivery{
+			{id: 1, url: server.URL + "/ok"},
+			{id: 2, attempts: 2, url: server.URL + "/down"},
+			{id: 3, url: server.URL + "/gone"},
+			{id: 4, attempts: 4, url: server.URL + "/down"},
+			{id: 5, url: server.URL + "/redirect"},
+			{id: 6, url: "http://127.0.0.1:1/unreachable"},
+		},
+		delivered: map[int64]int{},
+		failed:    map[int64]failedAttempt{},
+		observed:  map[string]int{},
+	}
+	w := NewWorker[testDelivery]("test", queue, Config{
+		BatchSize:   10,
+		Timeout:     5 * time.Second,
+		MaxAttempts: 5,
+		BackoffBase: 10 * time.Second,
+		BackoffMax:  time.Hour,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	w.now = func() time.Time { return now }
+
+	w.Drain(context.Background())
+
+	if len(queue.claims) != 1 || queue.claims[0] != 5*time.Second+leaseMargin {
+		t.Errorf("claims with lease %v, want one with timeout + leaseMargin", queue.claims)
+	}
+	if code, ok := queue.delivered[1]; !ok || code != http.StatusNoContent {
+		t.Errorf("delivery 1 delivered = %v (%d), want 204", ok, code)
+	}
+
+	retryAt := func(d time.Duration) *time.Time {
+		at := now.Add(d)
+		return &at
+	}
+	tests := []struct {
+		id            int64
+		statusCode    int
+		nextAttemptAt *time.Time
+	}{
+		// третья попытка: 10s * 2 * 2
+		{2, http.StatusServiceUnavailable, retryAt(40 * time.Second)},
+		// 4xx не повторяется
+		{3, http.StatusGone, nil},
+		// пятая попытка - последняя
+		{4, http.StatusServiceUnavailable, nil},
+		// редирект не выполняется и считается отказом получателя
+		{5, http.StatusFound, nil},
+		// сетевая ошибка повторяется
+		{6, 0, retryAt(10 * time.Second)},
+	}
+	for _, tt := range tests {
+		got, ok := queue.failed[tt.id]
+		if !ok {
+			t.Errorf("delivery %d: no failed attempt recorded", tt.id)
+			continue
+		}
+		if got.statusCode != tt.statusCode {
+			t.Errorf("delivery %d: status code = %d, want %d", tt.id, got.statusCode, tt.statusCode)
+		}
+		switch {
+		case tt.nextAttemptAt == nil && got.nextAttemptAt != nil:
+			t.Errorf("delivery %d: retry at %v, want failed", tt.id, got.nextAttemptAt)
+		case tt.nextAttemptAt != nil && (got.nextAttemptAt == nil || !got.nextAttemptAt.Equal(*tt.nextAttemptAt)):
+			t.Errorf("delivery %d: retry at %v, want %v", tt.id, got.nextAttemptAt, tt.nextAttemptAt)
+		}
+	}
+
+	if queue.observed["delivered"] != 1 || queue.observed["retry"] != 2 || queue.observed["failed"] != 3 {
+		t.Errorf("observed %v, want delivered 1, retry 2, failed 3", queue.observed)
+	}
+}
+
+// при остановке попытка не засчитывается: доставка вернётся в очередь после аренды
+func TestWorkerDrainStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	queue := &fakeQueue{
+		deliveries: []testDelivery{{id: 1, url: server.URL}},
+		delivered:  map[int64]int{},
+		failed:     map[int64]failedAttempt{},
+		observed:   map[string]int{},
+	}
+	w := NewWorker[testDelivery]("test", queue, Config{BatchSize: 10, Timeout: 5 * time.Second, MaxAttempts: 5}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	w.Drain(ctx)
+
+	if len(queue.delivered) != 0 || len(queue.failed) != 0 {
+		t.Errorf("stopped attempt recorded: delivered %v, failed %v", queue.delivered, queue.failed)
+	}
+}
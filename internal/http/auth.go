@@ -0,0 +1,74 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"antonvedaet/internship_task/internal/auth"
+	"antonvedaet/internship_task/internal/http/handlers"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/service"
+)
+
+// authenticator проверяет заголовок Authorization: Bearer <token> и роль вызывающего.
+// Токен принимается либо как JWT от SSO (если jwt настроен), либо как статический API-токен.
+type authenticator struct {
+	tokens  service.TokenService
+	jwt     *auth.JWTVerifier
+	enabled bool
+	logger  *slog.Logger
+}
+
+// require пропускает запрос, только если роль токена входит в roles.
+// Пустой roles означает публичный маршрут.
+func (a *authenticator) require(roles []string, next http.Handler) http.Handler {
+	if !a.enabled || len(roles) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pr-reviewer"`)
+			handlers.SendServiceError(w, r, a.logger, err, "authenticating request")
+			return
+		}
+
+		if !slices.Contains(roles, principal.Role) {
+			a.logger.WarnContext(r.Context(), "access denied", "token_id", principal.TokenID, "role", principal.Role)
+			handlers.SendServiceError(w, r, a.logger, service.ErrForbidden, "authorizing request")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func (a *authenticator) authenticate(r *http.Request) (*models.Principal, error) {
+	token := bearerToken(r)
+	if a.jwt == nil || !auth.LooksLikeJWT(token) {
+		return a.tokens.Authenticate(r.Context(), token)
+	}
+
+	principal, err := a.jwt.Verify(r.Context(), token)
+	if err != nil {
+		level := slog.LevelDebug
+		if errors.Is(err, auth.ErrKeyUnavailable) {
+			level = slog.LevelWarn
+		}
+		a.logger.Log(r.Context(), level, "jwt rejected", "error", err)
+		return nil, service.ErrUnauthorized
+	}
+	return principal, nil
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/service"
+)
+
+// Коды ошибок уровня HTTP, которых нет среди ошибок сервисов
+const (
+	codeMethodNotAllowed service.Code = "METHOD_NOT_ALLOWED"
+	codeTimeout          service.Code = "TIMEOUT"
+	codeCanceled         service.Code = "REQUEST_CANCELED"
+	codeInternal         service.Code = "INTERNAL_ERROR"
+	codeRateLimited      service.Code = "RATE_LIMITED"
+	codeTooLarge         service.Code = "REQUEST_TOO_LARGE"
+)
+
+// statusClientClosedRequest - нестандартный статус nginx для запросов, отменённых клиентом
+const statusClientClosedRequest = 499
+
+var statusByCode = map[service.Code]int{
+	service.CodeInvalidRequest:  http.StatusBadRequest,
+	service.CodeNotFound:        http.StatusNotFound,
+	service.CodeTeamExists:      http.StatusBadRequest,
+	service.CodePRExists:        http.StatusConflict,
+	service.CodePRMerged:        http.StatusConflict,
+	service.CodeNotAssigned:     http.StatusConflict,
+	service.CodeNoCandidate:     http.StatusConflict,
+	service.CodeUserInTeam:      http.StatusConflict,
+	service.CodeUserInOtherTeam: http.StatusConflict,
+	service.CodeTeamHasOpenPRs:  http.StatusConflict,
+	service.CodeTeamHasChildren: http.StatusConflict,
+	service.CodeUnauthorized:    http.StatusUnauthorized,
+	service.CodeForbidden:       http.StatusForbidden,
+	service.CodeEventNotDead:    http.StatusConflict,
+	service.CodePRChanged:       http.StatusConflict,
+}
+
+// sendServiceError - единственное место, где ошибка сервиса превращается в HTTP-ответ.
+// action попадает в лог для внутренних ошибок, клиенту их текст не отдаётся.
+func (h *Handlers) sendServiceError(w http.ResponseWriter, r *http.Request, err error, action string) {
+	SendServiceError(w, r, h.logger, err, action)
+}
+
+// SendServiceError доступен middleware, чтобы их ошибки выглядели так же, как ошибки обработчиков
+func SendServiceError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, err error, action string) {
+	var domainErr *service.Error
+	switch {
+	case errors.As(err, &domainErr):
+		status, ok := statusByCode[domainErr.Code]
+		if !ok {
+			status = http.StatusBadRequest
+		}
+		sendErrorResponse(w, domainErr.Code, domainErr.Message, status)
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.WarnContext(r.Context(), "timeout "+action, "error", err)
+		sendErrorResponse(w, codeTimeout, "request timed out", http.StatusGatewayTimeout)
+	case errors.Is(err, context.Canceled):
+		logger.InfoContext(r.Context(), "canceled "+action, "error", err)
+		sendErrorResponse(w, codeCanceled, "request canceled", statusClientClosedRequest)
+	default:
+		logger.ErrorContext(r.Context(), "error "+action, "error", err)
+		sendErrorResponse(w, codeInternal, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handlers) sendErrorResponse(w http.ResponseWriter, code service.Code, message string, statusCode int) {
+	sendErrorResponse(w, code, message, statusCode)
+}
+
+func sendErrorResponse(w http.ResponseWriter, code service.Code, message string, statusCode int) {
+	var response models.ErrorResponse
+	response.Error.Code = string(code)
+	response.Error.Message = message
+	response.Error.RequestID = w.Header().Get(RequestIDHeader)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+// SendRateLimited отвечает 429, заголовок Retry-After выставляет вызывающий
+func SendRateLimited(w http.ResponseWriter) {
+	sendErrorResponse(w, codeRateLimited, "too many requests", http.StatusTooManyRequests)
+}
+
+// SendRouteNotFound отвечает 404 на путь, которого нет среди маршрутов
+func SendRouteNotFound(w http.ResponseWriter) {
+	sendErrorResponse(w, service.CodeNotFound, "route not found", http.StatusNotFound)
+}
+
+// SendMethodNotAllowed отвечает 405, заголовок Allow выставляет вызывающий
+func SendMethodNotAllowed(w http.ResponseWriter) {
+	sendErrorResponse(w, codeMethodNotAllowed, "method not allowed", http.StatusMethodNotAllowed)
+}
+
+// SendBodyTooLarge отвечает 413, если тело превысило лимит http.MaxBytesReader
+func SendBodyTooLarge(w http.ResponseWriter, err *http.MaxBytesError) {
+	sendErrorResponse(w, codeTooLarge, fmt.Sprintf("request body exceeds %d bytes", err.Limit), http.StatusRequestEntityTooLarge)
+}
+
+// SendValidationError отвечает VALIDATION_ERROR с ошибками по отдельным полям
+func SendValidationError(w http.ResponseWriter, details []models.FieldError) {
+	var response models.ErrorResponse
+	response.Error.Code = string(service.CodeValidation)
+	response.Error.Message = "request validation failed"
+	response.Error.RequestID = w.Header().Get(RequestIDHeader)
+	response.Error.Details = details
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(response)
+}
@@ -2,52 +2,71 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"antonvedaet/internship_task/internal/auth"
 	"antonvedaet/internship_task/internal/models"
 	"antonvedaet/internship_task/internal/service"
 )
 
+// RequestIDHeader выставляется middleware до вызова обработчика и дублируется в теле ошибок
+const RequestIDHeader = "X-Request-ID"
+
 type Handlers struct {
-	teamService service.TeamService
-	userService service.UserService
-	prService   service.PRService
+	teamService         service.TeamService
+	userService         service.UserService
+	prService           service.PRService
+	statsService        service.StatsService
+	healthService       service.HealthService
+	tokenService        service.TokenService
+	webhookService      service.WebhookService
+	outboxService       service.OutboxService
+	streamService       service.StreamService
+	integrationService  service.IntegrationService
+	notificationService service.NotificationService
+	logger              *slog.Logger
+
+	// streamHeartbeat - интервал комментариев-пингов в SSE-потоке
+	streamHeartbeat time.Duration
 }
 
-func NewHandlers(teamService service.TeamService, userService service.UserService, prService service.PRService) *Handlers {
+func NewHandlers(teamService service.TeamService, userService service.UserService, prService service.PRService, statsService service.StatsService, healthService service.HealthService, tokenService service.TokenService, webhookService service.WebhookService, outboxService service.OutboxService, streamService service.StreamService, integrationService service.IntegrationService, notificationService service.NotificationService, streamHeartbeat time.Duration, logger *slog.Logger) *Handlers {
 	return &Handlers{
-		teamService: teamService,
-		userService: userService,
-		prService:   prService,
+		teamService:         teamService,
+		userService:         userService,
+		prService:           prService,
+		statsService:        statsService,
+		healthService:       healthService,
+		tokenService:        tokenService,
+		webhookService:      webhookService,
+		outboxService:       outboxService,
+		streamService:       streamService,
+		integrationService:  integrationService,
+		notificationService: notificationService,
+		logger:              logger,
+
+		streamHeartbeat: streamHeartbeat,
 	}
 }
 
 func (h *Handlers) AddTeam(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var team models.Team
-	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if !h.decodeJSON(w, r, &team) {
 		return
 	}
 
 	if team.TeamName == "" {
-		h.sendErrorResponse(w, "INVALID_REQUEST", "team_name is required", http.StatusBadRequest)
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "team_name is required", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.teamService.CreateTeam(&team); err != nil {
-		if strings.Contains(err.Error(), "unique constraint") || err == service.ErrTeamExists {
-			h.sendErrorResponse(w, "TEAM_EXISTS", "team_name already exists", http.StatusBadRequest)
-		} else {
-			log.Printf("Error creating team: %v", err)
-			h.sendError(w, "Internal server error", http.StatusInternalServerError)
-		}
+	if err := h.teamService.CreateTeam(r.Context(), &team); err != nil {
+		h.sendServiceError(w, r, err, "creating team")
 		return
 	}
 
@@ -57,25 +76,25 @@ func (h *Handlers) AddTeam(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) GetTeam(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	teamName := r.URL.Query().Get("team_name")
 	if teamName == "" {
-		h.sendErrorResponse(w, "INVALID_REQUEST", "team_name is required", http.StatusBadRequest)
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "team_name is required", http.StatusBadRequest)
 		return
 	}
 
-	team, err := h.teamService.GetTeam(teamName)
-	if err != nil {
-		if err == service.ErrNotFound {
-			h.sendErrorResponse(w, "NOT_FOUND", "team not found", http.StatusNotFound)
-		} else {
-			log.Printf("Error getting team: %v", err)
-			h.sendError(w, "Internal server error", http.StatusInternalServerError)
+	includeDescendants := false
+	if value := r.URL.Query().Get("include_descendants"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			h.sendErrorResponse(w, service.CodeInvalidRequest, "include_descendants must be true or false", http.StatusBadRequest)
+			return
 		}
+		includeDescendants = parsed
+	}
+
+	team, err := h.teamService.GetTeam(r.Context(), teamName, includeDescendants)
+	if err != nil {
+		h.sendServiceError(w, r, err, "getting team")
 		return
 	}
 
@@ -83,27 +102,77 @@ func (h *Handlers) GetTeam(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(team)
 }
 
-func (h *Handlers) DeactivateTeamUsers(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+func (h *Handlers) ListTeams(w http.ResponseWriter, r *http.Request) {
+	limit, ok := h.parseLimit(w, r)
+	if !ok {
+		return
+	}
+
+	teams, nextCursor, err := h.teamService.ListTeams(r.Context(), models.TeamListQuery{
+		Limit:  limit,
+		Cursor: r.URL.Query().Get("cursor"),
+	})
+	if err != nil {
+		h.sendServiceError(w, r, err, "listing teams")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TeamListResponse{Teams: teams, NextCursor: nextCursor})
+}
+
+func (h *Handlers) UpdateTeam(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateTeamRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.TeamName == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "team_name is required", http.StatusBadRequest)
+		return
+	}
+
+	team, err := h.teamService.UpdateTeam(r.Context(), &req)
+	if err != nil {
+		h.sendServiceError(w, r, err, "updating team")
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TeamResponse{Team: team})
+}
+
+func (h *Handlers) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "team_name is required", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.teamService.DeleteTeam(r.Context(), teamName)
+	if err != nil {
+		h.sendServiceError(w, r, err, "deleting team")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) DeactivateTeamUsers(w http.ResponseWriter, r *http.Request) {
 	var req models.DeactivateTeamRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
 	if req.TeamName == "" {
-		h.sendErrorResponse(w, "INVALID_REQUEST", "team_name is required", http.StatusBadRequest)
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "team_name is required", http.StatusBadRequest)
 		return
 	}
 
-	deactivatedCount, err := h.teamService.DeactivateTeamUsers(req.TeamName)
+	deactivatedCount, err := h.teamService.DeactivateTeamUsers(r.Context(), req.TeamName, req.IncludeDescendants)
 	if err != nil {
-		log.Printf("Error deactivating team users: %v", err)
-		h.sendError(w, "Internal server error", http.StatusInternalServerError)
+		h.sendServiceError(w, r, err, "deactivating team users")
 		return
 	}
 
@@ -116,56 +185,144 @@ func (h *Handlers) DeactivateTeamUsers(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func (h *Handlers) SetUserActive(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+func (h *Handlers) AddTeamMember(w http.ResponseWriter, r *http.Request) {
+	var req models.AddMemberRequest
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
-	var req models.SetActiveRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if req.TeamName == "" || req.UserID == "" || req.Username == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "team_name, user_id and username are required", http.StatusBadRequest)
 		return
 	}
 
-	user, err := h.userService.SetUserActive(req.UserID, req.IsActive)
+	user, err := h.teamService.AddMember(r.Context(), &req)
 	if err != nil {
-		if err == service.ErrNotFound {
-			h.sendErrorResponse(w, "NOT_FOUND", "user not found", http.StatusNotFound)
-		} else {
-			log.Printf("Error setting user active: %v", err)
-			h.sendError(w, "Internal server error", http.StatusInternalServerError)
+		h.sendServiceError(w, r, err, "changing team membership")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.MembershipResponse{User: user, ReassignedReviews: []models.ReassignedReview{}})
+}
+
+func (h *Handlers) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	var req models.RemoveMemberRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.TeamName == "" || req.UserID == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "team_name and user_id are required", http.StatusBadRequest)
+		return
+	}
+
+	user, reassigned, err := h.teamService.RemoveMember(r.Context(), &req)
+	if err != nil {
+		h.sendServiceError(w, r, err, "changing team membership")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.MembershipResponse{User: user, ReassignedReviews: reassigned})
+}
+
+func (h *Handlers) MoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	var req models.MoveMemberRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.TeamName == "" || req.UserID == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "team_name and user_id are required", http.StatusBadRequest)
+		return
+	}
+
+	user, reassigned, err := h.teamService.MoveMember(r.Context(), &req)
+	if err != nil {
+		h.sendServiceError(w, r, err, "changing team membership")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.MembershipResponse{User: user, ReassignedReviews: reassigned})
+}
+
+func (h *Handlers) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userService.GetUser(r.Context(), userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "getting user")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.UserResponse{User: user})
+}
+
+func (h *Handlers) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := models.UserListQuery{
+		TeamName: r.URL.Query().Get("team_name"),
+		Username: r.URL.Query().Get("username"),
+		Cursor:   r.URL.Query().Get("cursor"),
+	}
+
+	if isActive := r.URL.Query().Get("is_active"); isActive != "" {
+		value, err := strconv.ParseBool(isActive)
+		if err != nil {
+			h.sendErrorResponse(w, service.CodeInvalidRequest, "is_active must be true or false", http.StatusBadRequest)
+			return
 		}
+		query.IsActive = &value
+	}
+
+	limit, ok := h.parseLimit(w, r)
+	if !ok {
+		return
+	}
+	query.Limit = limit
+
+	users, nextCursor, err := h.userService.ListUsers(r.Context(), query)
+	if err != nil {
+		h.sendServiceError(w, r, err, "listing users")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(models.SetActiveResponse{User: user})
+	json.NewEncoder(w).Encode(models.UserListResponse{Users: users, NextCursor: nextCursor})
 }
 
-func (h *Handlers) CreatePR(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+func (h *Handlers) SetUserActive(w http.ResponseWriter, r *http.Request) {
+	var req models.SetActiveRequest
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
+	user, err := h.userService.SetUserActive(r.Context(), req.UserID, req.IsActive)
+	if err != nil {
+		h.sendServiceError(w, r, err, "setting user active")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.SetActiveResponse{User: user})
+}
+
+func (h *Handlers) CreatePR(w http.ResponseWriter, r *http.Request) {
 	var req models.CreatePRRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
-	pr, err := h.prService.CreatePR(&req)
+	pr, err := h.prService.CreatePR(r.Context(), &req)
 	if err != nil {
-		switch err {
-		case service.ErrPRExists:
-			h.sendErrorResponse(w, "PR_EXISTS", "PR id already exists", http.StatusConflict)
-		case service.ErrNotFound:
-			h.sendErrorResponse(w, "NOT_FOUND", "author/team not found", http.StatusNotFound)
-		default:
-			log.Printf("Error creating PR: %v", err)
-			h.sendError(w, "Internal server error", http.StatusInternalServerError)
-		}
+		h.sendServiceError(w, r, err, "creating PR")
 		return
 	}
 
@@ -175,25 +332,14 @@ func (h *Handlers) CreatePR(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) MergePR(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var req models.MergePRRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
-	pr, err := h.prService.MergePR(req.PullRequestID)
+	pr, err := h.prService.MergePR(r.Context(), req.PullRequestID)
 	if err != nil {
-		if err == service.ErrNotFound {
-			h.sendErrorResponse(w, "NOT_FOUND", "PR not found", http.StatusNotFound)
-		} else {
-			log.Printf("Error merging PR: %v", err)
-			h.sendError(w, "Internal server error", http.StatusInternalServerError)
-		}
+		h.sendServiceError(w, r, err, "merging PR")
 		return
 	}
 
@@ -202,32 +348,30 @@ func (h *Handlers) MergePR(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	var req models.ReassignRequest
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
-	var req models.ReassignRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	// ревьюер может отказаться от ревью, не указывая себя явно
+	if req.OldUserID == "" {
+		if principal := auth.PrincipalFrom(r.Context()); principal != nil {
+			req.OldUserID = principal.UserID
+		}
+	}
+	if req.OldUserID == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "old_reviewer_id is required", http.StatusBadRequest)
 		return
 	}
 
-	pr, newReviewerID, err := h.prService.ReassignReviewer(req.PullRequestID, req.OldUserID)
+	if !ownsUser(r, req.OldUserID) {
+		h.sendServiceError(w, r, service.ErrForbidden, "reassigning reviewer")
+		return
+	}
+
+	pr, newReviewerID, err := h.prService.ReassignReviewer(r.Context(), req.PullRequestID, req.OldUserID)
 	if err != nil {
-		switch err {
-		case service.ErrNotFound:
-			h.sendErrorResponse(w, "NOT_FOUND", "PR or user not found", http.StatusNotFound)
-		case service.ErrPRAlreadyMerged:
-			h.sendErrorResponse(w, "PR_MERGED", "cannot reassign on merged PR", http.StatusConflict)
-		case service.ErrReviewerNotAssigned:
-			h.sendErrorResponse(w, "NOT_ASSIGNED", "reviewer is not assigned to this PR", http.StatusConflict)
-		case service.ErrNoAvailableReviewers:
-			h.sendErrorResponse(w, "NO_CANDIDATE", "no active replacement candidate in team", http.StatusConflict)
-		default:
-			log.Printf("Error reassigning reviewer: %v", err)
-			h.sendError(w, "Internal server error", http.StatusInternalServerError)
-		}
+		h.sendServiceError(w, r, err, "reassigning reviewer")
 		return
 	}
 
@@ -241,21 +385,36 @@ func (h *Handlers) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) GetUserReview(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "user_id is required", http.StatusBadRequest)
 		return
 	}
 
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		h.sendErrorResponse(w, "INVALID_REQUEST", "user_id is required", http.StatusBadRequest)
+	if !ownsUser(r, userID) {
+		h.sendServiceError(w, r, service.ErrForbidden, "getting user review PRs")
 		return
 	}
 
-	prs, err := h.userService.GetUserReviewPRs(userID)
+	query := models.ReviewQuery{
+		Status: r.URL.Query().Get("status"),
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+
+	if query.Status != "" && query.Status != "OPEN" && query.Status != "MERGED" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "status must be OPEN or MERGED", http.StatusBadRequest)
+		return
+	}
+
+	limit, ok := h.parseLimit(w, r)
+	if !ok {
+		return
+	}
+	query.Limit = limit
+
+	prs, nextCursor, err := h.userService.GetUserReviewPRs(r.Context(), userID, query)
 	if err != nil {
-		log.Printf("Error getting user review PRs: %v", err)
-		h.sendError(w, "Internal server error", http.StatusInternalServerError)
+		h.sendServiceError(w, r, err, "getting user review PRs")
 		return
 	}
 
@@ -272,38 +431,111 @@ func (h *Handlers) GetUserReview(w http.ResponseWriter, r *http.Request) {
 	response := models.UserReviewResponse{
 		UserID:       userID,
 		PullRequests: shortPRs,
+		NextCursor:   nextCursor,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+func (h *Handlers) GetStats(w http.ResponseWriter, r *http.Request) {
+	query := models.StatsQuery{
+		TeamName: r.URL.Query().Get("team_name"),
+	}
+
+	var ok bool
+	if query.From, ok = h.parseTime(w, r, "from"); !ok {
+		return
+	}
+	if query.To, ok = h.parseTime(w, r, "to"); !ok {
+		return
+	}
+
+	if value := r.URL.Query().Get("include_descendants"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			h.sendErrorResponse(w, service.CodeInvalidRequest, "include_descendants must be true or false", http.StatusBadRequest)
+			return
+		}
+		query.IncludeDescendants = parsed
+	}
+
+	stats, err := h.statsService.GetStats(r.Context(), query)
+	if err != nil {
+		h.sendServiceError(w, r, err, "getting stats")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"status":"healthy"}`))
+	json.NewEncoder(w).Encode(stats)
 }
 
-func (h *Handlers) sendError(w http.ResponseWriter, message string, statusCode int) {
+func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
+	w.Write([]byte(`{"status":"healthy"}`))
 }
 
-func (h *Handlers) sendErrorResponse(w http.ResponseWriter, code, message string, statusCode int) {
+// decodeJSON читает тело запроса и отклоняет поля, которых нет в модели
+func (h *Handlers) decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			SendBodyTooLarge(w, tooLarge)
+			return false
+		}
+
+		field, message := "body", "invalid JSON"
+		if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			field, message = strings.Trim(name, `"`), "unknown field"
+		}
+		SendValidationError(w, []models.FieldError{{Field: field, Message: message}})
+		return false
+	}
+	return true
+}
+
+func (h *Handlers) parseLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	limit := r.URL.Query().Get("limit")
+	if limit == "" {
+		return 0, true
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 || n > service.MaxPageLimit {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "limit must be between 1 and "+strconv.Itoa(service.MaxPageLimit), http.StatusBadRequest)
+		return 0, false
+	}
+
+	return n, true
+}
+
+func (h *Handlers) parseTime(w http.ResponseWriter, r *http.Request, param string) (*time.Time, bool) {
+	value := r.URL.Query().Get(param)
+	if value == "" {
+		return nil, true
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, param+" must be an RFC 3339 timestamp", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &parsed, true
+}
+
+func (h *Handlers) Ready(w http.ResponseWriter, r *http.Request) {
+	response := h.healthService.Ready(r.Context())
+
+	statusCode := http.StatusOK
+	if response.Status != service.StatusReady {
+		statusCode = http.StatusServiceUnavailable
+		h.logger.WarnContext(r.Context(), "readiness check failed", "checks", response.Checks)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(models.ErrorResponse{
-		Error: struct {
-			Code    string `json:"code"`
-			Message string `json:"message"`
-		}{
-			Code:    code,
-			Message: message,
-		},
-	})
+	json.NewEncoder(w).Encode(response)
 }
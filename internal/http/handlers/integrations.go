@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/service"
+)
+
+// GitHubWebhook принимает вебхук GitHub. Токен не нужен: запрос подтверждается подписью
+// X-Hub-Signature-256, поэтому тело читается целиком до разбора.
+func (h *Handlers) GitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.integrationService.VerifyGitHubSignature(body, r.Header.Get("X-Hub-Signature-256")) {
+		h.logger.WarnContext(r.Context(), "github webhook signature mismatch", "delivery", r.Header.Get("X-GitHub-Delivery"))
+		h.sendServiceError(w, r, service.ErrInvalidSignature, "verifying github webhook")
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	if event != "pull_request" {
+		// ping приходит при создании вебхука, остальные события сервису не нужны
+		h.sendIntegrationResult(w, &models.IntegrationEventResponse{
+			Result: models.IntegrationResultIgnored,
+			Reason: fmt.Sprintf("event %q is not handled", event),
+		})
+		return
+	}
+
+	var payload models.GitHubPullRequestEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		SendValidationError(w, []models.FieldError{{Field: "body", Message: "invalid JSON"}})
+		return
+	}
+	if payload.Repository.FullName == "" || payload.PullRequest.Number == 0 {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "repository.full_name and pull_request.number are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.integrationService.HandleGitHubPullRequest(r.Context(), &payload)
+	if err != nil {
+		h.sendServiceError(w, r, err, "handling github pull_request event")
+		return
+	}
+
+	h.sendIntegrationResult(w, result)
+}
+
+// GitLabWebhook принимает Merge Request Hook GitLab (и системный хук с object_kind merge_request).
+// Запрос подтверждается секретным токеном в X-Gitlab-Token.
+func (h *Handlers) GitLabWebhook(w http.ResponseWriter, r *http.Request) {
+	if !h.integrationService.VerifyGitLabToken(r.Header.Get("X-Gitlab-Token")) {
+		h.logger.WarnContext(r.Context(), "gitlab webhook token mismatch", "event_uuid", r.Header.Get("X-Gitlab-Event-UUID"))
+		h.sendServiceError(w, r, service.ErrInvalidWebhookToken, "verifying gitlab webhook")
+		return
+	}
+
+	body, ok := h.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.GitLabMergeRequestEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		SendValidationError(w, []models.FieldError{{Field: "body", Message: "invalid JSON"}})
+		return
+	}
+	if payload.ObjectKind != "merge_request" {
+		h.sendIntegrationResult(w, &models.IntegrationEventResponse{
+			Result: models.IntegrationResultIgnored,
+			Reason: fmt.Sprintf("event %q is not handled", r.Header.Get("X-Gitlab-Event")),
+		})
+		return
+	}
+	if payload.Project.PathWithNamespace == "" || payload.ObjectAttributes.IID == 0 {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "project.path_with_namespace and object_attributes.iid are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.integrationService.HandleGitLabMergeRequest(r.Context(), &payload)
+	if err != nil {
+		h.sendServiceError(w, r, err, "handling gitlab merge request event")
+		return
+	}
+
+	h.sendIntegrationResult(w, result)
+}
+
+func (h *Handlers) LinkAccount(w http.ResponseWriter, r *http.Request) {
+	var req models.LinkAccountRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.Provider == "" || req.Login == "" || req.UserID == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "provider, login and user_id are required", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.integrationService.LinkAccount(r.Context(), &req)
+	if err != nil {
+		h.sendServiceError(w, r, err, "linking code host account")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.CodeHostAccountResponse{Account: account})
+}
+
+func (h *Handlers) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	query := models.CodeHostAccountQuery{
+		Provider: r.URL.Query().Get("provider"),
+		UserID:   r.URL.Query().Get("user_id"),
+	}
+
+	accounts, err := h.integrationService.ListAccounts(r.Context(), query)
+	if err != nil {
+		h.sendServiceError(w, r, err, "listing code host accounts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.CodeHostAccountListResponse{Accounts: accounts})
+}
+
+func (h *Handlers) UnlinkAccount(w http.ResponseWriter, r *http.Request) {
+	provider := r.URL.Query().Get("provider")
+	login := r.URL.Query().Get("login")
+	if provider == "" || login == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "provider and login are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.integrationService.UnlinkAccount(r.Context(), provider, login); err != nil {
+		h.sendServiceError(w, r, err, "unlinking code host account")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readBody читает тело целиком: подпись считается по исходным байтам, а события хостингов
+// разбираются без decodeJSON, потому что в них много полей, которые сервису не нужны
+func (h *Handlers) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			SendBodyTooLarge(w, tooLarge)
+			return nil, false
+		}
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "cannot read request body", http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+func (h *Handlers) sendIntegrationResult(w http.ResponseWriter, result *models.IntegrationEventResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,466 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/service"
+	"antonvedaet/internship_task/internal/store"
+)
+
+const (
+	testGitHubSecret = "github-webhook-secret"
+	testGitLabToken  = "gitlab-webhook-token"
+)
+
+// fakePRService записывает вызовы, которые обработчики вебхуков делают вместо REST-клиента
+type fakePRService struct {
+	service.PRService
+
+	created []models.CreatePRRequest
+	merged  []string
+	deleted []string
+}
+
+func (f *fakePRService) CreatePR(_ context.Context, req *models.CreatePRRequest) (*models.PullRequest, error) {
+	f.created = append(f.created, *req)
+	return &models.PullRequest{
+		PullRequestID:     req.PullRequestID,
+		PullRequestName:   req.PullRequestName,
+		AuthorID:          req.AuthorID,
+		Status:            "OPEN",
+		AssignedReviewers: []string{},
+	}, nil
+}
+
+func (f *fakePRService) MergePR(_ context.Context, prID string) (*models.PullRequest, error) {
+	f.merged = append(f.merged, prID)
+	return &models.PullRequest{PullRequestID: prID, Status: "MERGED", AssignedReviewers: []string{}}, nil
+}
+
+func (f *fakePRService) DeletePR(_ context.Context, prID string) (*models.PullRequest, error) {
+	f.deleted = append(f.deleted, prID)
+	return &models.PullRequest{PullRequestID: prID, Status: "OPEN", AssignedReviewers: []string{}}, nil
+}
+
+// newIntegrationHandlers собирает обработчики с настоящим IntegrationService поверх sqlmock
+func newIntegrationHandlers(t *testing.T) (*Handlers, *fakePRService, sqlmock.Sqlmock) {
+	t.Helper()
+	return newIntegrationHandlersWithGitLab(t, "")
+}
+
+// newIntegrationHandlersWithGitLab - то же с API GitLab по адресу gitlabAPI
+func newIntegrationHandlersWithGitLab(t *testing.T, gitlabAPI string) (*Handlers, *fakePRService, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	prService := &fakePRService{}
+	cfg := config.IntegrationsConfig{
+		GitHub: config.GitHubConfig{WebhookSecret: testGitHubSecret},
+		GitLab: config.GitLabConfig{WebhookToken: testGitLabToken, APIURL: gitlabAPI},
+	}
+	integrationService := service.NewIntegrationService(store.Wrap(sqlDB, time.Second), prService, cfg, logger)
+
+	h := NewHandlers(nil, nil, prService, nil, nil, nil, nil, nil, nil, integrationService, nil, time.Second, logger)
+	return h, prService, mock
+}
+
+func expectLinkedLogin(mock sqlmock.Sqlmock, provider, login, userID string) {
+	mock.ExpectQuery("SELECT user_id FROM code_host_accounts").
+		WithArgs(provider, login).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(userID))
+}
+
+func readFixture(t *testing.T, path ...string) []byte {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join(append([]string{"..", "..", "..", "testdata"}, path...)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func githubSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func postGitHubWebhook(h *Handlers, event, signature string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/integrations/github/webhook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	if signature != "" {
+		req.Header.Set("X-Hub-Signature-256", signature)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GitHubWebhook(rec, req)
+	return rec
+}
+
+func decodeIntegrationResult(t *testing.T, rec *httptest.ResponseRecorder) models.IntegrationEventResponse {
+	t.Helper()
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	var result models.IntegrationEventResponse
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestGitHubWebhookSignature(t *testing.T) {
+	body := readFixture(t, "github", "pull_request_opened.json")
+
+	tests := []struct {
+		name      string
+		signature string
+		wantCode  int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong secret", githubSignature("other-secret", body), http.StatusUnauthorized},
+		{"sha1 header format", "sha1=" + githubSignature(testGitHubSecret, body)[len("sha256="):], http.StatusUnauthorized},
+		{"not hex", "sha256=zz", http.StatusUnauthorized},
+		{"signature of another body", githubSignature(testGitHubSecret, append(slices.Clone(body), ' ')), http.StatusUnauthorized},
+		{"valid", githubSignature(testGitHubSecret, body), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, prService, mock := newIntegrationHandlers(t)
+			if tt.wantCode == http.StatusOK {
+				expectLinkedLogin(mock, models.ProviderGitHub, "alice-dev", "u1")
+			}
+
+			rec := postGitHubWebhook(h, "pull_request", tt.signature, body)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode != http.StatusOK && len(prService.created) > 0 {
+				t.Errorf("PR created from a request with an invalid signature: %+v", prService.created)
+			}
+		})
+	}
+}
+
+func TestGitHubWebhookEvents(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		event       string
+		linkedLogin string
+		wantResult  string
+		wantCreated []models.CreatePRRequest
+		wantMerged  []string
+		wantDeleted []string
+	}{
+		{
+			fixture:     "pull_request_opened.json",
+			event:       "pull_request",
+			linkedLogin: "alice-dev",
+			wantResult:  models.IntegrationResultCreated,
+			wantCreated: []models.CreatePRRequest{{PullRequestID: "acme/api#42", PullRequestName: "Add search", AuthorID: "u1"}},
+		},
+		{
+			fixture:    "pull_request_opened_draft.json",
+			event:      "pull_request",
+			wantResult: models.IntegrationResultIgnored,
+		},
+		{
+			fixture:     "pull_request_ready_for_review.json",
+			event:       "pull_request",
+			linkedLogin: "alice-dev",
+			wantResult:  models.IntegrationResultCreated,
+			wantCreated: []models.CreatePRRequest{{PullRequestID: "acme/api#42", PullRequestName: "Add search", AuthorID: "u1"}},
+		},
+		{
+			// новые коммиты не меняют ни состав ревьюеров, ни статус PR
+			fixture:    "pull_request_synchronize.json",
+			event:      "pull_request",
+			wantResult: models.IntegrationResultIgnored,
+		},
+		{
+			fixture:    "pull_request_closed_merged.json",
+			event:      "pull_request",
+			wantResult: models.IntegrationResultMerged,
+			wantMerged: []string{"acme/api#42"},
+		},
+		{
+			// статуса "закрыт" нет: PR, закрытый без мержа, не должен остаться открытым
+			fixture:     "pull_request_closed.json",
+			event:       "pull_request",
+			wantResult:  models.IntegrationResultDeleted,
+			wantDeleted: []string{"acme/api#42"},
+		},
+		{
+			fixture:    "ping.json",
+			event:      "ping",
+			wantResult: models.IntegrationResultIgnored,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			h, prService, mock := newIntegrationHandlers(t)
+			if tt.linkedLogin != "" {
+				expectLinkedLogin(mock, models.ProviderGitHub, tt.linkedLogin, "u1")
+			}
+
+			body := readFixture(t, "github", tt.fixture)
+			result := decodeIntegrationResult(t, postGitHubWebhook(h, tt.event, githubSignature(testGitHubSecret, body), body))
+
+			if result.Result != tt.wantResult {
+				t.Errorf("result = %q (%s), want %q", result.Result, result.Reason, tt.wantResult)
+			}
+			if !slices.Equal(prService.created, tt.wantCreated) {
+				t.Errorf("CreatePR calls = %+v, want %+v", prService.created, tt.wantCreated)
+			}
+			if !slices.Equal(prService.merged, tt.wantMerged) {
+				t.Errorf("MergePR calls = %v, want %v", prService.merged, tt.wantMerged)
+			}
+			if !slices.Equal(prService.deleted, tt.wantDeleted) {
+				t.Errorf("DeletePR calls = %v, want %v", prService.deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestGitHubWebhookUnlinkedAuthor(t *testing.T) {
+	h, prService, mock := newIntegrationHandlers(t)
+	mock.ExpectQuery("SELECT user_id FROM code_host_accounts").
+		WithArgs(models.ProviderGitHub, "alice-dev").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	body := readFixture(t, "github", "pull_request_opened.json")
+	result := decodeIntegrationResult(t, postGitHubWebhook(h, "pull_request", githubSignature(testGitHubSecret, body), body))
+
+	if result.Result != models.IntegrationResultIgnored {
+		t.Errorf("result = %q, want ignored", result.Result)
+	}
+	if len(prService.created) > 0 {
+		t.Errorf("PR created for an unlinked author: %+v", prService.created)
+	}
+}
+
+func postGitLabWebhook(h *Handlers, token string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/integrations/gitlab/webhook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gitlab-Event", "Merge Request Hook")
+	req.Header.Set("X-Gitlab-Event-UUID", "13792a34-cac6-4fda-95a8-c58e00a3954e")
+	if token != "" {
+		req.Header.Set("X-Gitlab-Token", token)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GitLabWebhook(rec, req)
+	return rec
+}
+
+func TestGitLabWebhookToken(t *testing.T) {
+	body := readFixture(t, "gitlab", "merge_request_open.json")
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong", "not-the-token", http.StatusUnauthorized},
+		{"prefix of the token", testGitLabToken[:len(testGitLabToken)-1], http.StatusUnauthorized},
+		{"correct", testGitLabToken, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, prService, mock := newIntegrationHandlers(t)
+			if tt.wantCode == http.StatusOK {
+				expectLinkedLogin(mock, models.ProviderGitLab, "alice.dev", "u1")
+			}
+
+			rec := postGitLabWebhook(h, tt.token, body)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode != http.StatusOK && len(prService.created) > 0 {
+				t.Errorf("PR created from a request with an invalid token: %+v", prService.created)
+			}
+		})
+	}
+}
+
+func TestGitLabWebhookEvents(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		linkedLogin string
+		wantResult  string
+		wantCreated []models.CreatePRRequest
+		wantMerged  []string
+		wantDeleted []string
+	}{
+		{
+			fixture:     "merge_request_open.json",
+			linkedLogin: "alice.dev",
+			wantResult:  models.IntegrationResultCreated,
+			wantCreated: []models.CreatePRRequest{{PullRequestID: "platform/billing!17", PullRequestName: "Export invoices to CSV", AuthorID: "u1"}},
+		},
+		{
+			fixture:    "merge_request_open_draft.json",
+			wantResult: models.IntegrationResultIgnored,
+		},
+		{
+			// снятие draft - момент, когда MR готов к ревью
+			fixture:     "merge_request_update_ready.json",
+			linkedLogin: "alice.dev",
+			wantResult:  models.IntegrationResultCreated,
+			wantCreated: []models.CreatePRRequest{{PullRequestID: "platform/billing!17", PullRequestName: "Export invoices to CSV", AuthorID: "u1"}},
+		},
+		{
+			fixture:    "merge_request_update_draft.json",
+			wantResult: models.IntegrationResultIgnored,
+		},
+		{
+			fixture:    "merge_request_update.json",
+			wantResult: models.IntegrationResultIgnored,
+		},
+		{
+			// мержит не автор: автор в событии не нужен
+			fixture:    "merge_request_merge.json",
+			wantResult: models.IntegrationResultMerged,
+			wantMerged: []string{"platform/billing!17"},
+		},
+		{
+			fixture:     "merge_request_close.json",
+			wantResult:  models.IntegrationResultDeleted,
+			wantDeleted: []string{"platform/billing!17"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			h, prService, mock := newIntegrationHandlers(t)
+			if tt.linkedLogin != "" {
+				expectLinkedLogin(mock, models.ProviderGitLab, tt.linkedLogin, "u1")
+			}
+
+			body := readFixture(t, "gitlab", tt.fixture)
+			result := decodeIntegrationResult(t, postGitLabWebhook(h, testGitLabToken, body))
+
+			if result.Result != tt.wantResult {
+				t.Errorf("result = %q (%s), want %q", result.Result, result.Reason, tt.wantResult)
+			}
+			if result.PullRequestID != "platform/billing!17" {
+				t.Errorf("pull_request_id = %q, want %q", result.PullRequestID, "platform/billing!17")
+			}
+			if !slices.Equal(prService.created, tt.wantCreated) {
+				t.Errorf("CreatePR calls = %+v, want %+v", prService.created, tt.wantCreated)
+			}
+			if !slices.Equal(prService.merged, tt.wantMerged) {
+				t.Errorf("MergePR calls = %v, want %v", prService.merged, tt.wantMerged)
+			}
+			if !slices.Equal(prService.deleted, tt.wantDeleted) {
+				t.Errorf("DeletePR calls = %v, want %v", prService.deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+// Открытие MR чужими руками (например, ботом от имени автора) записывает PR на автора из author_id,
+// а не на того, кто вызвал событие
+func TestGitLabWebhookOpenedByAnotherUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		authorFound bool
+		wantResult  string
+		wantCreated []models.CreatePRRequest
+	}{
+		{
+			name:        "author resolved by id",
+			authorFound: true,
+			wantResult:  models.IntegrationResultCreated,
+			wantCreated: []models.CreatePRRequest{{PullRequestID: "platform/billing!17", PullRequestName: "Export invoices to CSV", AuthorID: "u1"}},
+		},
+		{
+			name:       "author not found",
+			wantResult: models.IntegrationResultIgnored,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitlab := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v4/users/31" || !tt.authorFound {
+					http.NotFound(w, r)
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]any{"id": 31, "username": "alice.dev"})
+			}))
+			defer gitlab.Close()
+
+			h, prService, mock := newIntegrationHandlersWithGitLab(t, gitlab.URL+"/api/v4")
+			if tt.authorFound {
+				expectLinkedLogin(mock, models.ProviderGitLab, "alice.dev", "u1")
+			}
+
+			var event map[string]any
+			if err := json.Unmarshal(readFixture(t, "gitlab", "merge_request_open.json"), &event); err != nil {
+				t.Fatal(err)
+			}
+			event["user"] = map[string]any{"id": 44, "username": "bob.lead", "name": "Bob Lead"}
+			body, _ := json.Marshal(event)
+
+			result := decodeIntegrationResult(t, postGitLabWebhook(h, testGitLabToken, body))
+			if result.Result != tt.wantResult {
+				t.Errorf("result = %q (%s), want %q", result.Result, result.Reason, tt.wantResult)
+			}
+			if !slices.Equal(prService.created, tt.wantCreated) {
+				t.Errorf("CreatePR calls = %+v, want %+v", prService.created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestGitLabWebhookIgnoresOtherHooks(t *testing.T) {
+	h, prService, _ := newIntegrationHandlers(t)
+
+	body := []byte(`{"object_kind": "push", "ref": "refs/heads/main", "project": {"path_with_namespace": "platform/billing"}}`)
+	result := decodeIntegrationResult(t, postGitLabWebhook(h, testGitLabToken, body))
+
+	if result.Result != models.IntegrationResultIgnored {
+		t.Errorf("result = %q, want ignored", result.Result)
+	}
+	if len(prService.created) > 0 || len(prService.merged) > 0 {
+		t.Errorf("push hook drove PRService: created %+v, merged %v", prService.created, prService.merged)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/service"
+)
+
+func (h *Handlers) CreateNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateNotificationChannelRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.URL == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "url is required", http.StatusBadRequest)
+		return
+	}
+
+	channel, err := h.notificationService.CreateChannel(r.Context(), &req)
+	if err != nil {
+		h.sendServiceError(w, r, err, "creating notification channel")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.NotificationChannelResponse{Channel: channel})
+}
+
+func (h *Handlers) ListNotificationChannels(w http.ResponseWriter, r *http.Request) {
+	query := models.NotificationChannelQuery{
+		TeamName: r.URL.Query().Get("team_name"),
+		UserID:   r.URL.Query().Get("user_id"),
+	}
+
+	channels, err := h.notificationService.ListChannels(r.Context(), query)
+	if err != nil {
+		h.sendServiceError(w, r, err, "listing notification channels")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NotificationChannelListResponse{Channels: channels})
+}
+
+func (h *Handlers) DeleteNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channel_id")
+	if channelID == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "channel_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.notificationService.DeleteChannel(r.Context(), channelID); err != nil {
+		h.sendServiceError(w, r, err, "deleting notification channel")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) ListNotificationDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := models.NotificationDeliveryQuery{
+		ChannelID: r.URL.Query().Get("channel_id"),
+		Status:    r.URL.Query().Get("status"),
+		Cursor:    r.URL.Query().Get("cursor"),
+	}
+
+	limit, ok := h.parseLimit(w, r)
+	if !ok {
+		return
+	}
+	query.Limit = limit
+
+	deliveries, nextCursor, err := h.notificationService.ListDeliveries(r.Context(), query)
+	if err != nil {
+		h.sendServiceError(w, r, err, "listing notification deliveries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NotificationDeliveryListResponse{Deliveries: deliveries, NextCursor: nextCursor})
+}
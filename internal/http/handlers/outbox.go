@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/service"
+)
+
+func (h *Handlers) ListOutboxEvents(w http.ResponseWriter, r *http.Request) {
+	query := models.OutboxEventQuery{
+		Status: r.URL.Query().Get("status"),
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+
+	limit, ok := h.parseLimit(w, r)
+	if !ok {
+		return
+	}
+	query.Limit = limit
+
+	events, nextCursor, err := h.outboxService.ListEvents(r.Context(), query)
+	if err != nil {
+		h.sendServiceError(w, r, err, "listing outbox events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.OutboxEventListResponse{Events: events, NextCursor: nextCursor})
+}
+
+func (h *Handlers) RequeueOutboxEvent(w http.ResponseWriter, r *http.Request) {
+	var req models.RequeueOutboxEventRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.EventID == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "event_id is required", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.outboxService.RequeueEvent(r.Context(), req.EventID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "requeueing outbox event")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.OutboxEventResponse{Event: event})
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"antonvedaet/internship_task/internal/metrics"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/service"
+)
+
+// streamRetry - через сколько миллисекунд EventSource переподключается после обрыва
+const streamRetry = 3000
+
+// StreamUserEvents отдаёт SSE-поток назначений, переназначений и мержей для ревьюера.
+// id события - seq записи журнала outbox: после переподключения клиент присылает его
+// в Last-Event-ID (или last_event_id в query) и получает пропущенные события.
+func (h *Handlers) StreamUserEvents(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if !ownsUser(r, userID) {
+		h.sendServiceError(w, r, service.ErrForbidden, "streaming user events")
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var lastSeq int64
+	if lastEventID != "" {
+		seq, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || seq < 0 {
+			h.sendErrorResponse(w, service.CodeInvalidRequest, "Last-Event-ID must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		lastSeq = seq
+	}
+
+	ctx := r.Context()
+
+	// подписка оформляется до чтения журнала, чтобы не потерять события между ними
+	sub, err := h.streamService.Subscribe(ctx, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "subscribing to user events")
+		return
+	}
+	defer h.streamService.Unsubscribe(sub)
+
+	backlog := lastEventID != ""
+	if !backlog {
+		if lastSeq, err = h.streamService.LatestSeq(ctx); err != nil {
+			h.sendServiceError(w, r, err, "subscribing to user events")
+			return
+		}
+	}
+
+	// поток живёт дольше HTTP_WRITE_TIMEOUT, дедлайн записи снимается только для него
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	if rc.Flush() != nil {
+		return
+	}
+
+	metrics.EventStreams.Inc()
+	defer metrics.EventStreams.Dec()
+
+	// lastSeq - наибольший seq отправленного события: браузер присылает в Last-Event-ID id последнего
+	// полученного события, а позднее событие с меньшим seq не должно откатить курсор назад.
+	// События приходят в порядке коммита, а не seq, поэтому журнал и подписка могут
+	// повторять друг друга; sent - отправленные события, которые ещё могут прийти повторно.
+	// Когда sent разрастается, из него удаляются события ниже SettledSeq(lastSeq).
+	sent := map[int64]bool{}
+	pruneAt := service.StreamBacklogPage
+	send := func(event models.OutboxEvent) error {
+		if sent[event.Seq] {
+			return nil
+		}
+		if err := writeStreamEvent(w, event); err != nil {
+			return err
+		}
+		sent[event.Seq] = true
+		lastSeq = max(lastSeq, event.Seq)
+
+		if len(sent) < pruneAt {
+			return nil
+		}
+		settled, err := h.streamService.SettledSeq(ctx, lastSeq)
+		if err != nil {
+			return err
+		}
+		for seq := range sent {
+			if seq < settled {
+				delete(sent, seq)
+			}
+		}
+		// долгая транзакция держит горизонт: следующая попытка - когда sent вырастет вдвое
+		pruneAt = max(service.StreamBacklogPage, 2*len(sent))
+		return nil
+	}
+	catchUp := func() error {
+		after := lastSeq
+		for lateAfter := int64(0); ; {
+			late, err := h.streamService.LateEvents(ctx, userID, after, lateAfter)
+			if err != nil {
+				return err
+			}
+			for _, event := range late {
+				if err := send(event); err != nil {
+					return err
+				}
+				lateAfter = event.Seq
+			}
+			if len(late) < service.StreamBacklogPage {
+				break
+			}
+		}
+		for {
+			events, err := h.streamService.Backlog(ctx, userID, after)
+			if err != nil {
+				return err
+			}
+			for _, event := range events {
+				if err := send(event); err != nil {
+					return err
+				}
+				after = event.Seq
+			}
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+			if len(events) < service.StreamBacklogPage {
+				return nil
+			}
+		}
+	}
+
+	if backlog {
+		if err := catchUp(); err != nil {
+			h.logStreamEnd(r, userID, err)
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(h.streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Done:
+			return
+		case event := <-sub.Events:
+			if sent[event.Seq] {
+				continue
+			}
+			if err = send(event); err == nil {
+				err = rc.Flush()
+			}
+		case <-sub.Resync:
+			err = catchUp()
+		case <-heartbeat.C:
+			if _, err = io.WriteString(w, ": ping\n\n"); err == nil {
+				err = rc.Flush()
+			}
+		}
+		if err != nil {
+			h.logStreamEnd(r, userID, err)
+			return
+		}
+	}
+}
+
+func (h *Handlers) logStreamEnd(r *http.Request, userID string, err error) {
+	if r.Context().Err() != nil {
+		return
+	}
+	h.logger.WarnContext(r.Context(), "user event stream closed", "user_id", userID, "error", err)
+}
+
+func writeStreamEvent(w io.Writer, event models.OutboxEvent) error {
+	var data bytes.Buffer
+	if err := json.Compact(&data, event.Payload); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.EventType, data.Bytes())
+	return err
+}
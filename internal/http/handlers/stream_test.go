@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/service"
+	"antonvedaet/internship_task/internal/stream"
+)
+
+// fakeStreamService отдаёт журнал из памяти и записывает курсоры, с которых его читали
+type fakeStreamService struct {
+	sub     *stream.Subscription
+	late    map[int64][]models.OutboxEvent
+	backlog map[int64][]models.OutboxEvent
+	settled int64
+	// onBacklog вызывается после каждого чтения журнала вперёд
+	onBacklog func()
+
+	lateFrom    []int64
+	latePages   []int64
+	backlogFrom []int64
+	settledFrom []int64
+}
+
+func (f *fakeStreamService) Subscribe(context.Context, string) (*stream.Subscription, error) {
+	return f.sub, nil
+}
+
+func (f *fakeStreamService) Unsubscribe(*stream.Subscription) {}
+
+func (f *fakeStreamService) LatestSeq(context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeStreamService) Backlog(_ context.Context, _ string, afterSeq int64) ([]models.OutboxEvent, error) {
+	f.backlogFrom = append(f.backlogFrom, afterSeq)
+	if f.onBacklog != nil {
+		f.onBacklog()
+	}
+	return f.backlog[afterSeq], nil
+}
+
+// LateEvents отдаёт late[seq] страницами по service.StreamBacklogPage
+func (f *fakeStreamService) LateEvents(_ context.Context, _ string, seq, afterSeq int64) ([]models.OutboxEvent, error) {
+	if afterSeq == 0 {
+		f.lateFrom = append(f.lateFrom, seq)
+	}
+	f.latePages = append(f.latePages, afterSeq)
+
+	page := []models.OutboxEvent{}
+	for _, event := range f.late[seq] {
+		if event.Seq > afterSeq && len(page) < service.StreamBacklogPage {
+			page = append(page, event)
+		}
+	}
+	return page, nil
+}
+
+func (f *fakeStreamService) SettledSeq(_ context.Context, seq int64) (int64, error) {
+	f.settledFrom = append(f.settledFrom, seq)
+	return f.settled, nil
+}
+
+func streamEvent(seq int64) models.OutboxEvent {
+	return models.OutboxEvent{
+		Seq:       seq,
+		EventID:   fmt.Sprintf("evt_%d", seq),
+		EventType: models.EventPRCreated,
+		Payload:   []byte(fmt.Sprintf(`{"id": "evt_%d"}`, seq)),
+	}
+}
+
+// streamRecorder вызывает onWrite после каждой записи в поток
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+	onWrite func(body string)
+}
+
+func (r *streamRecorder) Write(p []byte) (int, error) {
+	n, err := r.ResponseRecorder.Write(p)
+	r.onWrite(r.Body.String())
+	return n, err
+}
+
+var streamEventID = regexp.MustCompile(`(?m)^id: (\d+)$`)
+
+func TestStreamUserEventsCatchesUpLateCommits(t *testing.T) {
+	sub := &stream.Subscription{
+		UserID: "u2",
+		Events: make(chan models.OutboxEvent, 2),
+		Resync: make(chan struct{}, 1),
+		Done:   make(chan struct{}),
+	}
+	// 11 уже отдан из журнала, 13 - новое живое событие
+	sub.Events <- streamEvent(11)
+	sub.Events <- streamEvent(13)
+
+	// клиент видел 10; событие 7 закоммитилось позже 10, а 8 клиент уже получил живым,
+	// но сервер этого не знает и отдаёт повторно
+	streamService := &fakeStreamService{
+		sub:     sub,
+		late:    map[int64][]models.OutboxEvent{10: {streamEvent(7), streamEvent(8)}},
+		backlog: map[int64][]models.OutboxEvent{10: {streamEvent(11), streamEvent(12)}},
+	}
+	h := NewHandlers(nil, nil, nil, nil, nil, nil, nil, nil, streamService, nil, nil, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/users/events?user_id=u2", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "10")
+	rec := &streamRecorder{ResponseRecorder: httptest.NewRecorder(), onWrite: func(body string) {
+		if strings.Contains(body, "id: 13\n") {
+			cancel()
+		}
+	}}
+
+	h.StreamUserEvents(rec, req)
+
+	var ids []string
+	for _, match := range streamEventID.FindAllStringSubmatch(rec.Body.String(), -1) {
+		ids = append(ids, match[1])
+	}
+	if want := []string{"7", "8", "11", "12", "13"}; !slices.Equal(ids, want) {
+		t.Errorf("event ids = %v, want %v", ids, want)
+	}
+	if !slices.Equal(streamService.lateFrom, []int64{10}) {
+		t.Errorf("late events read after %v, want [10]", streamService.lateFrom)
+	}
+	// журнал вперёд читается от Last-Event-ID, а не от последнего отданного позднего события
+	if !slices.Equal(streamService.backlogFrom, []int64{10}) {
+		t.Errorf("backlog read after %v, want [10]", streamService.backlogFrom)
+	}
+}
+
+func TestStreamUserEventsResyncFromLastSent(t *testing.T) {
+	sub := &stream.Subscription{
+		UserID: "u2",
+		Events: make(chan models.OutboxEvent, 1),
+		Resync: make(chan struct{}, 1),
+		Done:   make(chan struct{}),
+	}
+	// живое событие 21 пришло раньше 20: 20 закоммитилось позже, и его уведомление потеряно
+	sub.Events <- streamEvent(21)
+
+	streamService := &fakeStreamService{
+		sub:     sub,
+		late:    map[int64][]models.OutboxEvent{21: {streamEvent(20)}},
+		backlog: map[int64][]models.OutboxEvent{},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	streamService.onBacklog = func() {
+		if len(streamService.backlogFrom) == 2 {
+			cancel()
+		}
+	}
+	h := NewHandlers(nil, nil, nil, nil, nil, nil, nil, nil, streamService, nil, nil, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/events?user_id=u2", nil).WithContext(ctx)
+	resyncs := 0
+	rec := &streamRecorder{ResponseRecorder: httptest.NewRecorder(), onWrite: func(body string) {
+		// после 21 и после позднего 20 - по одной перечитке журнала
+		if want := strings.Count(body, "id: "); resyncs < want {
+			resyncs++
+			sub.Resync <- struct{}{}
+		}
+	}}
+
+	h.StreamUserEvents(rec, req)
+
+	var ids []string
+	for _, match := range streamEventID.FindAllStringSubmatch(rec.Body.String(), -1) {
+		ids = append(ids, match[1])
+	}
+	if want := []string{"21", "20"}; !slices.Equal(ids, want) {
+		t.Errorf("event ids = %v, want %v", ids, want)
+	}
+	// позднее событие 20 не откатывает курсор: вторая перечитка снова идёт от 21
+	if !slices.Equal(streamService.lateFrom, []int64{21, 21}) {
+		t.Errorf("late events read before %v, want [21 21]", streamService.lateFrom)
+	}
+	if !slices.Equal(streamService.backlogFrom, []int64{21, 21}) {
+		t.Errorf("backlog read after %v, want [21 21]", streamService.backlogFrom)
+	}
+}
+
+func TestStreamUserEventsPagesLateEventsAndPrunesSent(t *testing.T) {
+	sub := &stream.Subscription{
+		UserID: "u2",
+		Events: make(chan models.OutboxEvent, 3),
+		Resync: make(chan struct{}, 1),
+		Done:   make(chan struct{}),
+	}
+	// 350 и 401 уже отданы как поздние события, а их уведомления пришли после перечитки; 501 - новое
+	sub.Events <- streamEvent(350)
+	sub.Events <- streamEvent(401)
+	sub.Events <- streamEvent(501)
+
+	// поздних событий больше страницы: 250 событий 201..450 до курсора 500
+	var late []models.OutboxEvent
+	for seq := int64(201); seq <= 450; seq++ {
+		late = append(late, streamEvent(seq))
+	}
+	streamService := &fakeStreamService{
+		sub:     sub,
+		late:    map[int64][]models.OutboxEvent{500: late},
+		backlog: map[int64][]models.OutboxEvent{},
+		settled: 300,
+	}
+	h := NewHandlers(nil, nil, nil, nil, nil, nil, nil, nil, streamService, nil, nil, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/users/events?user_id=u2", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "500")
+	rec := &streamRecorder{ResponseRecorder: httptest.NewRecorder(), onWrite: func(body string) {
+		if strings.Contains(body, "id: 501\n") {
+			cancel()
+		}
+	}}
+
+	h.StreamUserEvents(rec, req)
+
+	ids := streamEventID.FindAllStringSubmatch(rec.Body.String(), -1)
+	if len(ids) != 251 {
+		t.Errorf("sent %d events, want each of 250 late events and 501 once", len(ids))
+	}
+	if want := []int64{0, 300, 400}; !slices.Equal(streamService.latePages, want) {
+		t.Errorf("late pages read after %v, want %v", streamService.latePages, want)
+	}
+	// отправленные ниже горизонта забываются, и sent не растёт с каждым событием
+	if len(streamService.settledFrom) == 0 || streamService.settledFrom[0] != 500 {
+		t.Errorf("settled seq asked for %v, want the cursor 500", streamService.settledFrom)
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"antonvedaet/internship_task/internal/auth"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/service"
+)
+
+func (h *Handlers) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTokenRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.Name == "" || req.Role == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "name and role are required", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.tokenService.CreateToken(r.Context(), &req)
+	if err != nil {
+		h.sendServiceError(w, r, err, "creating token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) ListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.tokenService.ListTokens(r.Context())
+	if err != nil {
+		h.sendServiceError(w, r, err, "listing tokens")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TokenListResponse{Tokens: tokens})
+}
+
+func (h *Handlers) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	var req models.RevokeTokenRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.TokenID == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "token_id is required", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.tokenService.RevokeToken(r.Context(), req.TokenID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "revoking token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TokenResponse{Token: token})
+}
+
+// ownsUser - токен с ролью user работает только со своими ревью
+func ownsUser(r *http.Request, userID string) bool {
+	principal := auth.PrincipalFrom(r.Context())
+	if principal == nil || principal.Role != auth.RoleUser {
+		return true
+	}
+	return principal.UserID == userID
+}
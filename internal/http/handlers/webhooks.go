@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/service"
+)
+
+func (h *Handlers) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateWebhookRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.URL == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "url is required", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.webhookService.CreateSubscription(r.Context(), &req)
+	if err != nil {
+		h.sendServiceError(w, r, err, "creating webhook subscription")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhookService.ListSubscriptions(r.Context())
+	if err != nil {
+		h.sendServiceError(w, r, err, "listing webhook subscriptions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.WebhookListResponse{Subscriptions: subs})
+}
+
+func (h *Handlers) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	subscriptionID := r.URL.Query().Get("subscription_id")
+	if subscriptionID == "" {
+		h.sendErrorResponse(w, service.CodeInvalidRequest, "subscription_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(r.Context(), subscriptionID); err != nil {
+		h.sendServiceError(w, r, err, "deleting webhook subscription")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := models.WebhookDeliveryQuery{
+		SubscriptionID: r.URL.Query().Get("subscription_id"),
+		Status:         r.URL.Query().Get("status"),
+		Cursor:         r.URL.Query().Get("cursor"),
+	}
+
+	limit, ok := h.parseLimit(w, r)
+	if !ok {
+		return
+	}
+	query.Limit = limit
+
+	deliveries, nextCursor, err := h.webhookService.ListDeliveries(r.Context(), query)
+	if err != nil {
+		h.sendServiceError(w, r, err, "listing webhook deliveries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.WebhookDeliveryListResponse{Deliveries: deliveries, NextCursor: nextCursor})
+}
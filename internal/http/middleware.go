@@ -0,0 +1,156 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"antonvedaet/internship_task/internal/http/handlers"
+	"antonvedaet/internship_task/internal/logging"
+	"antonvedaet/internship_task/internal/metrics"
+	"antonvedaet/internship_task/internal/tracing"
+)
+
+const maxRequestIDLength = 128
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// withRequestID берёт X-Request-ID из запроса или генерирует новый,
+// кладёт его в контекст и возвращает в заголовке ответа
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(handlers.RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(handlers.RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// withJSONErrors отвечает на запросы без маршрута тем же JSON, что и обработчики:
+// ServeMux сам отдаёт 404 и 405 текстом
+func withJSONErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// статус и Allow для 405 вычисляет ServeMux, текст ответа отбрасывается
+		rec := &discardRecorder{header: http.Header{}}
+		h.ServeHTTP(rec, r)
+		if rec.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+			handlers.SendMethodNotAllowed(w)
+			return
+		}
+		handlers.SendRouteNotFound(w)
+	})
+}
+
+type discardRecorder struct {
+	header http.Header
+	status int
+}
+
+func (r *discardRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *discardRecorder) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (r *discardRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+// instrument открывает span, собирает метрики и пишет access log для маршрута pattern вида "POST /team/add".
+// Контекст трассировки продолжается из заголовков traceparent/tracestate входящего запроса.
+func instrument(pattern string, logger *slog.Logger, next http.Handler) http.Handler {
+	method, route, found := strings.Cut(pattern, " ")
+	if !found {
+		method, route = "", pattern
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		requestMethod := method
+		if requestMethod == "" {
+			requestMethod = r.Method
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Start(ctx, requestMethod+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", requestMethod),
+				attribute.String("http.route", route),
+				attribute.String("request_id", logging.RequestID(ctx)),
+			),
+		)
+		defer span.End()
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(recorder, r)
+
+		span.SetAttributes(attribute.Int("http.response.status_code", recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+
+		duration := time.Since(start)
+		metrics.ObserveRequest(requestMethod, route, recorder.status, duration)
+
+		logger.InfoContext(r.Context(), "request completed",
+			"method", requestMethod,
+			"route", route,
+			"status", recorder.status,
+			"duration_ms", float64(duration.Microseconds())/1000,
+		)
+	})
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"antonvedaet/internship_task/internal/auth"
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/http/handlers"
+)
+
+// rateLimitSweepInterval - как часто удаляются корзины клиентов, которые успели полностью восстановиться
+const rateLimitSweepInterval = time.Minute
+
+// rateLimiter - token bucket на клиента для одной группы маршрутов.
+// nil означает, что группа не ограничена.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	// trustedProxies - прокси, чьему X-Forwarded-For можно верить
+	trustedProxies []netip.Prefix
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newRateLimiter(cfg config.RateLimitConfig, rule config.RateLimitRule) *rateLimiter {
+	if !cfg.Enabled || rule.RPS <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:           rule.RPS,
+		burst:          float64(rule.Burst),
+		trustedProxies: cfg.TrustedProxyPrefixes(),
+		buckets:        make(map[string]*bucket),
+	}
+}
+
+// allow списывает токен из корзины key; если токенов нет - возвращает, через сколько он появится
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimitSweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep удаляет заполненные корзины: новая корзина ничем от них не отличается
+func (l *rateLimiter) sweep(now time.Time) {
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// wrap после аутентификации определяет клиента по токену, до неё и без токена - по IP
+func (l *rateLimiter) wrap(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, retryAfter := l.allow(clientKey(r, l.trustedProxies), time.Now())
+		if !ok {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+			handlers.SendRateLimited(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func clientKey(r *http.Request, trustedProxies []netip.Prefix) string {
+	if principal := auth.PrincipalFrom(r.Context()); principal != nil {
+		if principal.TokenID != "" {
+			return "token:" + principal.TokenID
+		}
+		return "subject:" + principal.Name
+	}
+	return "ip:" + clientIP(r, trustedProxies)
+}
+
+// clientIP - адрес соединения, а если оно пришло от доверенного прокси - первый справа адрес
+// X-Forwarded-For, который не принадлежит прокси. Левые записи заголовка мог подставить сам клиент.
+func clientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !trusted(peer, trustedProxies) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			// мусор в заголовке: дальше влево верить нельзя, клиентом считается последний прокси
+			return peer.String()
+		}
+		if !trusted(addr, trustedProxies) {
+			return addr.Unmap().String()
+		}
+		peer = addr
+	}
+	return peer.Unmap().String()
+}
+
+func trusted(addr netip.Addr, prefixes []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitBody ограничивает размер тела запроса; превышение обработчики и валидатор отдают как 413
+func limitBody(maxBytes int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
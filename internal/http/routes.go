@@ -1,44 +1,146 @@
 package http
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 
+	internshiptask "antonvedaet/internship_task"
+	"antonvedaet/internship_task/internal/auth"
+	"antonvedaet/internship_task/internal/config"
 	"antonvedaet/internship_task/internal/http/handlers"
+	"antonvedaet/internship_task/internal/metrics"
 	"antonvedaet/internship_task/internal/service"
 	"antonvedaet/internship_task/internal/store"
 )
 
-func MakeMux() *http.ServeMux {
+func MakeMux(cfg *config.Config, db *store.DB, healthService service.HealthService, tokenService service.TokenService, streamService service.StreamService, logger *slog.Logger) (http.Handler, error) {
 	mux := http.NewServeMux()
 
-	db, err := store.New()
+	validator, err := newRequestValidator(internshiptask.OpenAPISpec)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		return nil, err
 	}
 
-	teamService := service.NewTeamService(db)
-	userService := service.NewUserService(db)
-	prService := service.NewPRService(db)
+	teamService := service.NewTeamService(db, logger)
+	userService := service.NewUserService(db, logger)
+	prService := service.NewPRService(db, logger)
+	statsService := service.NewStatsService(db)
+	webhookService := service.NewWebhookService(db, logger)
+	outboxService := service.NewOutboxService(db, logger)
+	integrationService := service.NewIntegrationService(db, prService, cfg.Integrations, logger)
+	notificationService := service.NewNotificationService(db, logger)
+
+	if cfg.Features.Metrics {
+		metrics.RegisterDB(db.DB)
+	}
+
+	authn := &authenticator{tokens: tokenService, enabled: cfg.Auth.Enabled, logger: logger}
+	if cfg.Auth.Enabled && cfg.Auth.JWT.Enabled() {
+		verifier, err := auth.NewJWTVerifier(cfg.Auth.JWT)
+		if err != nil {
+			return nil, fmt.Errorf("set up jwt verifier: %w", err)
+		}
+		authn.jwt = verifier
+	}
+
+	// маршрут без ролей доступен без токена, limit == nil - без ограничения частоты.
+	// Маршруты с ролями ограничиваются дважды: по IP до проверки токена, чтобы перебор
+	// токенов не обходил лимит, и по вызывающему после неё.
+	maxBodyBytes := int64(cfg.Server.MaxBodyBytes)
+	ipLimit := newRateLimiter(cfg.RateLimit, cfg.RateLimit.IP)
+	handle := func(pattern string, limit *rateLimiter, h http.HandlerFunc, roles ...string) {
+		var next http.Handler = authn.require(roles, limit.wrap(validator.wrap(pattern, h)))
+		if len(roles) > 0 {
+			next = ipLimit.wrap(next)
+		}
+		mux.Handle(pattern, instrument(pattern, logger, limitBody(maxBodyBytes, next)))
+	}
+
+	readLimit := newRateLimiter(cfg.RateLimit, cfg.RateLimit.Read)
+	writeLimit := newRateLimiter(cfg.RateLimit, cfg.RateLimit.Write)
+	adminLimit := newRateLimiter(cfg.RateLimit, cfg.RateLimit.Admin)
+
+	admin := []string{auth.RoleAdmin}
+	anyRole := []string{auth.RoleAdmin, auth.RoleUser, auth.RoleService}
+	reviewer := []string{auth.RoleAdmin, auth.RoleUser}
+	pipeline := []string{auth.RoleAdmin, auth.RoleService}
 
 	handler := handlers.NewHandlers(
 		teamService,
 		userService,
 		prService,
+		statsService,
+		healthService,
+		tokenService,
+		webhookService,
+		outboxService,
+		streamService,
+		integrationService,
+		notificationService,
+		cfg.Stream.Heartbeat,
+		logger,
 	)
 
-	mux.HandleFunc("POST /team/add", handler.AddTeam)
-	mux.HandleFunc("GET /team/get", handler.GetTeam)
-	mux.HandleFunc("POST /team/deactivate", handler.DeactivateTeamUsers)
+	handle("POST /team/add", adminLimit, handler.AddTeam, admin...)
+	handle("GET /team/get", readLimit, handler.GetTeam, anyRole...)
+	handle("GET /team/list", readLimit, handler.ListTeams, anyRole...)
+	handle("PUT /team/update", adminLimit, handler.UpdateTeam, admin...)
+	handle("DELETE /team", adminLimit, handler.DeleteTeam, admin...)
+	handle("POST /team/deactivate", adminLimit, handler.DeactivateTeamUsers, admin...)
+	handle("POST /team/addMember", adminLimit, handler.AddTeamMember, admin...)
+	handle("POST /team/removeMember", adminLimit, handler.RemoveTeamMember, admin...)
+	handle("POST /team/moveMember", adminLimit, handler.MoveTeamMember, admin...)
+
+	handle("GET /users/get", readLimit, handler.GetUser, anyRole...)
+	handle("GET /users/list", readLimit, handler.ListUsers, anyRole...)
+	handle("POST /users/setIsActive", adminLimit, handler.SetUserActive, admin...)
+	handle("GET /users/getReview", readLimit, handler.GetUserReview, reviewer...)
+	// streamService == nil, если SSE выключен в конфиге
+	if streamService != nil {
+		handle("GET /users/events", readLimit, handler.StreamUserEvents, reviewer...)
+	}
+
+	handle("POST /pullRequest/create", writeLimit, handler.CreatePR, pipeline...)
+	handle("POST /pullRequest/merge", writeLimit, handler.MergePR, pipeline...)
+	handle("POST /pullRequest/reassign", writeLimit, handler.ReassignReviewer, reviewer...)
+
+	handle("GET /stats", readLimit, handler.GetStats, anyRole...)
+
+	handle("POST /tokens/create", adminLimit, handler.CreateToken, admin...)
+	handle("GET /tokens/list", adminLimit, handler.ListTokens, admin...)
+	handle("POST /tokens/revoke", adminLimit, handler.RevokeToken, admin...)
+
+	handle("POST /webhooks/create", adminLimit, handler.CreateWebhook, admin...)
+	handle("GET /webhooks/list", adminLimit, handler.ListWebhooks, admin...)
+	handle("DELETE /webhooks", adminLimit, handler.DeleteWebhook, admin...)
+	handle("GET /webhooks/deliveries", adminLimit, handler.ListWebhookDeliveries, admin...)
 
-	mux.HandleFunc("POST /users/setIsActive", handler.SetUserActive)
-	mux.HandleFunc("GET /users/getReview", handler.GetUserReview)
+	handle("POST /notifications/channels/create", adminLimit, handler.CreateNotificationChannel, admin...)
+	handle("GET /notifications/channels/list", adminLimit, handler.ListNotificationChannels, admin...)
+	handle("DELETE /notifications/channels", adminLimit, handler.DeleteNotificationChannel, admin...)
+	handle("GET /notifications/deliveries", adminLimit, handler.ListNotificationDeliveries, admin...)
 
-	mux.HandleFunc("POST /pullRequest/create", handler.CreatePR)
-	mux.HandleFunc("POST /pullRequest/merge", handler.MergePR)
-	mux.HandleFunc("POST /pullRequest/reassign", handler.ReassignReviewer)
+	handle("GET /outbox/events", adminLimit, handler.ListOutboxEvents, admin...)
+	handle("POST /outbox/requeue", adminLimit, handler.RequeueOutboxEvent, admin...)
 
-	mux.HandleFunc("GET /health", handler.Health)
+	handle("POST /integrations/accounts/link", adminLimit, handler.LinkAccount, admin...)
+	handle("GET /integrations/accounts/list", adminLimit, handler.ListAccounts, admin...)
+	handle("DELETE /integrations/accounts", adminLimit, handler.UnlinkAccount, admin...)
+	// вебхуки хостингов подтверждаются подписью, а не токеном; без секрета маршрут не регистрируется
+	if cfg.Integrations.GitHub.Enabled() {
+		handle("POST /integrations/github/webhook", nil, handler.GitHubWebhook)
+	}
+	if cfg.Integrations.GitLab.Enabled() {
+		handle("POST /integrations/gitlab/webhook", nil, handler.GitLabWebhook)
+	}
+
+	handle("GET /health", nil, handler.Health)
+	handle("GET /ready", nil, handler.Ready)
+	// метрики раскрывают маршруты, нагрузку и состояние пула, поэтому отдаются только admin
+	if cfg.Features.Metrics {
+		handle("GET /metrics", adminLimit, metrics.Handler().ServeHTTP, admin...)
+	}
 
-	return mux
+	return withRequestID(withJSONErrors(mux)), nil
 }
@@ -0,0 +1,378 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/http/handlers"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/service"
+	"antonvedaet/internship_task/internal/store"
+)
+
+// fakeTokenService узнаёт токены по имени роли: "admin-token", "user-token" и т.д.
+type fakeTokenService struct {
+	service.TokenService
+}
+
+func (fakeTokenService) Authenticate(_ context.Context, token string) (*models.Principal, error) {
+	role, ok := strings.CutSuffix(token, "-token")
+	if !ok {
+		return nil, service.ErrUnauthorized
+	}
+	return &models.Principal{TokenID: "tok_" + role, Name: role, Role: role, UserID: "u1"}, nil
+}
+
+func newTestMux(t *testing.T, cfg *config.Config) http.Handler {
+	t.Helper()
+
+	mux, _ := newTestMuxWithMock(t, cfg)
+	return mux
+}
+
+func newTestMuxWithMock(t *testing.T, cfg *config.Config) (http.Handler, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mux, err := MakeMux(cfg, store.Wrap(sqlDB, time.Second), nil, fakeTokenService{}, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mux, mock
+}
+
+func TestMetricsRequiresAdmin(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = true
+	cfg.Features.Metrics = true
+	mux := newTestMux(t, cfg)
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"user", "user-token", http.StatusForbidden},
+		{"service", "service-token", http.StatusForbidden},
+		{"admin", "admin-token", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && !strings.Contains(rec.Body.String(), "go_sql_open_connections") {
+				t.Error("metrics of the connection pool are missing")
+			}
+		})
+	}
+}
+
+// MakeMux с метриками собирается повторно, например в тестах, и не должен паниковать
+// на повторной регистрации коллектора пула
+func TestMakeMuxTwice(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = false
+	cfg.Features.Metrics = true
+
+	newTestMux(t, cfg)
+	mux := newTestMux(t, cfg)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+}
+
+func TestRateLimitBeforeAuthentication(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = true
+	cfg.Features.Metrics = true
+	cfg.RateLimit.IP = config.RateLimitRule{RPS: 0.001, Burst: 2}
+	cfg.RateLimit.Admin = config.RateLimitRule{RPS: 0.001, Burst: 1}
+	mux := newTestMux(t, cfg)
+
+	get := func(addr, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.RemoteAddr = addr + ":40000"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	steps := []struct {
+		name   string
+		addr   string
+		token  string
+		status int
+	}{
+		{"wrong token", "192.0.2.1", "guess", http.StatusUnauthorized},
+		{"another wrong token", "192.0.2.1", "guess-again", http.StatusUnauthorized},
+		// перебор токенов с одного адреса упирается в лимит до проверки токена
+		{"wrong token over ip limit", "192.0.2.1", "guess-more", http.StatusTooManyRequests},
+		{"valid token from limited ip", "192.0.2.1", "admin-token", http.StatusTooManyRequests},
+		{"other ip", "192.0.2.2", "admin-token", http.StatusOK},
+		// лимит вызывающего общий для всех его адресов
+		{"same token from third ip", "192.0.2.3", "admin-token", http.StatusTooManyRequests},
+	}
+
+	for _, step := range steps {
+		if status := get(step.addr, step.token); status != step.status {
+			t.Errorf("%s: status = %d, want %d", step.name, status, step.status)
+		}
+	}
+}
+
+// запросы мимо маршрутов получают тот же JSON, что и ошибки обработчиков
+func TestUnknownRouteErrors(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = true
+	mux := newTestMux(t, cfg)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		code   string
+		allow  string
+	}{
+		{"unknown path", http.MethodGet, "/teams", http.StatusNotFound, "NOT_FOUND", ""},
+		{"wrong method", http.MethodGet, "/pullRequest/merge", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "POST"},
+		{"wrong method on read route", http.MethodPost, "/team/get", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "GET, HEAD"},
+		{"known route", http.MethodPost, "/pullRequest/merge", http.StatusUnauthorized, "UNAUTHORIZED", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set(handlers.RequestIDHeader, "req-1")
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if allow := rec.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("Allow = %q, want %q", allow, tt.allow)
+			}
+			var response models.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decode error response: %v: %s", err, rec.Body)
+			}
+			if response.Error.Code != tt.code || response.Error.RequestID != "req-1" {
+				t.Errorf("error = %+v, want code %s and request id", response.Error, tt.code)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trustedProxies := config.RateLimitConfig{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.10"}}.TrustedProxyPrefixes()
+
+	tests := []struct {
+		name      string
+		peer      string
+		forwarded []string
+		want      string
+	}{
+		{"direct client", "198.51.100.7:40000", nil, "198.51.100.7"},
+		// заголовок от недоверенного адреса игнорируется
+		{"spoofed header", "198.51.100.7:40000", []string{"203.0.113.1"}, "198.51.100.7"},
+		{"behind proxy", "10.0.0.5:40000", []string{"203.0.113.1"}, "203.0.113.1"},
+		// левую запись подставил клиент, правую - прокси
+		{"spoofed behind proxy", "10.0.0.5:40000", []string{"1.1.1.1, 203.0.113.1"}, "203.0.113.1"},
+		{"proxy chain", "192.0.2.10:40000", []string{"203.0.113.1, 10.0.0.7"}, "203.0.113.1"},
+		{"several headers", "10.0.0.5:40000", []string{"1.1.1.1", "203.0.113.1"}, "203.0.113.1"},
+		{"proxy without header", "10.0.0.5:40000", nil, "10.0.0.5"},
+		{"garbage in header", "10.0.0.5:40000", []string{"203.0.113.1, unknown"}, "10.0.0.5"},
+		{"ipv6 client", "10.0.0.5:40000", []string{"2001:db8::1"}, "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/team/list", nil)
+			req.RemoteAddr = tt.peer
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := clientIP(req, trustedProxies); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// за прокси каждый клиент получает свою корзину, а не одну на всех
+func TestRateLimitBehindTrustedProxy(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = true
+	cfg.Features.Metrics = true
+	cfg.RateLimit.IP = config.RateLimitRule{RPS: 0.001, Burst: 1}
+	cfg.RateLimit.TrustedProxies = []string{"10.0.0.0/8"}
+	mux := newTestMux(t, cfg)
+
+	get := func(peer, forwarded string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.RemoteAddr = peer + ":40000"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	steps := []struct {
+		name      string
+		peer      string
+		forwarded string
+		status    int
+	}{
+		{"first client", "10.0.0.5", "203.0.113.1", http.StatusUnauthorized},
+		{"second client through the same proxy", "10.0.0.5", "203.0.113.2", http.StatusUnauthorized},
+		{"first client again", "10.0.0.6", "203.0.113.1", http.StatusTooManyRequests},
+		{"direct client", "198.51.100.7", "203.0.113.3", http.StatusUnauthorized},
+		// недоверенный адрес не может сменить корзину, подставив заголовок
+		{"direct client with another header", "198.51.100.7", "203.0.113.4", http.StatusTooManyRequests},
+	}
+
+	for _, step := range steps {
+		if status := get(step.peer, step.forwarded); status != step.status {
+			t.Errorf("%s: status = %d, want %d", step.name, status, step.status)
+		}
+	}
+}
+
+// PR, созданные вебхуками, должны проходить проверку тела по openapi.yml в REST-маршрутах
+func TestWebhookPullRequestIDPassesValidation(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = true
+	mux, mock := newTestMuxWithMock(t, cfg)
+
+	prColumns := []string{"pull_request_id", "pull_request_name", "author_id", "status", "assigned_reviewers", "created_at", "merged_at"}
+	now := time.Now()
+	mock.ExpectQuery(`FROM pull_requests\s+WHERE pull_request_id = \$1`).
+		WithArgs("acme/api#42").
+		WillReturnRows(sqlmock.NewRows(prColumns).AddRow("acme/api#42", "Add bugs", "u1", "MERGED", "{u2}", now, now))
+	mock.ExpectQuery(`FROM pull_requests\s+WHERE pull_request_id = \$1`).
+		WithArgs("group/sub/project!7").
+		WillReturnError(sql.ErrNoRows)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"github id", "/pullRequest/merge", `{"pull_request_id":"acme/api#42"}`, http.StatusOK},
+		{"gitlab id", "/pullRequest/reassign", `{"pull_request_id":"group/sub/project!7","old_reviewer_id":"u2"}`, http.StatusNotFound},
+		{"space in id", "/pullRequest/merge", `{"pull_request_id":"acme api#42"}`, http.StatusBadRequest},
+		{"id too long", "/pullRequest/merge", `{"pull_request_id":"` + strings.Repeat("a", 256) + `"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer admin-token")
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// next_cursor первой страницы продолжает выборку с последнего PR, неверный курсор - 400 без запроса в БД
+func TestUserReviewPagination(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = true
+	mux, mock := newTestMuxWithMock(t, cfg)
+
+	prColumns := []string{"pull_request_id", "pull_request_name", "author_id", "status", "assigned_reviewers", "created_at", "merged_at"}
+	createdAt := time.Date(2025, 10, 24, 12, 34, 56, 123456789, time.UTC)
+	// два PR с одинаковым временем создания разделяются по pull_request_id
+	mock.ExpectQuery(`ORDER BY created_at, pull_request_id LIMIT \$2`).
+		WithArgs("u2", 3).
+		WillReturnRows(sqlmock.NewRows(prColumns).
+			AddRow("pr-1", "Add search", "u1", "OPEN", "{u2}", createdAt, nil).
+			AddRow("pr-2", "Fix search", "u1", "OPEN", "{u2}", createdAt, nil).
+			AddRow("pr-3", "Drop search", "u1", "OPEN", "{u2}", createdAt, nil))
+	mock.ExpectQuery(`AND \(created_at, pull_request_id\) > \(\$2, \$3\)`).
+		WithArgs("u2", createdAt, "pr-2", 3).
+		WillReturnRows(sqlmock.NewRows(prColumns).
+			AddRow("pr-3", "Drop search", "u1", "OPEN", "{u2}", createdAt, nil))
+
+	get := func(query string) (*httptest.ResponseRecorder, models.UserReviewResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/users/getReview?user_id=u2&limit=2"+query, nil)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		var response models.UserReviewResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec, response
+	}
+
+	rec, first := get("")
+	if rec.Code != http.StatusOK || len(first.PullRequests) != 2 || first.PullRequests[1].PullRequestID != "pr-2" || first.NextCursor == "" {
+		t.Fatalf("first page = %d %s, want pr-1, pr-2 and next_cursor", rec.Code, rec.Body)
+	}
+
+	rec, second := get("&cursor=" + url.QueryEscape(first.NextCursor))
+	if rec.Code != http.StatusOK || len(second.PullRequests) != 1 || second.PullRequests[0].PullRequestID != "pr-3" || second.NextCursor != "" {
+		t.Fatalf("second page = %d %s, want pr-3 without next_cursor", rec.Code, rec.Body)
+	}
+
+	for _, cursor := range []string{"not-a-cursor!", "dTE", base64.RawURLEncoding.EncodeToString([]byte("yesterday\x00pr-2"))} {
+		rec, _ := get("&cursor=" + url.QueryEscape(cursor))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"INVALID_REQUEST"`) {
+			t.Errorf("cursor %q: %d %s, want 400 INVALID_REQUEST", cursor, rec.Code, rec.Body)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
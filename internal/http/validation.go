@@ -0,0 +1,181 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+
+	"antonvedaet/internship_task/internal/http/handlers"
+	"antonvedaet/internship_task/internal/models"
+)
+
+// requestValidator проверяет параметры и тело запроса по операции из openapi.yml
+type requestValidator struct {
+	spec    *openapi3.T
+	options *openapi3filter.Options
+}
+
+func newRequestValidator(spec []byte) (*requestValidator, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec: %w", err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+
+	return &requestValidator{
+		spec: doc,
+		options: &openapi3filter.Options{
+			MultiError: true,
+			// значения по умолчанию выставляют сервисы, тело запроса не переписывается
+			SkipSettingDefaults: true,
+			// токен проверяет authenticator до валидации
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		},
+	}, nil
+}
+
+// wrap валидирует запросы к маршруту pattern ("METHOD /path").
+// Маршруты, которых нет в спецификации, пропускаются без проверки.
+func (v *requestValidator) wrap(pattern string, next http.Handler) http.Handler {
+	method, path, _ := strings.Cut(pattern, " ")
+	pathItem := v.spec.Paths.Find(path)
+	if pathItem == nil {
+		return next
+	}
+	operation := pathItem.GetOperation(method)
+	if operation == nil {
+		return next
+	}
+
+	route := &routers.Route{
+		Spec:      v.spec,
+		Path:      path,
+		PathItem:  pathItem,
+		Method:    method,
+		Operation: operation,
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+			Request: r,
+			Route:   route,
+			Options: v.options,
+		})
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				handlers.SendBodyTooLarge(w, tooLarge)
+				return
+			}
+			handlers.SendValidationError(w, validationDetails(err))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func validationDetails(err error) []models.FieldError {
+	var details []models.FieldError
+	for _, err := range flattenErrors(err) {
+		var requestErr *openapi3filter.RequestError
+		if !errors.As(err, &requestErr) {
+			details = append(details, models.FieldError{Field: "body", Message: err.Error()})
+			continue
+		}
+
+		field := "body"
+		if requestErr.Parameter != nil {
+			field = requestErr.Parameter.Name
+		}
+
+		var parseErr *openapi3filter.ParseError
+		if errors.As(requestErr.Err, &parseErr) {
+			message := "malformed value"
+			if requestErr.Parameter == nil {
+				message = "invalid JSON"
+			}
+			details = append(details, models.FieldError{Field: field, Message: message})
+			continue
+		}
+
+		schemaErrs := schemaErrors(requestErr.Err)
+		if len(schemaErrs) == 0 {
+			message := requestErr.Reason
+			if requestErr.Err != nil {
+				message = requestErr.Err.Error()
+			}
+			details = append(details, models.FieldError{Field: field, Message: message})
+			continue
+		}
+
+		for _, schemaErr := range schemaErrs {
+			pointer := schemaErr.JSONPointer()
+			if name, ok := unsupportedProperty(schemaErr.Reason); ok {
+				pointer = append(pointer, name)
+			}
+			fieldPath := field
+			if len(pointer) > 0 {
+				fieldPath = strings.Join(pointer, ".")
+			}
+			details = append(details, models.FieldError{Field: fieldPath, Message: schemaErr.Reason})
+		}
+	}
+	return details
+}
+
+// flattenErrors раскрывает вложенные MultiError. errors.As здесь не подходит:
+// RequestError разворачивается в свою причину, и имя параметра потерялось бы.
+func flattenErrors(err error) []error {
+	multi, ok := err.(openapi3.MultiError)
+	if !ok {
+		return []error{err}
+	}
+
+	var result []error
+	for _, err := range multi {
+		result = append(result, flattenErrors(err)...)
+	}
+	return result
+}
+
+func schemaErrors(err error) []*openapi3.SchemaError {
+	if err == nil {
+		return nil
+	}
+
+	var result []*openapi3.SchemaError
+	for _, err := range flattenErrors(err) {
+		var schemaErr *openapi3.SchemaError
+		if errors.As(err, &schemaErr) {
+			result = append(result, schemaErr)
+		}
+	}
+	return result
+}
+
+// unsupportedProperty достаёт имя лишнего поля из сообщения kin-openapi:
+// для additionalProperties: false путь указывает на объект, а не на само поле
+func unsupportedProperty(reason string) (string, bool) {
+	name, ok := strings.CutPrefix(reason, "property ")
+	if !ok {
+		return "", false
+	}
+	name, ok = strings.CutSuffix(name, " is unsupported")
+	if !ok {
+		return "", false
+	}
+	unquoted, err := strconv.Unquote(name)
+	if err != nil {
+		return "", false
+	}
+	return unquoted, true
+}
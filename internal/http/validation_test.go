@@ -0,0 +1,91 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/service"
+)
+
+func TestRequestValidation(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = true
+	mux, mock := newTestMuxWithMock(t, cfg)
+
+	// до хранилища доходит только запрос, прошедший проверку
+	mock.ExpectQuery(`FROM users\s+WHERE user_id = \$1`).
+		WithArgs("u2").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active"}).AddRow("u2", "Bob", "backend", true))
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		// fields - поля из error.details
+		fields []string
+	}{
+		{"valid query", http.MethodGet, "/users/get?user_id=u2", "", http.StatusOK, nil},
+		{"missing query parameter", http.MethodGet, "/users/get", "", http.StatusBadRequest, []string{"user_id"}},
+		{"invalid user id", http.MethodGet, "/users/get?user_id=u%202", "", http.StatusBadRequest, []string{"user_id"}},
+		{"malformed boolean", http.MethodGet, "/users/list?is_active=maybe", "", http.StatusBadRequest, []string{"is_active"}},
+		{"invalid enum", http.MethodGet, "/users/getReview?user_id=u2&status=CLOSED", "", http.StatusBadRequest, []string{"status"}},
+		{"invalid JSON", http.MethodPost, "/users/setIsActive", `{"user_id":`, http.StatusBadRequest, []string{"body"}},
+		{"missing body", http.MethodPost, "/users/setIsActive", "", http.StatusBadRequest, []string{"body"}},
+		{"wrong type", http.MethodPost, "/users/setIsActive", `{"user_id":"u2","is_active":"no"}`, http.StatusBadRequest, []string{"is_active"}},
+		{"missing field", http.MethodPost, "/users/setIsActive", `{"user_id":"u2"}`, http.StatusBadRequest, []string{"is_active"}},
+		{"unknown field", http.MethodPost, "/users/setIsActive", `{"user_id":"u2","is_active":true,"role":"admin"}`, http.StatusBadRequest, []string{"role"}},
+		{"several errors", http.MethodPost, "/users/setIsActive", `{"user_id":"","is_active":1}`, http.StatusBadRequest, []string{"user_id", "is_active"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.method == http.MethodPost {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			req.Header.Set("Authorization", "Bearer admin-token")
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusBadRequest {
+				return
+			}
+
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			var response models.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decode error response: %v: %s", err, rec.Body)
+			}
+			if response.Error.Code != string(service.CodeValidation) {
+				t.Errorf("code = %q, want %q", response.Error.Code, service.CodeValidation)
+			}
+			fields := map[string]bool{}
+			for _, detail := range response.Error.Details {
+				fields[detail.Field] = true
+			}
+			for _, field := range tt.fields {
+				if !fields[field] {
+					t.Errorf("details %v, want error for %q", response.Error.Details, field)
+				}
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "pr_reviewer"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	PRsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "prs_created_total",
+		Help:      "Number of created pull requests.",
+	})
+
+	PRsMerged = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "prs_merged_total",
+		Help:      "Number of merged pull requests.",
+	})
+
+	Reassignments = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviewer_reassignments_total",
+		Help:      "Number of successful reviewer reassignments.",
+	})
+
+	NoCandidate = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviewer_no_candidate_total",
+		Help:      "Number of reassignments that failed with NO_CANDIDATE.",
+	})
+
+	PRsUnderstaffed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "prs_understaffed_total",
+		Help:      "Number of pull requests created with fewer reviewers than the team requires.",
+	})
+
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_attempts_total",
+		Help:      "Webhook delivery attempts by result: delivered, retry, failed.",
+	}, []string{"result"})
+
+	OutboxPublishes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_publish_attempts_total",
+		Help:      "Outbox publish attempts by sink and result: published, error.",
+	}, []string{"sink", "result"})
+
+	OutboxDeadLetters = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_dead_events_total",
+		Help:      "Number of outbox events moved to dead after exhausting attempts.",
+	})
+
+	EventStreams = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "event_streams_open",
+		Help:      "Number of open /users/events SSE streams on this instance.",
+	})
+
+	IntegrationEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "integration_events_total",
+		Help:      "Code host webhook events by provider and result: created, merged, ignored.",
+	}, []string{"provider", "result"})
+
+	NotificationDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notification_delivery_attempts_total",
+		Help:      "Chat notification delivery attempts by kind and result: delivered, retry, failed.",
+	}, []string{"kind", "result"})
+
+	OverdueReviews = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "overdue_reviews_total",
+		Help:      "Review assignments reported as overdue.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		PRsCreated,
+		PRsMerged,
+		Reassignments,
+		NoCandidate,
+		PRsUnderstaffed,
+		WebhookDeliveries,
+		OutboxPublishes,
+		OutboxDeadLetters,
+		EventStreams,
+		IntegrationEvents,
+		NotificationDeliveries,
+		OverdueReviews,
+	)
+}
+
+var (
+	dbMu        sync.Mutex
+	dbCollector prometheus.Collector
+)
+
+// RegisterDB добавляет метрики пула соединений из sql.DB.Stats(). Повторный вызов
+// заменяет пул, а не регистрирует второй коллектор с теми же именами.
+func RegisterDB(db *sql.DB) {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
+	if dbCollector != nil {
+		registry.Unregister(dbCollector)
+	}
+	dbCollector = collectors.NewDBStatsCollector(db, "postgres")
+	registry.MustRegister(dbCollector)
+}
+
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,105 @@
+package models
+
+import "time"
+
+type ReviewQuery struct {
+	Status string
+	Limit  int
+	Cursor string
+}
+
+type ReviewFilter struct {
+	Status         string
+	Limit          int
+	AfterCreatedAt *time.Time
+	AfterID        string
+}
+
+type TeamListQuery struct {
+	Limit  int
+	Cursor string
+}
+
+type TeamFilter struct {
+	Limit     int
+	AfterName string
+}
+
+type UserListQuery struct {
+	TeamName string
+	IsActive *bool
+	Username string
+	Limit    int
+	Cursor   string
+}
+
+type UserFilter struct {
+	TeamName string
+	IsActive *bool
+	Username string
+	Limit    int
+	AfterID  string
+}
+
+type StatsQuery struct {
+	From               *time.Time
+	To                 *time.Time
+	TeamName           string
+	IncludeDescendants bool
+}
+
+type StatsFilter struct {
+	From      *time.Time
+	To        *time.Time
+	TeamNames []string
+}
+
+type WebhookDeliveryQuery struct {
+	SubscriptionID string
+	Status         string
+	Limit          int
+	Cursor         string
+}
+
+type OutboxEventQuery struct {
+	Status string
+	Limit  int
+	Cursor string
+}
+
+type OutboxEventFilter struct {
+	Status    string
+	Limit     int
+	BeforeSeq int64
+}
+
+type WebhookDeliveryFilter struct {
+	SubscriptionID string
+	Status         string
+	Limit          int
+	BeforeID       int64
+}
+
+type CodeHostAccountQuery struct {
+	Provider string
+	UserID   string
+}
+
+type NotificationChannelQuery struct {
+	TeamName string
+	UserID   string
+}
+
+type NotificationDeliveryQuery struct {
+	ChannelID string
+	Status    string
+	Limit     int
+	Cursor    string
+}
+
+type NotificationDeliveryFilter struct {
+	ChannelID string
+	Status    string
+	Limit     int
+	BeforeID  int64
+}
@@ -1,12 +1,34 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
 type Team struct {
-	TeamName string       `json:"team_name"`
-	Members  []TeamMember `json:"members"`
+	TeamName    string        `json:"team_name"`
+	ParentTeam  string        `json:"parent_team,omitempty"`
+	Members     []TeamMember  `json:"members"`
+	Settings    *TeamSettings `json:"settings,omitempty"`
+	Descendants []Team        `json:"descendants,omitempty"`
+}
+
+type TeamSettings struct {
+	RequiredReviewers int  `json:"required_reviewers"`
+	ReviewerFallback  bool `json:"reviewer_fallback"`
+}
+
+// TeamSettingsUpdate - изменение настроек команды; nil-поле оставляет текущее значение
+type TeamSettingsUpdate struct {
+	RequiredReviewers *int  `json:"required_reviewers"`
+	ReviewerFallback  *bool `json:"reviewer_fallback"`
+}
+
+type TeamSummary struct {
+	TeamName    string `json:"team_name"`
+	ParentTeam  string `json:"parent_team,omitempty"`
+	MemberCount int    `json:"member_count"`
+	ActiveCount int    `json:"active_count"`
 }
 
 type TeamMember struct {
@@ -38,3 +60,263 @@ type PullRequestShort struct {
 	AuthorID        string `json:"author_id"`
 	Status          string `json:"status"`
 }
+
+type UserStats struct {
+	UserID                string `json:"user_id"`
+	TeamName              string `json:"team_name"`
+	TotalAssignments      int    `json:"total_assignments"`
+	OpenAssignments       int    `json:"open_assignments"`
+	ReassignmentsReceived int    `json:"reassignments_received"`
+	ReassignmentsGiven    int    `json:"reassignments_given"`
+}
+
+type TeamStats struct {
+	TeamName              string   `json:"team_name"`
+	TotalPRs              int      `json:"total_prs"`
+	OpenPRs               int      `json:"open_prs"`
+	MergedPRs             int      `json:"merged_prs"`
+	AvgTimeToMergeSeconds *float64 `json:"avg_time_to_merge_seconds"`
+}
+
+type APIToken struct {
+	TokenID    string     `json:"token_id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"` // admin, user, service
+	UserID     string     `json:"user_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Principal - аутентифицированный вызывающий
+type Principal struct {
+	TokenID string
+	Name    string
+	Role    string
+	UserID  string
+}
+
+// Типы доменных событий. Событие пишется в outbox в той же транзакции, что и изменение.
+const (
+	EventPRCreated        = "pull_request.created"
+	EventReassigned       = "pull_request.reviewer_reassigned"
+	EventPRMerged         = "pull_request.merged"
+	EventPRUpdated        = "pull_request.updated"
+	EventPRDeleted        = "pull_request.deleted"
+	EventReviewOverdue    = "pull_request.review_overdue"
+	EventUserCreated      = "user.created"
+	EventUserUpdated      = "user.updated"
+	EventTeamCreated      = "team.created"
+	EventTeamUpdated      = "team.updated"
+	EventTeamDeleted      = "team.deleted"
+	EventUsersDeactivated = "team.users_deactivated"
+)
+
+// EventTypes - все типы событий, на которые можно подписаться
+var EventTypes = []string{
+	EventPRCreated, EventReassigned, EventPRMerged, EventPRUpdated, EventPRDeleted, EventReviewOverdue,
+	EventUserCreated, EventUserUpdated,
+	EventTeamCreated, EventTeamUpdated, EventTeamDeleted, EventUsersDeactivated,
+}
+
+type Event struct {
+	EventID    string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+type PRCreatedData struct {
+	PR *PullRequest `json:"pr"`
+}
+
+type ReassignedData struct {
+	PR            *PullRequest `json:"pr"`
+	OldReviewerID string       `json:"old_reviewer_id"`
+	NewReviewerID string       `json:"new_reviewer_id"`
+}
+
+type PRMergedData struct {
+	PR *PullRequest `json:"pr"`
+}
+
+// PRUpdatedData - изменение PR, которое не является мержем
+type PRUpdatedData struct {
+	PR *PullRequest `json:"pr"`
+}
+
+// PRDeletedData - PR удалён, data.pr - его состояние перед удалением
+type PRDeletedData struct {
+	PR *PullRequest `json:"pr"`
+}
+
+// ReviewOverdueData - ревьюер не закрыл назначение дольше notifications.overdue_after
+type ReviewOverdueData struct {
+	PR         *PullRequest `json:"pr"`
+	ReviewerID string       `json:"reviewer_id"`
+	AssignedAt time.Time    `json:"assigned_at"`
+}
+
+type UserEventData struct {
+	User *User `json:"user"`
+}
+
+type TeamCreatedData struct {
+	Team *Team `json:"team"`
+}
+
+type TeamUpdatedData struct {
+	TeamName    string        `json:"team_name"`
+	NewTeamName string        `json:"new_team_name"`
+	ParentTeam  string        `json:"parent_team,omitempty"`
+	Settings    *TeamSettings `json:"settings"`
+}
+
+type TeamDeletedData struct {
+	TeamName      string `json:"team_name"`
+	DeletedUsers  int    `json:"deleted_users"`
+	DetachedUsers int    `json:"detached_users"`
+}
+
+type UsersDeactivatedData struct {
+	TeamNames []string `json:"team_names"`
+	UserIDs   []string `json:"user_ids"`
+}
+
+// OutboxEvent - запись outbox: событие и состояние его публикации в приёмники
+type OutboxEvent struct {
+	Seq            int64           `json:"seq"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"` // pending, published, dead
+	Attempts       int             `json:"attempts"`
+	PublishedSinks []string        `json:"published_sinks"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	PublishedAt    *time.Time      `json:"published_at,omitempty"`
+}
+
+type WebhookSubscription struct {
+	SubscriptionID string    `json:"subscription_id"`
+	URL            string    `json:"url"`
+	Events         []string  `json:"events"`
+	IsActive       bool      `json:"is_active"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	DeliveryID     int64           `json:"delivery_id"`
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"` // pending, delivered, failed
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// WebhookTask - доставка, взятая диспетчером в работу, вместе с адресом и секретом подписки
+type WebhookTask struct {
+	DeliveryID int64
+	EventID    string
+	EventType  string
+	Payload    []byte
+	Attempts   int
+	URL        string
+	Secret     string
+}
+
+// Хостинги кода, вебхуки которых создают и мержат PR
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+var Providers = []string{ProviderGitHub, ProviderGitLab}
+
+// CodeHostAccount связывает логин на хостинге кода с пользователем сервиса
+type CodeHostAccount struct {
+	Provider  string    `json:"provider"`
+	Login     string    `json:"login"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Чем закончилась обработка события хостинга кода
+const (
+	IntegrationResultCreated = "created"
+	IntegrationResultMerged  = "merged"
+	IntegrationResultDeleted = "deleted"
+	IntegrationResultIgnored = "ignored"
+)
+
+// Виды чат-уведомлений; канал получает только выбранные виды
+const (
+	NotifyAssigned       = "assigned"
+	NotifyReassignedAway = "reassigned_away"
+	NotifyMerged         = "merged"
+	NotifyReviewOverdue  = "review_overdue"
+)
+
+var NotificationKinds = []string{NotifyAssigned, NotifyReassignedAway, NotifyMerged, NotifyReviewOverdue}
+
+// Форматы incoming webhook: Slack ждёт mrkdwn, Mattermost - Markdown
+const (
+	FormatSlack      = "slack"
+	FormatMattermost = "mattermost"
+)
+
+var NotificationFormats = []string{FormatSlack, FormatMattermost}
+
+// NotificationChannel - incoming webhook чата; задаётся ровно одно из TeamName и UserID
+type NotificationChannel struct {
+	ChannelID string    `json:"channel_id"`
+	TeamName  string    `json:"team_name,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	URL       string    `json:"url"`
+	Format    string    `json:"format"`
+	Kinds     []string  `json:"kinds"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotificationRecipient - пользователь и каналы, в которые приходят его уведомления:
+// личные и канал его команды
+type NotificationRecipient struct {
+	UserID   string
+	Username string
+	Channels []NotificationChannel
+}
+
+type NotificationDelivery struct {
+	DeliveryID     int64           `json:"delivery_id"`
+	ChannelID      string          `json:"channel_id"`
+	EventID        string          `json:"event_id"`
+	RecipientID    string          `json:"recipient_id"`
+	Kind           string          `json:"kind"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"` // pending, delivered, failed
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// NotificationTask - сообщение, взятое диспетчером в работу, вместе с адресом канала
+type NotificationTask struct {
+	DeliveryID int64
+	Kind       string
+	Payload    []byte
+	Attempts   int
+	URL        string
+}
@@ -1,12 +1,21 @@
 package models
 
+import "time"
+
 type ErrorResponse struct {
 	Error struct {
-		Code    string `json:"code"`
-		Message string `json:"message"`
+		Code      string       `json:"code"`
+		Message   string       `json:"message"`
+		RequestID string       `json:"request_id,omitempty"`
+		Details   []FieldError `json:"details,omitempty"`
 	} `json:"error"`
 }
 
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 type SetActiveRequest struct {
 	UserID   string `json:"user_id"`
 	IsActive bool   `json:"is_active"`
@@ -16,6 +25,15 @@ type SetActiveResponse struct {
 	User *User `json:"user"`
 }
 
+type UserResponse struct {
+	User *User `json:"user"`
+}
+
+type UserListResponse struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
 type CreatePRRequest struct {
 	PullRequestID   string `json:"pull_request_id"`
 	PullRequestName string `json:"pull_request_name"`
@@ -47,17 +65,247 @@ type ReassignResponse struct {
 type UserReviewResponse struct {
 	UserID       string             `json:"user_id"`
 	PullRequests []PullRequestShort `json:"pull_requests"`
+	NextCursor   string             `json:"next_cursor,omitempty"`
 }
 
 type TeamResponse struct {
 	Team *Team `json:"team"`
 }
 
+type TeamListResponse struct {
+	Teams      []TeamSummary `json:"teams"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+type UpdateTeamRequest struct {
+	TeamName    string              `json:"team_name"`
+	NewTeamName string              `json:"new_team_name"`
+	ParentTeam  *string             `json:"parent_team"`
+	Settings    *TeamSettingsUpdate `json:"settings"`
+}
+
+type DeleteTeamResponse struct {
+	TeamName      string `json:"team_name"`
+	DeletedUsers  int    `json:"deleted_users"`
+	DetachedUsers int    `json:"detached_users"`
+}
+
 type DeactivateTeamRequest struct {
-	TeamName string `json:"team_name"`
+	TeamName           string `json:"team_name"`
+	IncludeDescendants bool   `json:"include_descendants"`
 }
 
 type DeactivateTeamResponse struct {
 	Message          string `json:"message"`
 	DeactivatedCount int    `json:"deactivated_count"`
 }
+
+type AddMemberRequest struct {
+	TeamName string `json:"team_name"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	// IsActive не указан - пользователь добавляется активным
+	IsActive *bool `json:"is_active"`
+}
+
+type RemoveMemberRequest struct {
+	TeamName     string `json:"team_name"`
+	UserID       string `json:"user_id"`
+	ReviewPolicy string `json:"review_policy"`
+}
+
+type MoveMemberRequest struct {
+	UserID       string `json:"user_id"`
+	TeamName     string `json:"team_name"`
+	ReviewPolicy string `json:"review_policy"`
+}
+
+type ReassignedReview struct {
+	PullRequestID string `json:"pull_request_id"`
+	ReplacedBy    string `json:"replaced_by,omitempty"`
+}
+
+type MembershipResponse struct {
+	User              *User              `json:"user"`
+	ReassignedReviews []ReassignedReview `json:"reassigned_reviews"`
+}
+
+type StatsResponse struct {
+	From                  *time.Time  `json:"from,omitempty"`
+	To                    *time.Time  `json:"to,omitempty"`
+	Users                 []UserStats `json:"users"`
+	Teams                 []TeamStats `json:"teams"`
+	AvgTimeToMergeSeconds *float64    `json:"avg_time_to_merge_seconds"`
+}
+
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	Version   *int    `json:"version,omitempty"`
+	Expected  *int    `json:"expected,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyStatus `json:"checks"`
+}
+
+type CreateTokenRequest struct {
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	UserID string `json:"user_id"`
+}
+
+type CreateTokenResponse struct {
+	Token  *APIToken `json:"token"`
+	Secret string    `json:"secret"`
+}
+
+type TokenListResponse struct {
+	Tokens []APIToken `json:"tokens"`
+}
+
+type RevokeTokenRequest struct {
+	TokenID string `json:"token_id"`
+}
+
+type TokenResponse struct {
+	Token *APIToken `json:"token"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+type CreateWebhookResponse struct {
+	Subscription *WebhookSubscription `json:"subscription"`
+	Secret       string               `json:"secret"`
+}
+
+type WebhookListResponse struct {
+	Subscriptions []WebhookSubscription `json:"subscriptions"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+type OutboxEventListResponse struct {
+	Events     []OutboxEvent `json:"events"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+type OutboxEventResponse struct {
+	Event *OutboxEvent `json:"event"`
+}
+
+type RequeueOutboxEventRequest struct {
+	EventID string `json:"event_id"`
+}
+
+type LinkAccountRequest struct {
+	Provider string `json:"provider"`
+	Login    string `json:"login"`
+	UserID   string `json:"user_id"`
+}
+
+type CodeHostAccountResponse struct {
+	Account *CodeHostAccount `json:"account"`
+}
+
+type CodeHostAccountListResponse struct {
+	Accounts []CodeHostAccount `json:"accounts"`
+}
+
+// IntegrationEventResponse - ответ хостингу кода; reason объясняет, почему событие пропущено
+type IntegrationEventResponse struct {
+	Result        string       `json:"result"` // created, merged, ignored
+	PullRequestID string       `json:"pull_request_id,omitempty"`
+	Reason        string       `json:"reason,omitempty"`
+	PR            *PullRequest `json:"pr,omitempty"`
+}
+
+// GitHubPullRequestEvent - поля события pull_request GitHub, которые использует сервис
+type GitHubPullRequestEvent struct {
+	Action      string            `json:"action"`
+	PullRequest GitHubPullRequest `json:"pull_request"`
+	Repository  GitHubRepository  `json:"repository"`
+}
+
+type GitHubPullRequest struct {
+	Number int        `json:"number"`
+	Title  string     `json:"title"`
+	Draft  bool       `json:"draft"`
+	Merged bool       `json:"merged"`
+	User   GitHubUser `json:"user"`
+}
+
+type GitHubRepository struct {
+	FullName string `json:"full_name"`
+}
+
+type GitHubUser struct {
+	Login string `json:"login"`
+}
+
+// GitLabMergeRequestEvent - поля Merge Request Hook GitLab, которые использует сервис
+type GitLabMergeRequestEvent struct {
+	ObjectKind       string                    `json:"object_kind"`
+	User             GitLabUser                `json:"user"`
+	Project          GitLabProject             `json:"project"`
+	ObjectAttributes GitLabMergeRequest        `json:"object_attributes"`
+	Changes          GitLabMergeRequestChanges `json:"changes"`
+}
+
+type GitLabUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type GitLabProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+type GitLabMergeRequest struct {
+	IID            int    `json:"iid"`
+	Title          string `json:"title"`
+	Action         string `json:"action"`
+	AuthorID       int64  `json:"author_id"`
+	Draft          bool   `json:"draft"`
+	WorkInProgress bool   `json:"work_in_progress"`
+}
+
+// GitLabMergeRequestChanges - изменённые поля для action update; nil - поле не менялось
+type GitLabMergeRequestChanges struct {
+	Draft *GitLabBoolChange `json:"draft"`
+}
+
+type GitLabBoolChange struct {
+	Previous bool `json:"previous"`
+	Current  bool `json:"current"`
+}
+
+type CreateNotificationChannelRequest struct {
+	TeamName string   `json:"team_name"`
+	UserID   string   `json:"user_id"`
+	URL      string   `json:"url"`
+	Format   string   `json:"format"`
+	Kinds    []string `json:"kinds"`
+}
+
+type NotificationChannelResponse struct {
+	Channel *NotificationChannel `json:"channel"`
+}
+
+type NotificationChannelListResponse struct {
+	Channels []NotificationChannel `json:"channels"`
+}
+
+type NotificationDeliveryListResponse struct {
+	Deliveries []NotificationDelivery `json:"deliveries"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/delivery"
+	"antonvedaet/internship_task/internal/metrics"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+// NewDispatcher разбирает очередь notification_deliveries и отправляет сообщения в incoming webhooks.
+// Сообщения ставит в очередь приёмник outbox chat, поэтому назначение ревьюера не ждёт чат.
+func NewDispatcher(db *store.DB, cfg config.NotificationsConfig, logger *slog.Logger) *delivery.Worker[models.NotificationTask] {
+	return delivery.NewWorker("notification", &queue{db: db}, delivery.Config{
+		PollInterval: cfg.PollInterval,
+		BatchSize:    cfg.BatchSize,
+		Timeout:      cfg.Timeout,
+		MaxAttempts:  cfg.MaxAttempts,
+		BackoffBase:  cfg.BackoffBase,
+		BackoffMax:   cfg.BackoffMax,
+	}, logger)
+}
+
+type queue struct {
+	db *store.DB
+}
+
+func (q *queue) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.NotificationTask, error) {
+	return q.db.ClaimNotificationDeliveries(ctx, limit, lease)
+}
+
+func (q *queue) Task(task models.NotificationTask) delivery.Task {
+	return delivery.Task{ID: task.DeliveryID, Attempts: task.Attempts, Kind: task.Kind}
+}
+
+func (q *queue) NewRequest(ctx context.Context, task models.NotificationTask) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, task.URL, bytes.NewReader(task.Payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pr-reviewer-notifications")
+	return req, nil
+}
+
+func (q *queue) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	return q.db.MarkNotificationDelivered(ctx, id, statusCode)
+}
+
+func (q *queue) MarkAttemptFailed(ctx context.Context, id int64, statusCode int, lastError string, nextAttemptAt *time.Time) error {
+	return q.db.MarkNotificationAttemptFailed(ctx, id, statusCode, lastError, nextAttemptAt)
+}
+
+func (q *queue) Observe(task delivery.Task, result string) {
+	metrics.NotificationDeliveries.WithLabelValues(task.Kind, result).Inc()
+}
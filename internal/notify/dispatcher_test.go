@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+func TestDispatcherDrain(t *testing.T) {
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			contentType = r.Header.Get("Content-Type")
+		case "/busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/revoked":
+			http.Error(w, "invalid_token", http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	cfg := config.NotificationsConfig{
+		BatchSize:   10,
+		Timeout:     5 * time.Second,
+		MaxAttempts: 5,
+		BackoffBase: 10 * time.Second,
+		BackoffMax:  time.Hour,
+	}
+	d := NewDispatcher(store.Wrap(sqlDB, time.Second), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rows := sqlmock.NewRows([]string{"delivery_id", "kind", "payload", "attempts", "url"}).
+		AddRow(1, models.NotifyAssigned, []byte(`{"text":"hi"}`), 0, server.URL+"/ok").
+		AddRow(2, models.NotifyAssigned, []byte(`{"text":"hi"}`), 1, server.URL+"/busy").
+		AddRow(3, models.NotifyMerged, []byte(`{"text":"hi"}`), 0, server.URL+"/revoked")
+	mock.ExpectQuery("UPDATE notification_deliveries d").
+		WithArgs(10, (cfg.Timeout + 30*time.Second).Milliseconds()).
+		WillReturnRows(rows)
+	// доставки отправляются параллельно
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectExec("SET status = 'delivered'").WithArgs(1, http.StatusOK).WillReturnResult(sqlmock.NewResult(0, 1))
+	// 429 повторяется, 403 - нет
+	mock.ExpectExec("UPDATE notification_deliveries\\s+SET status = CASE").
+		WithArgs(2, http.StatusTooManyRequests, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE notification_deliveries\\s+SET status = CASE").
+		WithArgs(3, http.StatusForbidden, sqlmock.AnyArg(), (*time.Time)(nil)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	d.Drain(context.Background())
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+}
@@ -0,0 +1,109 @@
+// Package notify отправляет чат-уведомления ревьюерам в incoming webhooks Slack и Mattermost
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+// Message - данные шаблона уведомления
+type Message struct {
+	Kind string
+	// Recipient - имя пользователя, которому адресовано уведомление
+	Recipient string
+	PR        *models.PullRequest
+	Author    string
+	// NewReviewer - кто заменил ревьюера; пусто, если ревьюер снят без замены
+	NewReviewer string
+	// Waiting - сколько назначение ждёт ревью, для review_overdue
+	Waiting time.Duration
+}
+
+var templates = map[string]string{
+	models.NotifyAssigned: `{{bold .Recipient}}, you were assigned to review {{code .PR.PullRequestID}} {{text .PR.PullRequestName}} by {{text .Author}}`,
+	models.NotifyReassignedAway: `{{bold .Recipient}}, you were removed from the review of {{code .PR.PullRequestID}} {{text .PR.PullRequestName}}` +
+		`{{if .NewReviewer}}, {{bold .NewReviewer}} takes over{{end}}`,
+	models.NotifyMerged:        `{{bold .Recipient}}, {{code .PR.PullRequestID}} {{text .PR.PullRequestName}} you were reviewing has been merged`,
+	models.NotifyReviewOverdue: `{{bold .Recipient}}, {{code .PR.PullRequestID}} {{text .PR.PullRequestName}} has been waiting for your review for {{duration .Waiting}}`,
+}
+
+// Разметка отличается: Slack понимает mrkdwn и требует экранировать &, <, >, Mattermost - Markdown
+var (
+	slackTemplates = parseTemplates(template.FuncMap{
+		"bold": wrap("*", slackEscape), "code": wrap("`", slackEscape), "text": slackEscape,
+	})
+	mattermostTemplates = parseTemplates(template.FuncMap{
+		"bold": wrap("**", markdownEscape), "code": wrap("`", noEscape), "text": markdownEscape,
+	})
+)
+
+// Render возвращает тело запроса в incoming webhook: {"text": ...} понимают и Slack, и Mattermost
+func Render(format string, msg Message) ([]byte, error) {
+	set := slackTemplates
+	if format == models.FormatMattermost {
+		set = mattermostTemplates
+	}
+
+	tmpl, ok := set[msg.Kind]
+	if !ok {
+		return nil, fmt.Errorf("no template for notification %q", msg.Kind)
+	}
+
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, msg); err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]string{"text": text.String()})
+}
+
+func parseTemplates(funcs template.FuncMap) map[string]*template.Template {
+	funcs["duration"] = humanDuration
+	set := make(map[string]*template.Template, len(templates))
+	for kind, text := range templates {
+		set[kind] = template.Must(template.New(kind).Funcs(funcs).Parse(text))
+	}
+	return set
+}
+
+// wrap выделяет значение разметкой; сам знак разметки из значения убирается
+func wrap(mark string, escape func(string) string) func(string) string {
+	return func(s string) string {
+		return mark + escape(strings.ReplaceAll(s, mark, "")) + mark
+	}
+}
+
+func noEscape(s string) string {
+	return s
+}
+
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// markdownEscape не даёт названию PR превратиться в разметку, а @channel - в упоминание
+// (после @ вставляется пробел нулевой ширины)
+func markdownEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "@", "@\u200b",
+	).Replace(s)
+}
+
+// humanDuration округляет до минут: "45m", "5h 10m", "2d 3h"
+func humanDuration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	days, hours := minutes/(24*60), minutes/60%24
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes%60)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
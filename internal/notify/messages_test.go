@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+func TestRender(t *testing.T) {
+	pr := &models.PullRequest{PullRequestID: "pr-1", PullRequestName: "Fix <script> & *bold* @channel"}
+
+	tests := []struct {
+		name   string
+		format string
+		msg    Message
+		want   string
+	}{
+		{
+			name:   "slack escapes html entities",
+			format: models.FormatSlack,
+			msg:    Message{Kind: models.NotifyAssigned, Recipient: "Alice", PR: pr, Author: "Bob"},
+			want:   "*Alice*, you were assigned to review `pr-1` Fix &lt;script&gt; &amp; *bold* @channel by Bob",
+		},
+		{
+			name:   "mattermost escapes markdown and mentions",
+			format: models.FormatMattermost,
+			msg:    Message{Kind: models.NotifyMerged, Recipient: "Alice", PR: pr},
+			want:   "**Alice**, `pr-1` Fix <script> & \\*bold\\* @\u200bchannel you were reviewing has been merged",
+		},
+		{
+			name:   "reassigned with replacement",
+			format: models.FormatSlack,
+			msg:    Message{Kind: models.NotifyReassignedAway, Recipient: "Alice", PR: &models.PullRequest{PullRequestID: "pr-2", PullRequestName: "Add"}, NewReviewer: "Carol"},
+			want:   "*Alice*, you were removed from the review of `pr-2` Add, *Carol* takes over",
+		},
+		{
+			name:   "overdue duration",
+			format: models.FormatMattermost,
+			msg:    Message{Kind: models.NotifyReviewOverdue, Recipient: "Alice", PR: &models.PullRequest{PullRequestID: "pr-2", PullRequestName: "Add"}, Waiting: 26*time.Hour + 30*time.Minute},
+			want:   "**Alice**, `pr-2` Add has been waiting for your review for 1d 2h",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := Render(tt.format, tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var payload struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.Text != tt.want {
+				t.Errorf("text = %q, want %q", payload.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderUnknownKind(t *testing.T) {
+	if _, err := Render(models.FormatSlack, Message{Kind: "unknown", PR: &models.PullRequest{}}); err == nil {
+		t.Error("want error for unknown notification kind")
+	}
+}
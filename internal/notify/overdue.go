@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/metrics"
+	"antonvedaet/internship_task/internal/store"
+)
+
+// overdueBatchSize - сколько назначений отмечается в одной транзакции
+const overdueBatchSize = 100
+
+// OverdueChecker раз в OverdueCheckInterval записывает событие pull_request.review_overdue
+// по каждому назначению в открытом PR старше OverdueAfter. Уведомление отправляет приёмник chat.
+type OverdueChecker struct {
+	db     *store.DB
+	cfg    config.NotificationsConfig
+	logger *slog.Logger
+}
+
+func NewOverdueChecker(db *store.DB, cfg config.NotificationsConfig, logger *slog.Logger) *OverdueChecker {
+	return &OverdueChecker{db: db, cfg: cfg, logger: logger}
+}
+
+func (c *OverdueChecker) Run(ctx context.Context) {
+	c.logger.Info("overdue review checker started", "overdue_after", c.cfg.OverdueAfter, "interval", c.cfg.OverdueCheckInterval)
+	ticker := time.NewTicker(c.cfg.OverdueCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		c.check(ctx)
+		select {
+		case <-ctx.Done():
+			c.logger.Info("overdue review checker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *OverdueChecker) check(ctx context.Context) {
+	for ctx.Err() == nil {
+		marked, err := c.db.MarkOverdueReviews(ctx, time.Now().Add(-c.cfg.OverdueAfter), overdueBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.logger.ErrorContext(ctx, "mark overdue reviews", "error", err)
+			}
+			return
+		}
+		if marked > 0 {
+			metrics.OverdueReviews.Add(float64(marked))
+			c.logger.InfoContext(ctx, "overdue reviews found", "count", marked)
+		}
+		if marked < overdueBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/notify"
+	"antonvedaet/internship_task/internal/store"
+)
+
+// chatSink превращает событие в уведомления ревьюерам и ставит сообщения в очередь каналов;
+// отправку выполняет notify.Dispatcher
+type chatSink struct {
+	db *store.DB
+}
+
+func (s chatSink) Name() string {
+	return config.SinkChat
+}
+
+// notification - кому и о чём сообщить по событию
+type notification struct {
+	kind   string
+	userID string
+}
+
+func (s chatSink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	var envelope struct {
+		Data struct {
+			PR            *models.PullRequest `json:"pr"`
+			OldReviewerID string              `json:"old_reviewer_id"`
+			NewReviewerID string              `json:"new_reviewer_id"`
+			ReviewerID    string              `json:"reviewer_id"`
+			AssignedAt    time.Time           `json:"assigned_at"`
+		} `json:"data"`
+	}
+
+	switch event.EventType {
+	case models.EventPRCreated, models.EventReassigned, models.EventPRMerged, models.EventReviewOverdue:
+	default:
+		return nil
+	}
+	if err := json.Unmarshal(event.Payload, &envelope); err != nil {
+		return err
+	}
+	data := envelope.Data
+	if data.PR == nil {
+		return nil
+	}
+
+	var notifications []notification
+	switch event.EventType {
+	case models.EventPRCreated:
+		for _, reviewerID := range data.PR.AssignedReviewers {
+			notifications = append(notifications, notification{models.NotifyAssigned, reviewerID})
+		}
+	case models.EventReassigned:
+		if data.NewReviewerID != "" {
+			notifications = append(notifications, notification{models.NotifyAssigned, data.NewReviewerID})
+		}
+		notifications = append(notifications, notification{models.NotifyReassignedAway, data.OldReviewerID})
+	case models.EventPRMerged:
+		for _, reviewerID := range data.PR.AssignedReviewers {
+			notifications = append(notifications, notification{models.NotifyMerged, reviewerID})
+		}
+	case models.EventReviewOverdue:
+		notifications = append(notifications, notification{models.NotifyReviewOverdue, data.ReviewerID})
+	}
+
+	// имена автора и нового ревьюера нужны в тексте, поэтому они загружаются вместе с получателями
+	userIDs := []string{data.PR.AuthorID, data.NewReviewerID}
+	for _, n := range notifications {
+		userIDs = append(userIDs, n.userID)
+	}
+	recipients, err := s.db.GetNotificationRecipients(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	users := make(map[string]models.NotificationRecipient, len(recipients))
+	for _, recipient := range recipients {
+		users[recipient.UserID] = recipient
+	}
+
+	var deliveries []models.NotificationDelivery
+	for _, n := range notifications {
+		recipient, ok := users[n.userID]
+		if !ok {
+			continue
+		}
+		msg := notify.Message{
+			Kind:        n.kind,
+			Recipient:   recipient.Username,
+			PR:          data.PR,
+			Author:      usernameOf(users, data.PR.AuthorID),
+			NewReviewer: usernameOf(users, data.NewReviewerID),
+			Waiting:     event.CreatedAt.Sub(data.AssignedAt),
+		}
+
+		for _, channel := range recipient.Channels {
+			if len(channel.Kinds) > 0 && !slices.Contains(channel.Kinds, n.kind) {
+				continue
+			}
+			payload, err := notify.Render(channel.Format, msg)
+			if err != nil {
+				return err
+			}
+			deliveries = append(deliveries, models.NotificationDelivery{
+				ChannelID:   channel.ChannelID,
+				EventID:     event.EventID,
+				RecipientID: n.userID,
+				Kind:        n.kind,
+				Payload:     payload,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	_, err = s.db.EnqueueNotifications(ctx, deliveries)
+	return err
+}
+
+// usernameOf возвращает имя пользователя, а для удалённого - его id
+func usernameOf(users map[string]models.NotificationRecipient, userID string) string {
+	if user, ok := users[userID]; ok {
+		return user.Username
+	}
+	return userID
+}
@@ -0,0 +1,139 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"antonvedaet/internship_task/internal/codehost"
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+// CodeHostClient запрашивает и снимает ревьюеров PR на хостинге кода по их логинам
+type CodeHostClient interface {
+	RequestReviewers(ctx context.Context, ref codehost.PullRequestRef, logins []string) error
+	RemoveReviewers(ctx context.Context, ref codehost.PullRequestRef, logins []string) error
+}
+
+// NewCodeHostClients создаёт клиенты хостингов, для которых задан токен
+func NewCodeHostClients(cfg config.IntegrationsConfig) map[string]CodeHostClient {
+	client := codehost.NewHTTPClient()
+
+	clients := make(map[string]CodeHostClient)
+	if cfg.GitHub.Token != "" {
+		clients[models.ProviderGitHub] = codehost.NewGitHubClient(cfg.GitHub.APIURL, cfg.GitHub.Token, client)
+	}
+	if cfg.GitLab.Token != "" {
+		clients[models.ProviderGitLab] = codehost.NewGitLabClient(cfg.GitLab.APIURL, cfg.GitLab.Token, client)
+	}
+	return clients
+}
+
+// codeHostLockTTL - на сколько берётся блокировка PR; публикация в приёмник ограничена publishTimeout
+const codeHostLockTTL = 2 * publishTimeout
+
+// codeHostSink переносит назначения ревьюеров на хостинг. Запросы к API идут из диспетчера outbox,
+// поэтому недоступность хостинга не ломает создание PR и переназначение: событие повторяется с backoff.
+// Повтор безопасен - запрос уже запрошенного ревьюера и снятие уже снятого ничего не меняют.
+type codeHostSink struct {
+	db      *store.DB
+	clients map[string]CodeHostClient
+	logger  *slog.Logger
+}
+
+func (s *codeHostSink) Name() string {
+	return config.SinkCodeHost
+}
+
+func (s *codeHostSink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	if event.EventType != models.EventPRCreated && event.EventType != models.EventReassigned {
+		return nil
+	}
+
+	var envelope struct {
+		Data models.ReassignedData `json:"data"`
+	}
+	if err := json.Unmarshal(event.Payload, &envelope); err != nil {
+		return err
+	}
+	data := envelope.Data
+	if data.PR == nil {
+		return nil
+	}
+
+	// PR, созданные не из вебхуков хостингов, на хостинге не найти
+	ref, ok := codehost.ParsePullRequestID(data.PR.PullRequestID)
+	if !ok {
+		return nil
+	}
+	client, ok := s.clients[ref.Provider]
+	if !ok {
+		return nil
+	}
+
+	var requested, removed []string
+	if event.EventType == models.EventPRCreated {
+		requested = data.PR.AssignedReviewers
+	} else {
+		if data.NewReviewerID != "" {
+			requested = []string{data.NewReviewerID}
+		}
+		removed = []string{data.OldReviewerID}
+	}
+
+	err := s.sync(ctx, client, ref, data.PR.PullRequestID, requested, removed)
+	var apiErr *codehost.Error
+	if errors.As(err, &apiErr) && !apiErr.Retryable() {
+		// отказ хостинга (нет доступа, пользователь не может ревьюить) повтор не исправит
+		s.logger.WarnContext(ctx, "code host rejected reviewer update",
+			"event_id", event.EventID,
+			"pull_request_id", data.PR.PullRequestID,
+			"status_code", apiErr.StatusCode,
+			"error", apiErr.Message,
+		)
+		return nil
+	}
+	return err
+}
+
+// sync сначала запрашивает нового ревьюера, потом снимает старого, чтобы PR не остался без ревьюеров.
+// События одного PR могут публиковать разные реплики и горутины, а GitLab принимает только полный
+// список ревьюеров, поэтому изменения одного PR идут под блокировкой в БД. Если её держит другой,
+// событие повторяется с backoff, а не ждёт.
+func (s *codeHostSink) sync(ctx context.Context, client CodeHostClient, ref codehost.PullRequestRef, prID string, requested, removed []string) error {
+	logins, err := s.db.GetCodeHostLogins(ctx, ref.Provider, slices.Concat(requested, removed))
+	if err != nil {
+		return err
+	}
+
+	unlock, err := s.db.TryLock(ctx, "codehost:"+prID, codeHostLockTTL)
+	if err != nil {
+		return fmt.Errorf("lock pull request %s: %w", prID, err)
+	}
+	defer unlock()
+
+	// запросы к API не переживают блокировку
+	ctx, cancel := context.WithTimeout(ctx, codeHostLockTTL)
+	defer cancel()
+
+	if err := client.RequestReviewers(ctx, ref, loginsOf(requested, logins)); err != nil {
+		return err
+	}
+	return client.RemoveReviewers(ctx, ref, loginsOf(removed, logins))
+}
+
+// loginsOf пропускает пользователей, не связанных с аккаунтом на хостинге
+func loginsOf(userIDs []string, logins map[string]string) []string {
+	var result []string
+	for _, userID := range userIDs {
+		if login, ok := logins[userID]; ok {
+			result = append(result, login)
+		}
+	}
+	return result
+}
@@ -0,0 +1,193 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"antonvedaet/internship_task/internal/codehost"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+type fakeCodeHostClient struct {
+	calls []string
+	err   error
+}
+
+func (f *fakeCodeHostClient) RequestReviewers(_ context.Context, ref codehost.PullRequestRef, logins []string) error {
+	f.calls = append(f.calls, "request "+ref.Repo+" "+join(logins))
+	return f.err
+}
+
+func (f *fakeCodeHostClient) RemoveReviewers(_ context.Context, ref codehost.PullRequestRef, logins []string) error {
+	f.calls = append(f.calls, "remove "+ref.Repo+" "+join(logins))
+	return f.err
+}
+
+func join(logins []string) string {
+	data, _ := json.Marshal(logins)
+	return string(data)
+}
+
+func reassignedEvent(t *testing.T, prID string) models.OutboxEvent {
+	t.Helper()
+
+	payload, err := json.Marshal(models.Event{
+		EventID: "evt_1",
+		Type:    models.EventReassigned,
+		Data: models.ReassignedData{
+			PR:            &models.PullRequest{PullRequestID: prID, AssignedReviewers: []string{"u2", "u3"}},
+			OldReviewerID: "u1",
+			NewReviewerID: "u3",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models.OutboxEvent{EventID: "evt_1", EventType: models.EventReassigned, Payload: payload}
+}
+
+func newCodeHostSink(t *testing.T, client CodeHostClient) (*codeHostSink, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return &codeHostSink{
+		db:      store.Wrap(sqlDB, time.Second),
+		clients: map[string]CodeHostClient{models.ProviderGitHub: client},
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}, mock
+}
+
+func expectSync(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("FROM code_host_accounts").
+		WithArgs(models.ProviderGitHub, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "login"}).AddRow("u1", "alice-dev").AddRow("u3", "carol"))
+	expectLock(mock, true, 1)
+	mock.ExpectExec("DELETE FROM locks").WithArgs("codehost:acme/api#42", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// expectLock - короткая транзакция TryLock; claimed - сколько строк locks она заняла
+func expectLock(mock sqlmock.Sqlmock, advisory bool, claimed int64) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("pg_try_advisory_xact_lock").
+		WithArgs("codehost:acme/api#42").
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(advisory))
+	if !advisory {
+		mock.ExpectRollback()
+		return
+	}
+	mock.ExpectExec("INSERT INTO locks").
+		WithArgs("codehost:acme/api#42", sqlmock.AnyArg(), codeHostLockTTL.Milliseconds()).
+		WillReturnResult(sqlmock.NewResult(0, claimed))
+	if claimed == 0 {
+		mock.ExpectRollback()
+		return
+	}
+	mock.ExpectCommit()
+}
+
+func TestCodeHostSinkReassign(t *testing.T) {
+	client := &fakeCodeHostClient{}
+	sink, mock := newCodeHostSink(t, client)
+	expectSync(mock)
+
+	if err := sink.Publish(context.Background(), reassignedEvent(t, "acme/api#42")); err != nil {
+		t.Fatal(err)
+	}
+
+	// новый ревьюер запрашивается раньше, чем снимается старый
+	want := []string{`request acme/api ["carol"]`, `remove acme/api ["alice-dev"]`}
+	if len(client.calls) != len(want) || client.calls[0] != want[0] || client.calls[1] != want[1] {
+		t.Errorf("calls = %v, want %v", client.calls, want)
+	}
+}
+
+func TestCodeHostSinkErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{"rejected by code host", &codehost.Error{Provider: models.ProviderGitHub, StatusCode: http.StatusUnprocessableEntity}, false},
+		{"forbidden", &codehost.Error{Provider: models.ProviderGitHub, StatusCode: http.StatusForbidden}, false},
+		{"rate limited", &codehost.Error{Provider: models.ProviderGitHub, StatusCode: http.StatusForbidden, RateLimited: true}, true},
+		{"server error", &codehost.Error{Provider: models.ProviderGitHub, StatusCode: http.StatusBadGateway}, true},
+		{"network error", errors.New("dial tcp: connection refused"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink, mock := newCodeHostSink(t, &fakeCodeHostClient{err: tt.err})
+			expectSync(mock)
+
+			err := sink.Publish(context.Background(), reassignedEvent(t, "acme/api#42"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Publish() error = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// PR, который уже меняет другая реплика, не ждёт её: событие повторится с backoff
+func TestCodeHostSinkLocked(t *testing.T) {
+	tests := []struct {
+		name     string
+		advisory bool
+		claimed  int64
+	}{
+		{"lock row is being written", false, 0},
+		{"lock is held", true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeCodeHostClient{}
+			sink, mock := newCodeHostSink(t, client)
+			mock.ExpectQuery("FROM code_host_accounts").
+				WithArgs(models.ProviderGitHub, sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"user_id", "login"}).AddRow("u3", "carol"))
+			expectLock(mock, tt.advisory, tt.claimed)
+
+			err := sink.Publish(context.Background(), reassignedEvent(t, "acme/api#42"))
+			if !errors.Is(err, store.ErrLocked) {
+				t.Errorf("Publish() error = %v, want ErrLocked", err)
+			}
+			if len(client.calls) > 0 {
+				t.Errorf("calls = %v, want none", client.calls)
+			}
+		})
+	}
+}
+
+func TestCodeHostSinkSkipsForeignPRs(t *testing.T) {
+	client := &fakeCodeHostClient{}
+	sink, _ := newCodeHostSink(t, client)
+
+	// PR из REST API и PR хостинга без клиента не трогают ни БД, ни API
+	for _, prID := range []string{"pr-1001", "platform/billing!17"} {
+		if err := sink.Publish(context.Background(), reassignedEvent(t, prID)); err != nil {
+			t.Errorf("%s: %v", prID, err)
+		}
+	}
+	if len(client.calls) > 0 {
+		t.Errorf("calls = %v, want none", client.calls)
+	}
+}
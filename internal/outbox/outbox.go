@@ -0,0 +1,204 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/delivery"
+	"antonvedaet/internship_task/internal/metrics"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+	"antonvedaet/internship_task/internal/tracing"
+)
+
+const (
+	// publishTimeout ограничивает публикацию события в один приёмник
+	publishTimeout = 10 * time.Second
+	// minLease - на сколько пачка событий скрывается от других реплик, пока идёт публикация
+	minLease = time.Minute
+	// cleanupInterval - как часто удаляются опубликованные события старше Retention
+	cleanupInterval = time.Hour
+)
+
+// Dispatcher публикует события из outbox_events во все приёмники. Событие, которое
+// не удалось опубликовать хотя бы в один приёмник, повторяется с экспоненциальной задержкой
+// только для оставшихся приёмников, а после MaxAttempts попыток переходит в dead.
+type Dispatcher struct {
+	db     *store.DB
+	sinks  []Sink
+	cfg    config.OutboxConfig
+	logger *slog.Logger
+	// now подменяется в тестах продления аренды
+	now func() time.Time
+
+	lastCleanup time.Time
+}
+
+func NewDispatcher(db *store.DB, sinks []Sink, cfg config.OutboxConfig, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{db: db, sinks: sinks, cfg: cfg, logger: logger, now: time.Now}
+}
+
+// Run работает до отмены ctx. Событие, публикация которого прервана остановкой,
+// вернётся в очередь по истечении аренды и будет опубликовано повторно.
+func (d *Dispatcher) Run(ctx context.Context) {
+	sinkNames := make([]string, len(d.sinks))
+	for i, sink := range d.sinks {
+		sinkNames[i] = sink.Name()
+	}
+	d.logger.Info("outbox dispatcher started", "sinks", sinkNames, "poll_interval", d.cfg.PollInterval)
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.drain(ctx)
+		d.cleanup(ctx)
+		select {
+		case <-ctx.Done():
+			d.logger.Info("outbox dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain публикует пачки, пока очередь не опустеет. События пачки публикуются
+// по одному в порядке записи, чтобы приёмники получали их в том же порядке.
+// Пачка из BatchSize событий может публиковаться дольше аренды, поэтому аренда оставшихся
+// событий продлевается раньше, чем её может не хватить на очередное событие: иначе их
+// забрала бы другая реплика и опубликовала повторно и не по порядку.
+func (d *Dispatcher) drain(ctx context.Context) {
+	// eventBudget - публикация события во все приёмники и отметка о ней в БД укладываются в это время
+	eventBudget := publishTimeout * time.Duration(len(d.sinks)+1)
+	lease := max(minLease, 2*eventBudget)
+
+	for ctx.Err() == nil {
+		leasedUntil := d.now().Add(lease)
+		events, err := d.db.ClaimOutboxEvents(ctx, d.cfg.BatchSize, lease)
+		if err != nil {
+			if ctx.Err() == nil {
+				d.logger.ErrorContext(ctx, "claim outbox events", "error", err)
+			}
+			return
+		}
+
+		for i, event := range events {
+			if leasedUntil.Sub(d.now()) < eventBudget {
+				leasedUntil = d.now().Add(lease)
+				if err := d.db.ExtendOutboxLease(ctx, seqsOf(events[i:]), lease); err != nil {
+					// остальные события вернутся в очередь по истечении аренды
+					if ctx.Err() == nil {
+						d.logger.ErrorContext(ctx, "extend outbox lease", "error", err)
+					}
+					return
+				}
+			}
+			d.publish(ctx, event)
+		}
+
+		if len(events) < d.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) publish(ctx context.Context, event models.OutboxEvent) {
+	ctx, span := tracing.Start(ctx, "Outbox.Publish")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("outbox.event_id", event.EventID),
+		attribute.String("outbox.event_type", event.EventType),
+	)
+
+	published := slices.Clone(event.PublishedSinks)
+	var errs []error
+	for _, sink := range d.sinks {
+		if slices.Contains(published, sink.Name()) {
+			continue
+		}
+
+		sinkCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+		err := sink.Publish(sinkCtx, event)
+		cancel()
+		if ctx.Err() != nil {
+			// остановка сервиса: попытка не засчитывается
+			return
+		}
+
+		if err != nil {
+			metrics.OutboxPublishes.WithLabelValues(sink.Name(), "error").Inc()
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			continue
+		}
+		metrics.OutboxPublishes.WithLabelValues(sink.Name(), "published").Inc()
+		published = append(published, sink.Name())
+	}
+
+	if len(errs) == 0 {
+		if err := d.db.MarkOutboxPublished(ctx, event.Seq, published); err != nil {
+			d.logger.ErrorContext(ctx, "mark outbox event published", "event_id", event.EventID, "error", err)
+		}
+		return
+	}
+
+	err := errors.Join(errs...)
+	span.SetStatus(codes.Error, err.Error())
+
+	attempts := event.Attempts + 1
+	var nextAttemptAt *time.Time
+	if attempts < d.cfg.MaxAttempts {
+		next := time.Now().Add(delivery.Backoff(d.cfg.BackoffBase, d.cfg.BackoffMax, attempts))
+		nextAttemptAt = &next
+		d.logger.WarnContext(ctx, "outbox publish failed",
+			"event_id", event.EventID,
+			"event_type", event.EventType,
+			"attempts", attempts,
+			"error", err,
+			"next_attempt_at", next,
+		)
+	} else {
+		metrics.OutboxDeadLetters.Inc()
+		d.logger.ErrorContext(ctx, "outbox event moved to dead",
+			"event_id", event.EventID,
+			"event_type", event.EventType,
+			"attempts", attempts,
+			"error", err,
+		)
+	}
+
+	if err := d.db.MarkOutboxAttemptFailed(ctx, event.Seq, published, err.Error(), nextAttemptAt); err != nil {
+		d.logger.ErrorContext(ctx, "mark outbox attempt failed", "event_id", event.EventID, "error", err)
+	}
+}
+
+func (d *Dispatcher) cleanup(ctx context.Context) {
+	if d.cfg.Retention == 0 || time.Since(d.lastCleanup) < cleanupInterval || ctx.Err() != nil {
+		return
+	}
+	d.lastCleanup = time.Now()
+
+	deleted, err := d.db.DeletePublishedOutboxEvents(ctx, time.Now().Add(-d.cfg.Retention))
+	if err != nil {
+		d.logger.ErrorContext(ctx, "delete published outbox events", "error", err)
+		return
+	}
+	if deleted > 0 {
+		d.logger.InfoContext(ctx, "published outbox events deleted", "deleted", deleted, "retention", d.cfg.Retention)
+	}
+}
+
+func seqsOf(events []models.OutboxEvent) []int64 {
+	seqs := make([]int64, len(events))
+	for i, event := range events {
+		seqs[i] = event.Seq
+	}
+	return seqs
+}
@@ -0,0 +1,73 @@
+package outbox
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+// slowSink публикует событие за step по часам диспетчера
+type slowSink struct {
+	clock     *time.Time
+	step      time.Duration
+	published []string
+}
+
+func (s *slowSink) Name() string {
+	return config.SinkStdout
+}
+
+func (s *slowSink) Publish(_ context.Context, event models.OutboxEvent) error {
+	*s.clock = s.clock.Add(s.step)
+	s.published = append(s.published, event.EventID)
+	return nil
+}
+
+func TestDispatcherExtendsLeaseOfSlowBatch(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	clock := time.Now()
+	sink := &slowSink{clock: &clock, step: 15 * time.Second}
+	d := NewDispatcher(store.Wrap(sqlDB, time.Second), []Sink{sink}, config.OutboxConfig{BatchSize: 100}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	d.now = func() time.Time { return clock }
+
+	// один приёмник: на событие отводится 20s, аренда - минимальная, 1m
+	lease := minLease.Milliseconds()
+	rows := sqlmock.NewRows([]string{"seq", "event_id", "event_type", "payload", "attempts", "published_sinks", "created_at"})
+	for seq := 1; seq <= 5; seq++ {
+		rows.AddRow(seq, "evt_"+string(rune('0'+seq)), models.EventPRCreated, []byte(`{}`), 0, pq.StringArray{}, clock)
+	}
+	mock.ExpectQuery("UPDATE outbox_events e").WithArgs(100, lease).WillReturnRows(rows)
+	for seq := 1; seq <= 3; seq++ {
+		mock.ExpectExec("SET status = 'published'").WithArgs(seq, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	// к четвёртому событию (45s) аренды осталось 15s - меньше, чем нужно на событие
+	mock.ExpectExec("UPDATE outbox_events\\s+SET next_attempt_at").
+		WithArgs(pq.Array([]int64{4, 5}), lease).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	for seq := 4; seq <= 5; seq++ {
+		mock.ExpectExec("SET status = 'published'").WithArgs(seq, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	d.drain(context.Background())
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if len(sink.published) != 5 {
+		t.Errorf("published %v, want 5 events", sink.published)
+	}
+}
@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+// Sink - приёмник событий. Доставка at-least-once: после сбоя событие может прийти
+// в приёмник повторно, получатели отбрасывают дубликаты по id события.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event models.OutboxEvent) error
+}
+
+// NewSinks создаёт приёмники в порядке cfg.Sinks; имена проверены при загрузке конфигурации
+func NewSinks(cfg config.OutboxConfig, integrations config.IntegrationsConfig, db *store.DB, logger *slog.Logger) []Sink {
+	sinks := make([]Sink, 0, len(cfg.Sinks))
+	for _, name := range cfg.Sinks {
+		switch name {
+		case config.SinkWebhook:
+			sinks = append(sinks, webhookSink{db: db})
+		case config.SinkStdout:
+			sinks = append(sinks, &writerSink{name: name, w: os.Stdout})
+		case config.SinkFile:
+			sinks = append(sinks, &fileSink{path: cfg.FilePath})
+		case config.SinkChat:
+			sinks = append(sinks, chatSink{db: db})
+		case config.SinkCodeHost:
+			sinks = append(sinks, &codeHostSink{db: db, clients: NewCodeHostClients(integrations), logger: logger})
+		}
+	}
+	return sinks
+}
+
+// webhookSink раскладывает событие по очередям подписок; отправку выполняет webhook.Dispatcher
+type webhookSink struct {
+	db *store.DB
+}
+
+func (s webhookSink) Name() string {
+	return config.SinkWebhook
+}
+
+func (s webhookSink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	_, err := s.db.EnqueueWebhookEvent(ctx, event.EventID, event.EventType, event.Payload)
+	return err
+}
+
+// writerSink пишет события построчно в JSON, например в stdout для отладки
+type writerSink struct {
+	name string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *writerSink) Name() string {
+	return s.name
+}
+
+func (s *writerSink) Publish(_ context.Context, event models.OutboxEvent) error {
+	line, err := jsonLine(event.Payload)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(line)
+	return err
+}
+
+// fileSink дописывает события в файл JSON Lines - локальная замена топика NATS/Kafka:
+// потребитель читает файл с нужного смещения (tail -f). Файл открывается на каждую запись,
+// поэтому его можно ротировать без перезапуска сервиса.
+type fileSink struct {
+	path string
+
+	mu sync.Mutex
+}
+
+func (s *fileSink) Name() string {
+	return config.SinkFile
+}
+
+func (s *fileSink) Publish(_ context.Context, event models.OutboxEvent) error {
+	line, err := jsonLine(event.Payload)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	// событие считается опубликованным только после сброса на диск
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func jsonLine(payload json.RawMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, payload); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
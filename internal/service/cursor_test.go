@@ -0,0 +1,50 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"backend"},
+		{"42"},
+		{"2025-10-24T12:34:56.123456789Z", "acme/api#42"},
+		// разделитель частей не встречается в идентификаторах, пустая часть допустима
+		{"", "pr-1"},
+	}
+
+	for _, parts := range tests {
+		cursor := encodeCursor(parts...)
+		got, err := decodeCursor(cursor, len(parts))
+		if err != nil {
+			t.Fatalf("decodeCursor(%q): %v", cursor, err)
+		}
+		if !reflect.DeepEqual(got, parts) {
+			t.Errorf("decodeCursor(encodeCursor(%q)) = %q", parts, got)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+		parts  int
+	}{
+		{"not base64", "not a cursor!", 1},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("u1")), 1},
+		{"too few parts", encodeCursor("2025-10-24T12:34:56Z"), 2},
+		{"too many parts", encodeCursor("2025-10-24T12:34:56Z", "pr-1"), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor, tt.parts); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
@@ -1,13 +1,88 @@
 package service
 
-import "errors"
+import (
+	"strconv"
+	"strings"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+// Code - машинно-читаемый код ошибки, отдаётся клиенту в поле error.code (см. ErrorResponse в openapi.yml)
+type Code string
+
+const (
+	CodeInvalidRequest  Code = "INVALID_REQUEST"
+	CodeValidation      Code = "VALIDATION_ERROR"
+	CodeNotFound        Code = "NOT_FOUND"
+	CodeTeamExists      Code = "TEAM_EXISTS"
+	CodePRExists        Code = "PR_EXISTS"
+	CodePRMerged        Code = "PR_MERGED"
+	CodeNotAssigned     Code = "NOT_ASSIGNED"
+	CodeNoCandidate     Code = "NO_CANDIDATE"
+	CodeUserInTeam      Code = "USER_IN_TEAM"
+	CodeUserInOtherTeam Code = "USER_IN_OTHER_TEAM"
+	CodeTeamHasOpenPRs  Code = "TEAM_HAS_OPEN_PRS"
+	CodeTeamHasChildren Code = "TEAM_HAS_CHILDREN"
+	CodeUnauthorized    Code = "UNAUTHORIZED"
+	CodeForbidden       Code = "FORBIDDEN"
+	CodeEventNotDead    Code = "EVENT_NOT_DEAD"
+	CodePRChanged       Code = "PR_CHANGED"
+)
+
+// Error - ошибка предметной области. Сообщение безопасно показывать клиенту,
+// остальные ошибки сервисов считаются внутренними.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
 
 var (
-	ErrTeamExists           = errors.New("team already exists")
-	ErrPRExists             = errors.New("PR already exists")
-	ErrNotFound             = errors.New("not found")
-	ErrPRAlreadyMerged      = errors.New("PR already merged")
-	ErrReviewerNotAssigned  = errors.New("reviewer not assigned")
-	ErrNoAvailableReviewers = errors.New("no available reviewers")
-	ErrUserNotInTeam        = errors.New("user not in team")
+	ErrTeamNotFound         = newError(CodeNotFound, "team not found")
+	ErrUserNotFound         = newError(CodeNotFound, "user not found")
+	ErrPRNotFound           = newError(CodeNotFound, "PR not found")
+	ErrTeamExists           = newError(CodeTeamExists, "team_name already exists")
+	ErrPRExists             = newError(CodePRExists, "PR id already exists")
+	ErrPRAlreadyMerged      = newError(CodePRMerged, "cannot reassign on merged PR")
+	ErrReviewerNotAssigned  = newError(CodeNotAssigned, "reviewer is not assigned to this PR")
+	ErrPRChanged            = newError(CodePRChanged, "PR was changed by a concurrent request, retry")
+	ErrNoAvailableReviewers = newError(CodeNoCandidate, "no active replacement candidate in team")
+	ErrUserNotInTeam        = newError(CodeNotFound, "user is not a member of the team")
+	ErrInvalidCursor        = newError(CodeInvalidRequest, "invalid cursor")
+	ErrUserInOtherTeam      = newError(CodeUserInOtherTeam, "user belongs to another team, use /team/moveMember")
+	ErrUserInTeam           = newError(CodeUserInTeam, "user is already a member of the team")
+	ErrInvalidReviewPolicy  = newError(CodeInvalidRequest, "review_policy must be one of keep, reassign, unassign")
+	ErrInvalidTeamSettings  = newError(CodeInvalidRequest, "settings.required_reviewers must be between 0 and "+strconv.Itoa(MaxRequiredReviewers))
+	ErrTeamHasOpenPRs       = newError(CodeTeamHasOpenPRs, "team members have open PRs")
+	ErrTeamHasChildren      = newError(CodeTeamHasChildren, "team has child teams")
+	ErrInvalidParentTeam    = newError(CodeInvalidRequest, "parent_team must be an existing team outside of this team's subtree")
+	ErrInvalidTimeRange     = newError(CodeInvalidRequest, "from must be earlier than to")
+	ErrUnauthorized         = newError(CodeUnauthorized, "missing or invalid bearer token")
+	ErrForbidden            = newError(CodeForbidden, "token role is not allowed to perform this action")
+	ErrTokenNotFound        = newError(CodeNotFound, "token not found")
+	ErrInvalidTokenRole     = newError(CodeInvalidRequest, "role must be one of admin, user, service")
+	ErrInvalidTokenUser     = newError(CodeInvalidRequest, "user_id is required for role user and not allowed for other roles")
+	ErrWebhookNotFound      = newError(CodeNotFound, "webhook subscription not found")
+	ErrInvalidWebhookURL    = newError(CodeInvalidRequest, "url must be an absolute http(s) URL")
+	ErrInvalidWebhookEvent  = newError(CodeInvalidRequest, "events must contain only "+strings.Join(models.EventTypes, ", "))
+	ErrInvalidWebhookSecret = newError(CodeInvalidRequest, "secret must be at least "+strconv.Itoa(minWebhookSecretLength)+" characters")
+	ErrInvalidDeliveryState = newError(CodeInvalidRequest, "status must be one of pending, delivered, failed")
+	ErrEventNotFound        = newError(CodeNotFound, "outbox event not found")
+	ErrEventNotDead         = newError(CodeEventNotDead, "only dead events can be requeued")
+	ErrInvalidEventStatus   = newError(CodeInvalidRequest, "status must be one of pending, published, dead")
+	ErrInvalidSignature     = newError(CodeUnauthorized, "missing or invalid webhook signature")
+	ErrInvalidWebhookToken  = newError(CodeUnauthorized, "missing or invalid webhook token")
+	ErrInvalidProvider      = newError(CodeInvalidRequest, "provider must be one of "+strings.Join(models.Providers, ", "))
+	ErrAccountNotFound      = newError(CodeNotFound, "code host account not found")
+	ErrChannelNotFound      = newError(CodeNotFound, "notification channel not found")
+	ErrInvalidChannelTarget = newError(CodeInvalidRequest, "exactly one of team_name and user_id is required")
+	ErrInvalidChannelFormat = newError(CodeInvalidRequest, "format must be one of "+strings.Join(models.NotificationFormats, ", "))
+	ErrInvalidChannelKinds  = newError(CodeInvalidRequest, "kinds must contain only "+strings.Join(models.NotificationKinds, ", "))
 )
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+const readinessTimeout = 2 * time.Second
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+type healthService struct {
+	db           *store.DB
+	shuttingDown atomic.Bool
+}
+
+func NewHealthService(db *store.DB) HealthService {
+	return &healthService{db: db}
+}
+
+func (s *healthService) Ready(ctx context.Context) *models.ReadinessResponse {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	response := &models.ReadinessResponse{
+		Status: StatusReady,
+		Checks: map[string]models.DependencyStatus{
+			"server":     s.checkServer(),
+			"database":   s.checkDatabase(ctx),
+			"migrations": s.checkMigrations(ctx),
+		},
+	}
+
+	for _, check := range response.Checks {
+		if check.Status != StatusUp {
+			response.Status = StatusNotReady
+		}
+	}
+
+	return response
+}
+
+// SetShuttingDown переводит /ready в состояние not_ready, чтобы балансировщик
+// перестал присылать новые запросы до остановки сервера
+func (s *healthService) SetShuttingDown() {
+	s.shuttingDown.Store(true)
+}
+
+func (s *healthService) checkServer() models.DependencyStatus {
+	if s.shuttingDown.Load() {
+		return models.DependencyStatus{Status: StatusDown, Error: "shutting down"}
+	}
+	return models.DependencyStatus{Status: StatusUp}
+}
+
+func (s *healthService) checkDatabase(ctx context.Context) models.DependencyStatus {
+	start := time.Now()
+	err := s.db.PingContext(ctx)
+	return dependencyStatus(start, err)
+}
+
+func (s *healthService) checkMigrations(ctx context.Context) models.DependencyStatus {
+	start := time.Now()
+	version, err := s.db.GetSchemaVersion(ctx)
+	if err == nil && version < store.SchemaVersion {
+		err = fmt.Errorf("schema version %d is behind expected %d", version, store.SchemaVersion)
+	}
+
+	status := dependencyStatus(start, err)
+	expected := store.SchemaVersion
+	status.Expected = &expected
+	if version > 0 {
+		status.Version = &version
+	}
+	return status
+}
+
+func dependencyStatus(start time.Time, err error) models.DependencyStatus {
+	status := models.DependencyStatus{
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+	return status
+}
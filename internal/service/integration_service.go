@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"antonvedaet/internship_task/internal/codehost"
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/metrics"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+	"antonvedaet/internship_task/internal/tracing"
+)
+
+// maxPRNameLength - длина pull_requests.pull_request_name; заголовки на хостинге бывают длиннее
+const maxPRNameLength = 255
+
+// maxPRIDLength - длина pull_requests.pull_request_id и предел PullRequestId в openapi.yml
+const maxPRIDLength = 255
+
+type integrationService struct {
+	db        *store.DB
+	prService PRService
+	gitlab    *codehost.GitLabClient
+	cfg       config.IntegrationsConfig
+	logger    *slog.Logger
+}
+
+// NewIntegrationService создаёт и мержит PR через prService, так же как REST-обработчики
+func NewIntegrationService(db *store.DB, prService PRService, cfg config.IntegrationsConfig, logger *slog.Logger) IntegrationService {
+	return &integrationService{
+		db:        db,
+		prService: prService,
+		gitlab:    codehost.NewGitLabClient(cfg.GitLab.APIURL, cfg.GitLab.Token, codehost.NewHTTPClient()),
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+func (s *integrationService) LinkAccount(ctx context.Context, req *models.LinkAccountRequest) (*models.CodeHostAccount, error) {
+	ctx, span := tracing.Start(ctx, "IntegrationService.LinkAccount")
+	defer span.End()
+
+	if !slices.Contains(models.Providers, req.Provider) {
+		return nil, ErrInvalidProvider
+	}
+
+	account := &models.CodeHostAccount{
+		Provider: req.Provider,
+		Login:    normalizeLogin(req.Login),
+		UserID:   req.UserID,
+	}
+	if err := s.db.UpsertCodeHostAccount(ctx, account); err != nil {
+		if errors.Is(err, store.ErrReferenceViolation) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "code host account linked", "provider", account.Provider, "login", account.Login, "user_id", account.UserID)
+	return account, nil
+}
+
+func (s *integrationService) ListAccounts(ctx context.Context, query models.CodeHostAccountQuery) ([]models.CodeHostAccount, error) {
+	ctx, span := tracing.Start(ctx, "IntegrationService.ListAccounts")
+	defer span.End()
+
+	if query.Provider != "" && !slices.Contains(models.Providers, query.Provider) {
+		return nil, ErrInvalidProvider
+	}
+
+	return s.db.ListCodeHostAccounts(ctx, query)
+}
+
+func (s *integrationService) UnlinkAccount(ctx context.Context, provider, login string) error {
+	ctx, span := tracing.Start(ctx, "IntegrationService.UnlinkAccount")
+	defer span.End()
+
+	if !slices.Contains(models.Providers, provider) {
+		return ErrInvalidProvider
+	}
+
+	login = normalizeLogin(login)
+	err := s.db.DeleteCodeHostAccount(ctx, provider, login)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "code host account unlinked", "provider", provider, "login", login)
+	return nil
+}
+
+// VerifyGitHubSignature проверяет заголовок X-Hub-Signature-256: "sha256=" + hex(HMAC-SHA256(secret, body))
+func (s *integrationService) VerifyGitHubSignature(body []byte, signature string) bool {
+	digest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok || s.cfg.GitHub.WebhookSecret == "" {
+		return false
+	}
+	got, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.cfg.GitHub.WebhookSecret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// HandleGitHubPullRequest переводит событие pull_request в создание, мерж или удаление PR.
+// PR получает id "owner/repo#number", автор ищется по логину в code_host_accounts.
+// Повторная доставка того же события ничего не меняет и возвращает ignored.
+func (s *integrationService) HandleGitHubPullRequest(ctx context.Context, event *models.GitHubPullRequestEvent) (*models.IntegrationEventResponse, error) {
+	ctx, span := tracing.Start(ctx, "IntegrationService.HandleGitHubPullRequest")
+	defer span.End()
+
+	pr := event.PullRequest
+	prID := fmt.Sprintf("%s#%d", event.Repository.FullName, pr.Number)
+
+	var result *models.IntegrationEventResponse
+	var err error
+	switch event.Action {
+	case "opened", "reopened", "ready_for_review":
+		if pr.Draft {
+			result = ignored(prID, "draft pull request")
+			break
+		}
+		result, err = s.openPR(ctx, models.ProviderGitHub, prID, pr.Title, pr.User.Login)
+	case "closed":
+		if !pr.Merged {
+			result, err = s.deletePR(ctx, prID)
+			break
+		}
+		result, err = s.mergePR(ctx, prID)
+	default:
+		result = ignored(prID, fmt.Sprintf("action %q is not handled", event.Action))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordResult(ctx, models.ProviderGitHub, event.Action, result)
+	return result, nil
+}
+
+// VerifyGitLabToken сравнивает X-Gitlab-Token с секретным токеном вебхука
+func (s *integrationService) VerifyGitLabToken(token string) bool {
+	expected := s.cfg.GitLab.WebhookToken
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// HandleGitLabMergeRequest переводит Merge Request Hook в создание, мерж или удаление PR с id "group/project!iid".
+// Снятие отметки draft (update с changes.draft) создаёт PR так же, как open.
+func (s *integrationService) HandleGitLabMergeRequest(ctx context.Context, event *models.GitLabMergeRequestEvent) (*models.IntegrationEventResponse, error) {
+	ctx, span := tracing.Start(ctx, "IntegrationService.HandleGitLabMergeRequest")
+	defer span.End()
+
+	mr := event.ObjectAttributes
+	prID := fmt.Sprintf("%s!%d", event.Project.PathWithNamespace, mr.IID)
+
+	var result *models.IntegrationEventResponse
+	var err error
+	switch mr.Action {
+	case "open", "reopen":
+		if mr.Draft || mr.WorkInProgress {
+			result = ignored(prID, "draft merge request")
+			break
+		}
+		result, err = s.openGitLabMR(ctx, prID, event)
+	case "update":
+		switch change := event.Changes.Draft; {
+		case change == nil:
+			result = ignored(prID, "update does not change draft status")
+		case change.Current:
+			result = ignored(prID, "merge request marked as draft")
+		default:
+			result, err = s.openGitLabMR(ctx, prID, event)
+		}
+	case "merge":
+		result, err = s.mergePR(ctx, prID)
+	case "close":
+		result, err = s.deletePR(ctx, prID)
+	default:
+		result = ignored(prID, fmt.Sprintf("action %q is not handled", mr.Action))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordResult(ctx, models.ProviderGitLab, mr.Action, result)
+	return result, nil
+}
+
+// openGitLabMR - в событии есть только author_id автора, а username - у того, кто вызвал событие.
+// Если событие вызвал не автор, username автора запрашивается у API GitLab.
+func (s *integrationService) openGitLabMR(ctx context.Context, prID string, event *models.GitLabMergeRequestEvent) (*models.IntegrationEventResponse, error) {
+	author := event.User.Username
+	if authorID := event.ObjectAttributes.AuthorID; event.User.ID != authorID {
+		username, found, err := s.gitlab.Username(ctx, authorID)
+		if err != nil {
+			return nil, fmt.Errorf("resolve gitlab author %d: %w", authorID, err)
+		}
+		if !found {
+			return ignored(prID, fmt.Sprintf("gitlab author %d is not found", authorID)), nil
+		}
+		author = username
+	}
+	return s.openPR(ctx, models.ProviderGitLab, prID, event.ObjectAttributes.Title, author)
+}
+
+func (s *integrationService) openPR(ctx context.Context, provider, prID, title, login string) (*models.IntegrationEventResponse, error) {
+	if len(prID) > maxPRIDLength {
+		return ignored(prID, "pull request id is too long"), nil
+	}
+
+	authorID, err := s.db.GetCodeHostUserID(ctx, provider, normalizeLogin(login))
+	if errors.Is(err, store.ErrNotFound) {
+		return ignored(prID, fmt.Sprintf("%s login %q is not linked to a user", provider, login)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if runes := []rune(title); len(runes) > maxPRNameLength {
+		title = string(runes[:maxPRNameLength])
+	}
+
+	pr, err := s.prService.CreatePR(ctx, &models.CreatePRRequest{
+		PullRequestID:   prID,
+		PullRequestName: title,
+		AuthorID:        authorID,
+	})
+	if errors.Is(err, ErrPRExists) {
+		return ignored(prID, "pull request is already tracked"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.IntegrationEventResponse{Result: models.IntegrationResultCreated, PullRequestID: prID, PR: pr}, nil
+}
+
+func (s *integrationService) mergePR(ctx context.Context, prID string) (*models.IntegrationEventResponse, error) {
+	pr, err := s.prService.MergePR(ctx, prID)
+	if errors.Is(err, ErrPRNotFound) {
+		return ignored(prID, "pull request is not tracked"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.IntegrationEventResponse{Result: models.IntegrationResultMerged, PullRequestID: prID, PR: pr}, nil
+}
+
+// deletePR - статуса "закрыт" у PR нет, поэтому PR, закрытый без мержа, удаляется;
+// при повторном открытии он создаётся заново
+func (s *integrationService) deletePR(ctx context.Context, prID string) (*models.IntegrationEventResponse, error) {
+	pr, err := s.prService.DeletePR(ctx, prID)
+	if errors.Is(err, ErrPRNotFound) {
+		return ignored(prID, "pull request is not tracked"), nil
+	}
+	if errors.Is(err, ErrPRAlreadyMerged) {
+		return ignored(prID, "pull request is already merged"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.IntegrationEventResponse{Result: models.IntegrationResultDeleted, PullRequestID: prID, PR: pr}, nil
+}
+
+func (s *integrationService) recordResult(ctx context.Context, provider, action string, result *models.IntegrationEventResponse) {
+	metrics.IntegrationEvents.WithLabelValues(provider, result.Result).Inc()
+	if result.Result == models.IntegrationResultIgnored {
+		s.logger.InfoContext(ctx, "code host event ignored",
+			"provider", provider,
+			"action", action,
+			"pull_request_id", result.PullRequestID,
+			"reason", result.Reason,
+		)
+	}
+}
+
+func ignored(prID, reason string) *models.IntegrationEventResponse {
+	return &models.IntegrationEventResponse{Result: models.IntegrationResultIgnored, PullRequestID: prID, Reason: reason}
+}
+
+// normalizeLogin - логины GitHub и GitLab не зависят от регистра
+func normalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
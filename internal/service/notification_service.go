@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+	"antonvedaet/internship_task/internal/tracing"
+)
+
+type notificationService struct {
+	db     *store.DB
+	logger *slog.Logger
+}
+
+func NewNotificationService(db *store.DB, logger *slog.Logger) NotificationService {
+	return &notificationService{db: db, logger: logger}
+}
+
+// CreateChannel подключает incoming webhook команды или пользователя. Пустой kinds означает все виды уведомлений.
+func (s *notificationService) CreateChannel(ctx context.Context, req *models.CreateNotificationChannelRequest) (*models.NotificationChannel, error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.CreateChannel")
+	defer span.End()
+
+	if (req.TeamName == "") == (req.UserID == "") {
+		return nil, ErrInvalidChannelTarget
+	}
+
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+
+	format := req.Format
+	if format == "" {
+		format = models.FormatSlack
+	}
+	if !slices.Contains(models.NotificationFormats, format) {
+		return nil, ErrInvalidChannelFormat
+	}
+
+	kinds := []string{}
+	for _, kind := range req.Kinds {
+		if !slices.Contains(models.NotificationKinds, kind) {
+			return nil, ErrInvalidChannelKinds
+		}
+		if !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+
+	channelID, err := randomID("nc_", 8)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := &models.NotificationChannel{
+		ChannelID: channelID,
+		TeamName:  req.TeamName,
+		UserID:    req.UserID,
+		URL:       target.String(),
+		Format:    format,
+		Kinds:     kinds,
+	}
+	if err := s.db.CreateNotificationChannel(ctx, channel); err != nil {
+		if errors.Is(err, store.ErrReferenceViolation) {
+			if channel.TeamName != "" {
+				return nil, ErrTeamNotFound
+			}
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "notification channel created",
+		"channel_id", channel.ChannelID,
+		"team_name", channel.TeamName,
+		"user_id", channel.UserID,
+		"format", channel.Format,
+		"kinds", channel.Kinds,
+	)
+	return channel, nil
+}
+
+func (s *notificationService) ListChannels(ctx context.Context, query models.NotificationChannelQuery) ([]models.NotificationChannel, error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.ListChannels")
+	defer span.End()
+
+	return s.db.ListNotificationChannels(ctx, query)
+}
+
+func (s *notificationService) DeleteChannel(ctx context.Context, channelID string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.DeleteChannel")
+	defer span.End()
+
+	err := s.db.DeleteNotificationChannel(ctx, channelID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrChannelNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "notification channel deleted", "channel_id", channelID)
+	return nil
+}
+
+func (s *notificationService) ListDeliveries(ctx context.Context, query models.NotificationDeliveryQuery) ([]models.NotificationDelivery, string, error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.ListDeliveries")
+	defer span.End()
+
+	switch query.Status {
+	case "", "pending", "delivered", "failed":
+	default:
+		return nil, "", ErrInvalidDeliveryState
+	}
+
+	filter := models.NotificationDeliveryFilter{
+		ChannelID: query.ChannelID,
+		Status:    query.Status,
+		Limit:     normalizeLimit(query.Limit),
+	}
+
+	if query.Cursor != "" {
+		parts, err := decodeCursor(query.Cursor, 1)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.BeforeID, err = strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || filter.BeforeID <= 0 {
+			return nil, "", ErrInvalidCursor
+		}
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+
+	deliveries, err := s.db.ListNotificationDeliveries(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		nextCursor = encodeCursor(strconv.FormatInt(deliveries[limit-1].DeliveryID, 10))
+	}
+
+	return deliveries, nextCursor, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+	"antonvedaet/internship_task/internal/tracing"
+)
+
+type outboxService struct {
+	db     *store.DB
+	logger *slog.Logger
+}
+
+func NewOutboxService(db *store.DB, logger *slog.Logger) OutboxService {
+	return &outboxService{db: db, logger: logger}
+}
+
+func (s *outboxService) ListEvents(ctx context.Context, query models.OutboxEventQuery) ([]models.OutboxEvent, string, error) {
+	ctx, span := tracing.Start(ctx, "OutboxService.ListEvents")
+	defer span.End()
+
+	switch query.Status {
+	case "", "pending", "published", "dead":
+	default:
+		return nil, "", ErrInvalidEventStatus
+	}
+
+	filter := models.OutboxEventFilter{
+		Status: query.Status,
+		Limit:  normalizeLimit(query.Limit),
+	}
+
+	if query.Cursor != "" {
+		parts, err := decodeCursor(query.Cursor, 1)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.BeforeSeq, err = strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || filter.BeforeSeq <= 0 {
+			return nil, "", ErrInvalidCursor
+		}
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+
+	events, err := s.db.ListOutboxEvents(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(events) > limit {
+		events = events[:limit]
+		nextCursor = encodeCursor(strconv.FormatInt(events[limit-1].Seq, 10))
+	}
+
+	return events, nextCursor, nil
+}
+
+func (s *outboxService) RequeueEvent(ctx context.Context, eventID string) (*models.OutboxEvent, error) {
+	ctx, span := tracing.Start(ctx, "OutboxService.RequeueEvent")
+	defer span.End()
+
+	event, err := s.db.RequeueOutboxEvent(ctx, eventID)
+	if errors.Is(err, store.ErrNotFound) {
+		exists, err := s.db.OutboxEventExists(ctx, eventID)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrEventNotDead
+		}
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "outbox event requeued", "event_id", eventID, "event_type", event.EventType)
+	return event, nil
+}
@@ -1,22 +1,31 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"time"
 
+	"antonvedaet/internship_task/internal/metrics"
 	"antonvedaet/internship_task/internal/models"
 	"antonvedaet/internship_task/internal/store"
+	"antonvedaet/internship_task/internal/tracing"
 )
 
 type prService struct {
-	db *store.DB
+	db     *store.DB
+	logger *slog.Logger
 }
 
-func NewPRService(db *store.DB) PRService {
-	return &prService{db: db}
+func NewPRService(db *store.DB, logger *slog.Logger) PRService {
+	return &prService{db: db, logger: logger}
 }
 
-func (s *prService) CreatePR(prRequest *models.CreatePRRequest) (*models.PullRequest, error) {
-	exists, err := s.db.PRExists(prRequest.PullRequestID)
+func (s *prService) CreatePR(ctx context.Context, prRequest *models.CreatePRRequest) (*models.PullRequest, error) {
+	ctx, span := tracing.Start(ctx, "PRService.CreatePR")
+	defer span.End()
+
+	exists, err := s.db.PRExists(ctx, prRequest.PullRequestID)
 	if err != nil {
 		return nil, err
 	}
@@ -24,17 +33,37 @@ func (s *prService) CreatePR(prRequest *models.CreatePRRequest) (*models.PullReq
 		return nil, ErrPRExists
 	}
 
-	author, err := s.db.GetUser(prRequest.AuthorID)
+	author, err := s.db.GetUser(ctx, prRequest.AuthorID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, err
 	}
 
-	teamUsers, err := s.db.GetActiveTeamUsers(author.TeamName, prRequest.AuthorID)
+	teamUsers, err := s.db.GetActiveTeamUsers(ctx, author.TeamName, prRequest.AuthorID)
 	if err != nil {
 		return nil, err
 	}
 
-	reviewers := s.selectRandomReviewers(teamUsers, 2)
+	settings := &models.TeamSettings{RequiredReviewers: DefaultRequiredReviewers}
+	if author.TeamName != "" {
+		settings, err = s.db.GetTeamSettings(ctx, author.TeamName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reviewers := s.selectRandomReviewers(teamUsers, settings.RequiredReviewers)
+
+	if missing := settings.RequiredReviewers - len(reviewers); missing > 0 && settings.ReviewerFallback {
+		exclude := append([]string{author.UserID}, reviewers...)
+		extra, err := s.fallbackReviewers(ctx, author.TeamName, exclude, missing)
+		if err != nil {
+			return nil, err
+		}
+		reviewers = append(reviewers, extra...)
+	}
 
 	pr := &models.PullRequest{
 		PullRequestID:     prRequest.PullRequestID,
@@ -45,17 +74,37 @@ func (s *prService) CreatePR(prRequest *models.CreatePRRequest) (*models.PullReq
 		CreatedAt:         time.Now(),
 	}
 
-	if err := s.db.CreatePR(pr); err != nil {
+	if err := s.db.CreatePR(ctx, pr); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return nil, ErrPRExists
+		}
 		return nil, err
 	}
 
+	metrics.PRsCreated.Inc()
+	s.logger.InfoContext(ctx, "pull request created", "pull_request_id", pr.PullRequestID, "reviewers", reviewers)
+	if len(reviewers) < settings.RequiredReviewers {
+		metrics.PRsUnderstaffed.Inc()
+		s.logger.WarnContext(ctx, "pull request has fewer reviewers than required",
+			"pull_request_id", pr.PullRequestID,
+			"assigned", len(reviewers),
+			"required", settings.RequiredReviewers,
+		)
+	}
+
 	return pr, nil
 }
 
-func (s *prService) MergePR(prID string) (*models.PullRequest, error) {
-	pr, err := s.db.GetPR(prID)
+func (s *prService) MergePR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	ctx, span := tracing.Start(ctx, "PRService.MergePR")
+	defer span.End()
+
+	pr, err := s.db.GetPR(ctx, prID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrPRNotFound
+	}
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, err
 	}
 
 	if pr.Status == "MERGED" {
@@ -66,17 +115,53 @@ func (s *prService) MergePR(prID string) (*models.PullRequest, error) {
 	now := time.Now()
 	pr.MergedAt = &now
 
-	if err := s.db.UpdatePR(pr); err != nil {
+	changed, err := s.db.UpdatePR(ctx, pr)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrPRNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// параллельный мерж успел раньше: метрику, лог и событие записал он
+	if !changed {
+		return pr, nil
+	}
+
+	metrics.PRsMerged.Inc()
+	s.logger.InfoContext(ctx, "pull request merged", "pull_request_id", pr.PullRequestID)
+
+	return pr, nil
+}
+
+// DeletePR удаляет открытый PR; смерженный PR остаётся, и возвращается ErrPRAlreadyMerged
+func (s *prService) DeletePR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	ctx, span := tracing.Start(ctx, "PRService.DeletePR")
+	defer span.End()
+
+	pr, err := s.db.DeletePR(ctx, prID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return nil, ErrPRNotFound
+	case errors.Is(err, store.ErrStale):
+		return nil, ErrPRAlreadyMerged
+	case err != nil:
 		return nil, err
 	}
 
+	s.logger.InfoContext(ctx, "pull request deleted", "pull_request_id", pr.PullRequestID)
 	return pr, nil
 }
 
-func (s *prService) ReassignReviewer(prID, oldReviewerID string) (*models.PullRequest, string, error) {
-	pr, err := s.db.GetPR(prID)
+func (s *prService) ReassignReviewer(ctx context.Context, prID, oldReviewerID string) (*models.PullRequest, string, error) {
+	ctx, span := tracing.Start(ctx, "PRService.ReassignReviewer")
+	defer span.End()
+
+	pr, err := s.db.GetPR(ctx, prID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, "", ErrPRNotFound
+	}
 	if err != nil {
-		return nil, "", ErrNotFound
+		return nil, "", err
 	}
 
 	if pr.Status == "MERGED" {
@@ -87,12 +172,15 @@ func (s *prService) ReassignReviewer(prID, oldReviewerID string) (*models.PullRe
 		return nil, "", ErrReviewerNotAssigned
 	}
 
-	oldReviewer, err := s.db.GetUser(oldReviewerID)
+	oldReviewer, err := s.db.GetUser(ctx, oldReviewerID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, "", ErrUserNotFound
+	}
 	if err != nil {
-		return nil, "", ErrNotFound
+		return nil, "", err
 	}
 
-	teamUsers, err := s.db.GetActiveTeamUsers(oldReviewer.TeamName, oldReviewerID)
+	teamUsers, err := s.db.GetActiveTeamUsers(ctx, oldReviewer.TeamName, oldReviewerID)
 	if err != nil {
 		return nil, "", err
 	}
@@ -104,24 +192,76 @@ func (s *prService) ReassignReviewer(prID, oldReviewerID string) (*models.PullRe
 		}
 	}
 
-	if len(candidates) == 0 {
+	var newReviewerID string
+	if len(candidates) > 0 {
+		newReviewerID = candidates[randomInt(len(candidates))].UserID
+	} else if oldReviewer.TeamName != "" {
+		settings, err := s.db.GetTeamSettings(ctx, oldReviewer.TeamName)
+		if err != nil {
+			return nil, "", err
+		}
+		if settings.ReviewerFallback {
+			exclude := append([]string{pr.AuthorID}, pr.AssignedReviewers...)
+			fallback, err := s.fallbackReviewers(ctx, oldReviewer.TeamName, exclude, 1)
+			if err != nil {
+				return nil, "", err
+			}
+			if len(fallback) > 0 {
+				newReviewerID = fallback[0]
+			}
+		}
+	}
+
+	if newReviewerID == "" {
+		metrics.NoCandidate.Inc()
+		s.logger.WarnContext(ctx, "no replacement candidate", "pull_request_id", prID, "old_reviewer_id", oldReviewerID)
 		return nil, "", ErrNoAvailableReviewers
 	}
 
-	newReviewer := candidates[randomInt(len(candidates))]
+	pr, err = s.db.ReassignPR(ctx, prID, oldReviewerID, newReviewerID)
+	if errors.Is(err, store.ErrStale) {
+		return nil, "", ErrPRChanged
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	metrics.Reassignments.Inc()
+	s.logger.InfoContext(ctx, "reviewer reassigned", "pull_request_id", prID, "old_reviewer_id", oldReviewerID, "new_reviewer_id", newReviewerID)
+
+	return pr, newReviewerID, nil
+}
+
+// fallbackReviewers добирает до count ревьюверов, поднимаясь по родительским командам
+// teamName: на каждом уровне кандидаты ищутся во всём поддереве родителя.
+func (s *prService) fallbackReviewers(ctx context.Context, teamName string, exclude []string, count int) ([]string, error) {
+	ancestors, err := s.db.GetTeamAncestors(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
 
-	for i, reviewer := range pr.AssignedReviewers {
-		if reviewer == oldReviewerID {
-			pr.AssignedReviewers[i] = newReviewer.UserID
+	var reviewers []string
+	for _, ancestor := range ancestors {
+		if len(reviewers) >= count {
 			break
 		}
-	}
 
-	if err := s.db.UpdatePR(pr); err != nil {
-		return nil, "", err
+		descendants, err := s.db.GetTeamDescendants(ctx, ancestor)
+		if err != nil {
+			return nil, err
+		}
+
+		users, err := s.db.GetActiveUsersInTeams(ctx, append([]string{ancestor}, descendants...), exclude)
+		if err != nil {
+			return nil, err
+		}
+
+		picked := s.selectRandomReviewers(users, count-len(reviewers))
+		reviewers = append(reviewers, picked...)
+		exclude = append(exclude, picked...)
 	}
 
-	return pr, newReviewer.UserID, nil
+	return reviewers, nil
 }
 
 func (s *prService) selectRandomReviewers(users []models.User, max int) []string {
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"antonvedaet/internship_task/internal/metrics"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+func newTestPRService(t *testing.T) (PRService, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return NewPRService(store.Wrap(sqlDB, time.Second), slog.New(slog.NewTextHandler(io.Discard, nil))), mock
+}
+
+func TestMergePR(t *testing.T) {
+	prColumns := []string{"pull_request_id", "pull_request_name", "author_id", "status", "assigned_reviewers", "created_at", "merged_at"}
+	createdAt := time.Now().Add(-time.Hour)
+	firstMergedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name string
+		// lockedStatus - статус под блокировкой: MERGED, если параллельный мерж успел раньше
+		lockedStatus string
+		wantMerged   float64
+	}{
+		{"merged by this call", "OPEN", 1},
+		{"merged concurrently", "MERGED", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prs, mock := newTestPRService(t)
+
+			mock.ExpectQuery(`FROM pull_requests\s+WHERE pull_request_id = \$1`).
+				WithArgs("pr-1").
+				WillReturnRows(sqlmock.NewRows(prColumns).AddRow("pr-1", "Add search", "u1", "OPEN", pq.StringArray{"u2"}, createdAt, nil))
+			mock.ExpectBegin()
+			locked := sqlmock.NewRows(prColumns)
+			if tt.lockedStatus == "MERGED" {
+				locked.AddRow("pr-1", "Add search", "u1", "MERGED", pq.StringArray{"u2"}, createdAt, firstMergedAt)
+			} else {
+				locked.AddRow("pr-1", "Add search", "u1", "OPEN", pq.StringArray{"u2"}, createdAt, nil)
+			}
+			mock.ExpectQuery(`FROM pull_requests\s+WHERE pull_request_id = \$1\s+FOR UPDATE`).WithArgs("pr-1").WillReturnRows(locked)
+			if tt.lockedStatus == "OPEN" {
+				mock.ExpectExec(`SET status = \$1, merged_at = \$2`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO outbox_events").
+					WithArgs(sqlmock.AnyArg(), models.EventPRMerged, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectCommit()
+
+			before := testutil.ToFloat64(metrics.PRsMerged)
+			pr, err := prs.MergePR(context.Background(), "pr-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := testutil.ToFloat64(metrics.PRsMerged) - before; got != tt.wantMerged {
+				t.Errorf("merged counter += %v, want %v", got, tt.wantMerged)
+			}
+			if pr.Status != "MERGED" || pr.MergedAt == nil {
+				t.Fatalf("pr = %+v, want MERGED", pr)
+			}
+			// проигравший гонку возвращает время первого мержа
+			if tt.lockedStatus == "MERGED" && !pr.MergedAt.Equal(firstMergedAt) {
+				t.Errorf("merged_at = %v, want %v", pr.MergedAt, firstMergedAt)
+			}
+		})
+	}
+}
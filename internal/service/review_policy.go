@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+
+	"antonvedaet/internship_task/internal/metrics"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+// Политики обработки открытых ревью пользователя, который покидает команду
+const (
+	ReviewPolicyKeep     = "keep"
+	ReviewPolicyReassign = "reassign"
+	ReviewPolicyUnassign = "unassign"
+)
+
+func normalizeReviewPolicy(policy string) (string, error) {
+	switch policy {
+	case "":
+		return ReviewPolicyKeep, nil
+	case ReviewPolicyKeep, ReviewPolicyReassign, ReviewPolicyUnassign:
+		return policy, nil
+	default:
+		return "", ErrInvalidReviewPolicy
+	}
+}
+
+// applyReviewPolicy переводит user в команду teamName (пустая - исключение из команды)
+// и обрабатывает открытые PR, где он назначен ревьювером. При reassign замена ищется
+// среди активных участников текущей команды user, если кандидатов нет - ревьювер просто
+// снимается с PR. Замены и перевод сохраняются одной транзакцией.
+func applyReviewPolicy(ctx context.Context, db *store.DB, user *models.User, teamName, policy string) ([]models.ReassignedReview, error) {
+	planned := []models.ReassignedReview{}
+	if policy != ReviewPolicyKeep {
+		var err error
+		if planned, err = planReviewPolicy(ctx, db, user, policy); err != nil {
+			return nil, err
+		}
+	}
+
+	user.TeamName = teamName
+	reassigned, err := db.UpdateUserReassigning(ctx, user, planned)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, review := range reassigned {
+		if review.ReplacedBy != "" {
+			metrics.Reassignments.Inc()
+		}
+	}
+	return reassigned, nil
+}
+
+// planReviewPolicy выбирает замены по прочитанным PR; store перепроверяет их под блокировкой
+func planReviewPolicy(ctx context.Context, db *store.DB, user *models.User, policy string) ([]models.ReassignedReview, error) {
+	prs, err := db.GetPRsByReviewer(ctx, user.UserID, models.ReviewFilter{Status: "OPEN"})
+	if err != nil {
+		return nil, err
+	}
+
+	var teamUsers []models.User
+	if policy == ReviewPolicyReassign && user.TeamName != "" {
+		teamUsers, err = db.GetActiveTeamUsers(ctx, user.TeamName, user.UserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	planned := []models.ReassignedReview{}
+	for i := range prs {
+		pr := &prs[i]
+
+		var candidates []models.User
+		for _, candidate := range teamUsers {
+			if candidate.UserID != pr.AuthorID && !contains(pr.AssignedReviewers, candidate.UserID) {
+				candidates = append(candidates, candidate)
+			}
+		}
+
+		var replacedBy string
+		if len(candidates) > 0 {
+			replacedBy = candidates[randomInt(len(candidates))].UserID
+		}
+
+		planned = append(planned, models.ReassignedReview{
+			PullRequestID: pr.PullRequestID,
+			ReplacedBy:    replacedBy,
+		})
+	}
+
+	return planned, nil
+}
@@ -1,20 +1,92 @@
 package service
 
-import "antonvedaet/internship_task/internal/models"
+import (
+	"context"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/stream"
+)
 
 type TeamService interface {
-	CreateTeam(team *models.Team) error
-	GetTeam(teamName string) (*models.Team, error)
-	DeactivateTeamUsers(teamName string) (int, error)
+	CreateTeam(ctx context.Context, team *models.Team) error
+	GetTeam(ctx context.Context, teamName string, includeDescendants bool) (*models.Team, error)
+	ListTeams(ctx context.Context, query models.TeamListQuery) ([]models.TeamSummary, string, error)
+	UpdateTeam(ctx context.Context, req *models.UpdateTeamRequest) (*models.Team, error)
+	DeleteTeam(ctx context.Context, teamName string) (*models.DeleteTeamResponse, error)
+	DeactivateTeamUsers(ctx context.Context, teamName string, includeDescendants bool) (int, error)
+	AddMember(ctx context.Context, req *models.AddMemberRequest) (*models.User, error)
+	RemoveMember(ctx context.Context, req *models.RemoveMemberRequest) (*models.User, []models.ReassignedReview, error)
+	MoveMember(ctx context.Context, req *models.MoveMemberRequest) (*models.User, []models.ReassignedReview, error)
 }
 
 type UserService interface {
-	SetUserActive(userID string, isActive bool) (*models.User, error)
-	GetUserReviewPRs(userID string) ([]models.PullRequest, error)
+	GetUser(ctx context.Context, userID string) (*models.User, error)
+	ListUsers(ctx context.Context, query models.UserListQuery) ([]models.User, string, error)
+	SetUserActive(ctx context.Context, userID string, isActive bool) (*models.User, error)
+	GetUserReviewPRs(ctx context.Context, userID string, query models.ReviewQuery) ([]models.PullRequest, string, error)
 }
 
 type PRService interface {
-	CreatePR(prRequest *models.CreatePRRequest) (*models.PullRequest, error)
-	MergePR(prID string) (*models.PullRequest, error)
-	ReassignReviewer(prID, oldReviewerID string) (*models.PullRequest, string, error)
+	CreatePR(ctx context.Context, prRequest *models.CreatePRRequest) (*models.PullRequest, error)
+	MergePR(ctx context.Context, prID string) (*models.PullRequest, error)
+	DeletePR(ctx context.Context, prID string) (*models.PullRequest, error)
+	ReassignReviewer(ctx context.Context, prID, oldReviewerID string) (*models.PullRequest, string, error)
+}
+
+type StatsService interface {
+	GetStats(ctx context.Context, query models.StatsQuery) (*models.StatsResponse, error)
+}
+
+type TokenService interface {
+	CreateToken(ctx context.Context, req *models.CreateTokenRequest) (*models.CreateTokenResponse, error)
+	ListTokens(ctx context.Context) ([]models.APIToken, error)
+	RevokeToken(ctx context.Context, tokenID string) (*models.APIToken, error)
+	Authenticate(ctx context.Context, token string) (*models.Principal, error)
+	EnsureBootstrapToken(ctx context.Context, token string) error
+}
+
+type WebhookService interface {
+	CreateSubscription(ctx context.Context, req *models.CreateWebhookRequest) (*models.CreateWebhookResponse, error)
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, subscriptionID string) error
+	ListDeliveries(ctx context.Context, query models.WebhookDeliveryQuery) ([]models.WebhookDelivery, string, error)
+}
+
+// NotificationService ведёт каналы чат-уведомлений и журнал отправленных в них сообщений
+type NotificationService interface {
+	CreateChannel(ctx context.Context, req *models.CreateNotificationChannelRequest) (*models.NotificationChannel, error)
+	ListChannels(ctx context.Context, query models.NotificationChannelQuery) ([]models.NotificationChannel, error)
+	DeleteChannel(ctx context.Context, channelID string) error
+	ListDeliveries(ctx context.Context, query models.NotificationDeliveryQuery) ([]models.NotificationDelivery, string, error)
+}
+
+type OutboxService interface {
+	ListEvents(ctx context.Context, query models.OutboxEventQuery) ([]models.OutboxEvent, string, error)
+	RequeueEvent(ctx context.Context, eventID string) (*models.OutboxEvent, error)
+}
+
+// IntegrationService принимает события хостингов кода и ведёт связь логинов на них с пользователями
+type IntegrationService interface {
+	LinkAccount(ctx context.Context, req *models.LinkAccountRequest) (*models.CodeHostAccount, error)
+	ListAccounts(ctx context.Context, query models.CodeHostAccountQuery) ([]models.CodeHostAccount, error)
+	UnlinkAccount(ctx context.Context, provider, login string) error
+	VerifyGitHubSignature(body []byte, signature string) bool
+	HandleGitHubPullRequest(ctx context.Context, event *models.GitHubPullRequestEvent) (*models.IntegrationEventResponse, error)
+	VerifyGitLabToken(token string) bool
+	HandleGitLabMergeRequest(ctx context.Context, event *models.GitLabMergeRequestEvent) (*models.IntegrationEventResponse, error)
+}
+
+// StreamService - SSE-поток событий ревьюера: живые события из stream.Hub и догонка по журналу outbox
+type StreamService interface {
+	Subscribe(ctx context.Context, userID string) (*stream.Subscription, error)
+	Unsubscribe(sub *stream.Subscription)
+	LatestSeq(ctx context.Context) (int64, error)
+	Backlog(ctx context.Context, userID string, afterSeq int64) ([]models.OutboxEvent, error)
+	LateEvents(ctx context.Context, userID string, seq, afterSeq int64) ([]models.OutboxEvent, error)
+	SettledSeq(ctx context.Context, seq int64) (int64, error)
+}
+
+type HealthService interface {
+	Ready(ctx context.Context) *models.ReadinessResponse
+	SetShuttingDown()
 }
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+	"antonvedaet/internship_task/internal/tracing"
+	"context"
+)
+
+type statsService struct {
+	db *store.DB
+}
+
+func NewStatsService(db *store.DB) StatsService {
+	return &statsService{db: db}
+}
+
+func (s *statsService) GetStats(ctx context.Context, query models.StatsQuery) (*models.StatsResponse, error) {
+	ctx, span := tracing.Start(ctx, "StatsService.GetStats")
+	defer span.End()
+
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, ErrInvalidTimeRange
+	}
+
+	filter := models.StatsFilter{From: query.From, To: query.To}
+
+	if query.TeamName != "" {
+		exists, err := s.db.TeamExists(ctx, query.TeamName)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrTeamNotFound
+		}
+
+		filter.TeamNames = []string{query.TeamName}
+		if query.IncludeDescendants {
+			descendants, err := s.db.GetTeamDescendants(ctx, query.TeamName)
+			if err != nil {
+				return nil, err
+			}
+			filter.TeamNames = append(filter.TeamNames, descendants...)
+		}
+	}
+
+	users, err := s.db.GetUserStats(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	teams, err := s.db.GetTeamStats(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	avgTimeToMerge, err := s.db.GetAvgTimeToMerge(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StatsResponse{
+		From:                  query.From,
+		To:                    query.To,
+		Users:                 users,
+		Teams:                 teams,
+		AvgTimeToMergeSeconds: avgTimeToMerge,
+	}, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+	"antonvedaet/internship_task/internal/stream"
+	"antonvedaet/internship_task/internal/tracing"
+)
+
+// StreamBacklogPage - сколько событий журнала читается за один запрос при догонке
+const StreamBacklogPage = 100
+
+type streamService struct {
+	db  *store.DB
+	hub *stream.Hub
+}
+
+func NewStreamService(db *store.DB, hub *stream.Hub) StreamService {
+	return &streamService{db: db, hub: hub}
+}
+
+func (s *streamService) Subscribe(ctx context.Context, userID string) (*stream.Subscription, error) {
+	ctx, span := tracing.Start(ctx, "StreamService.Subscribe")
+	defer span.End()
+
+	_, err := s.db.GetUser(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.hub.Subscribe(userID), nil
+}
+
+func (s *streamService) Unsubscribe(sub *stream.Subscription) {
+	s.hub.Unsubscribe(sub)
+}
+
+func (s *streamService) LatestSeq(ctx context.Context) (int64, error) {
+	ctx, span := tracing.Start(ctx, "StreamService.LatestSeq")
+	defer span.End()
+
+	return s.db.GetLatestOutboxSeq(ctx)
+}
+
+// Backlog возвращает до StreamBacklogPage событий ревьюера после afterSeq
+func (s *streamService) Backlog(ctx context.Context, userID string, afterSeq int64) ([]models.OutboxEvent, error) {
+	ctx, span := tracing.Start(ctx, "StreamService.Backlog")
+	defer span.End()
+
+	return s.db.ListUserReviewEvents(ctx, userID, afterSeq, StreamBacklogPage)
+}
+
+// LateEvents возвращает до StreamBacklogPage событий ревьюера с seq между afterSeq и seq,
+// закоммиченных после события seq
+func (s *streamService) LateEvents(ctx context.Context, userID string, seq, afterSeq int64) ([]models.OutboxEvent, error) {
+	ctx, span := tracing.Start(ctx, "StreamService.LateEvents")
+	defer span.End()
+
+	return s.db.ListLateUserReviewEvents(ctx, userID, seq, afterSeq, StreamBacklogPage)
+}
+
+// SettledSeq возвращает seq, ниже которого события уже не придут из LateEvents для seq и следующих
+func (s *streamService) SettledSeq(ctx context.Context, seq int64) (int64, error) {
+	ctx, span := tracing.Start(ctx, "StreamService.SettledSeq")
+	defer span.End()
+
+	return s.db.GetSettledOutboxSeq(ctx, seq)
+}
@@ -1,26 +1,377 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+
 	"antonvedaet/internship_task/internal/models"
 	"antonvedaet/internship_task/internal/store"
+	"antonvedaet/internship_task/internal/tracing"
+)
+
+const (
+	DefaultRequiredReviewers = 2
+	MaxRequiredReviewers     = 5
 )
 
 type teamService struct {
-	db *store.DB
+	db     *store.DB
+	logger *slog.Logger
+}
+
+func NewTeamService(db *store.DB, logger *slog.Logger) TeamService {
+	return &teamService{db: db, logger: logger}
+}
+
+func (s *teamService) CreateTeam(ctx context.Context, team *models.Team) error {
+	ctx, span := tracing.Start(ctx, "TeamService.CreateTeam")
+	defer span.End()
+
+	if team.Settings == nil {
+		team.Settings = &models.TeamSettings{RequiredReviewers: DefaultRequiredReviewers}
+	}
+	if err := validateTeamSettings(team.Settings); err != nil {
+		return err
+	}
+
+	if team.ParentTeam != "" {
+		if err := s.validateParentTeam(ctx, team.TeamName, team.ParentTeam); err != nil {
+			return err
+		}
+	}
+
+	if team.Members == nil {
+		team.Members = []models.TeamMember{}
+	}
+
+	if err := s.db.CreateTeam(ctx, team); err != nil {
+		switch {
+		// занятое имя ловит уникальный ключ teams: проверка перед вставкой не защищает от параллельного создания
+		case errors.Is(err, store.ErrConflict):
+			return ErrTeamExists
+		case errors.Is(err, store.ErrReferenceViolation):
+			return ErrInvalidParentTeam
+		case errors.Is(err, store.ErrInOtherTeam):
+			// переводить пользователей между командами можно только через MoveMember
+			return ErrUserInOtherTeam
+		}
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "team created", "team_name", team.TeamName, "members", len(team.Members))
+	return nil
+}
+
+func (s *teamService) GetTeam(ctx context.Context, teamName string, includeDescendants bool) (*models.Team, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.GetTeam")
+	defer span.End()
+
+	team, err := s.db.GetTeam(ctx, teamName)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrTeamNotFound
+	}
+	if err != nil || !includeDescendants {
+		return team, err
+	}
+
+	team.Descendants, err = s.db.GetDescendantTeams(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+func (s *teamService) ListTeams(ctx context.Context, query models.TeamListQuery) ([]models.TeamSummary, string, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.ListTeams")
+	defer span.End()
+
+	filter := models.TeamFilter{Limit: normalizeLimit(query.Limit)}
+
+	if query.Cursor != "" {
+		parts, err := decodeCursor(query.Cursor, 1)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.AfterName = parts[0]
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+
+	teams, err := s.db.ListTeams(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(teams) > limit {
+		teams = teams[:limit]
+		nextCursor = encodeCursor(teams[limit-1].TeamName)
+	}
+
+	return teams, nextCursor, nil
+}
+
+func (s *teamService) UpdateTeam(ctx context.Context, req *models.UpdateTeamRequest) (*models.Team, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.UpdateTeam")
+	defer span.End()
+
+	if req.Settings != nil && req.Settings.RequiredReviewers != nil {
+		if err := validateTeamSettings(&models.TeamSettings{RequiredReviewers: *req.Settings.RequiredReviewers}); err != nil {
+			return nil, err
+		}
+	}
+
+	parentTeam, err := s.db.GetTeamParent(ctx, req.TeamName)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrTeamNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if req.ParentTeam != nil && *req.ParentTeam != parentTeam {
+		if *req.ParentTeam != "" {
+			if err := s.validateParentTeam(ctx, req.TeamName, *req.ParentTeam); err != nil {
+				return nil, err
+			}
+		}
+		parentTeam = *req.ParentTeam
+	}
+
+	// занятое новое имя ловит уникальный ключ teams, как в CreateTeam
+	newTeamName := req.TeamName
+	if req.NewTeamName != "" {
+		newTeamName = req.NewTeamName
+	}
+
+	if _, err := s.db.UpdateTeam(ctx, req.TeamName, newTeamName, parentTeam, req.Settings); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return nil, ErrTeamNotFound
+		case errors.Is(err, store.ErrConflict):
+			return nil, ErrTeamExists
+		case errors.Is(err, store.ErrReferenceViolation):
+			return nil, ErrInvalidParentTeam
+		}
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "team updated", "team_name", req.TeamName, "new_team_name", newTeamName)
+
+	team, err := s.db.GetTeam(ctx, newTeamName)
+	if errors.Is(err, store.ErrNotFound) {
+		// команду успели удалить или переименовать после обновления
+		return nil, ErrTeamNotFound
+	}
+	return team, err
+}
+
+func (s *teamService) DeleteTeam(ctx context.Context, teamName string) (*models.DeleteTeamResponse, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.DeleteTeam")
+	defer span.End()
+
+	deleted, detached, err := s.db.DeleteTeam(ctx, teamName)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return nil, ErrTeamNotFound
+	case errors.Is(err, store.ErrHasChildren):
+		return nil, ErrTeamHasChildren
+	case errors.Is(err, store.ErrHasOpenPRs):
+		return nil, ErrTeamHasOpenPRs
+	case err != nil:
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "team deleted", "team_name", teamName, "deleted_users", deleted, "detached_users", detached)
+
+	return &models.DeleteTeamResponse{
+		TeamName:      teamName,
+		DeletedUsers:  deleted,
+		DetachedUsers: detached,
+	}, nil
+}
+
+// validateParentTeam проверяет, что parentTeam существует и не лежит в поддереве teamName
+func (s *teamService) validateParentTeam(ctx context.Context, teamName, parentTeam string) error {
+	if parentTeam == teamName {
+		return ErrInvalidParentTeam
+	}
+
+	exists, err := s.db.TeamExists(ctx, parentTeam)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrInvalidParentTeam
+	}
+
+	descendants, err := s.db.GetTeamDescendants(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	if contains(descendants, parentTeam) {
+		return ErrInvalidParentTeam
+	}
+
+	return nil
+}
+
+func validateTeamSettings(settings *models.TeamSettings) error {
+	if settings.RequiredReviewers < 0 || settings.RequiredReviewers > MaxRequiredReviewers {
+		return ErrInvalidTeamSettings
+	}
+	return nil
 }
 
-func NewTeamService(db *store.DB) TeamService {
-	return &teamService{db: db}
+func (s *teamService) DeactivateTeamUsers(ctx context.Context, teamName string, includeDescendants bool) (int, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.DeactivateTeamUsers")
+	defer span.End()
+
+	exists, err := s.db.TeamExists(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrTeamNotFound
+	}
+
+	teamNames := []string{teamName}
+	if includeDescendants {
+		descendants, err := s.db.GetTeamDescendants(ctx, teamName)
+		if err != nil {
+			return 0, err
+		}
+		teamNames = append(teamNames, descendants...)
+	}
+
+	count, err := s.db.DeactivateTeamUsers(ctx, teamNames)
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.InfoContext(ctx, "team users deactivated", "teams", teamNames, "deactivated_count", count)
+	return count, nil
 }
 
-func (s *teamService) CreateTeam(team *models.Team) error {
-	return s.db.CreateTeam(team)
+func (s *teamService) AddMember(ctx context.Context, req *models.AddMemberRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.AddMember")
+	defer span.End()
+
+	exists, err := s.db.TeamExists(ctx, req.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrTeamNotFound
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	user, err := s.db.GetUser(ctx, req.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		user = &models.User{
+			UserID:   req.UserID,
+			Username: req.Username,
+			TeamName: req.TeamName,
+			IsActive: isActive,
+		}
+		if err := s.db.CreateUser(ctx, user); err != nil {
+			return nil, err
+		}
+		s.logger.InfoContext(ctx, "team member added", "team_name", req.TeamName, "user_id", user.UserID)
+		return user, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch user.TeamName {
+	case req.TeamName:
+		return nil, ErrUserInTeam
+	case "":
+	default:
+		return nil, ErrUserInOtherTeam
+	}
+
+	user.Username = req.Username
+	user.TeamName = req.TeamName
+	user.IsActive = isActive
+	if err := s.db.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "team member added", "team_name", req.TeamName, "user_id", user.UserID)
+	return user, nil
 }
 
-func (s *teamService) GetTeam(teamName string) (*models.Team, error) {
-	return s.db.GetTeam(teamName)
+func (s *teamService) RemoveMember(ctx context.Context, req *models.RemoveMemberRequest) (*models.User, []models.ReassignedReview, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.RemoveMember")
+	defer span.End()
+
+	policy, err := normalizeReviewPolicy(req.ReviewPolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.db.GetUser(ctx, req.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.TeamName != req.TeamName {
+		return nil, nil, ErrUserNotInTeam
+	}
+
+	reassigned, err := applyReviewPolicy(ctx, s.db, user, "", policy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.logger.InfoContext(ctx, "team member removed", "team_name", req.TeamName, "user_id", user.UserID, "review_policy", policy, "reassigned_reviews", len(reassigned))
+	return user, reassigned, nil
 }
 
-func (s *teamService) DeactivateTeamUsers(teamName string) (int, error) {
-	return s.db.DeactivateTeamUsers(teamName)
+func (s *teamService) MoveMember(ctx context.Context, req *models.MoveMemberRequest) (*models.User, []models.ReassignedReview, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.MoveMember")
+	defer span.End()
+
+	policy, err := normalizeReviewPolicy(req.ReviewPolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exists, err := s.db.TeamExists(ctx, req.TeamName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, ErrTeamNotFound
+	}
+
+	user, err := s.db.GetUser(ctx, req.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.TeamName == req.TeamName {
+		return nil, nil, ErrUserInTeam
+	}
+
+	// замена ищется в старой команде, переносит пользователя сама applyReviewPolicy
+	reassigned, err := applyReviewPolicy(ctx, s.db, user, req.TeamName, policy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.logger.InfoContext(ctx, "team member moved", "team_name", req.TeamName, "user_id", user.UserID, "review_policy", policy, "reassigned_reviews", len(reassigned))
+	return user, reassigned, nil
 }
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+func newTestTeamService(t *testing.T) (TeamService, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return NewTeamService(store.Wrap(sqlDB, time.Second), slog.New(slog.NewTextHandler(io.Discard, nil))), mock
+}
+
+// имя, занятое параллельным запросом после проверок, ловит уникальный ключ teams
+func TestCreateTeamExists(t *testing.T) {
+	teams, mock := newTestTeamService(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO teams").WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	err := teams.CreateTeam(context.Background(), &models.Team{TeamName: "backend"})
+	if !errors.Is(err, ErrTeamExists) {
+		t.Fatalf("err = %v, want ErrTeamExists", err)
+	}
+}
+
+// участник другой команды не переводится в новую: вставка не обновляет его строку, и команда не создаётся
+func TestCreateTeamMemberInOtherTeam(t *testing.T) {
+	teams, mock := newTestTeamService(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO teams").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO users").
+		WithArgs("u1", "Alice", "backend", true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO users[\s\S]+WHERE users.team_name IS NULL`).
+		WithArgs("u2", "Bob", "backend", true).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := teams.CreateTeam(context.Background(), &models.Team{
+		TeamName: "backend",
+		Members: []models.TeamMember{
+			{UserID: "u1", Username: "Alice", IsActive: true},
+			{UserID: "u2", Username: "Bob", IsActive: true},
+		},
+	})
+	if !errors.Is(err, ErrUserInOtherTeam) {
+		t.Fatalf("err = %v, want ErrUserInOtherTeam", err)
+	}
+}
+
+func TestUpdateTeamRenameExists(t *testing.T) {
+	teams, mock := newTestTeamService(t)
+
+	mock.ExpectQuery("SELECT COALESCE\\(parent_team, ''\\)").
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"parent_team"}).AddRow(""))
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE teams").WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	_, err := teams.UpdateTeam(context.Background(), &models.UpdateTeamRequest{TeamName: "backend", NewTeamName: "platform"})
+	if !errors.Is(err, ErrTeamExists) {
+		t.Fatalf("err = %v, want ErrTeamExists", err)
+	}
+}
+
+func TestUpdateTeamValidatesPartialSettings(t *testing.T) {
+	teams, _ := newTestTeamService(t)
+
+	tooMany := MaxRequiredReviewers + 1
+	_, err := teams.UpdateTeam(context.Background(), &models.UpdateTeamRequest{
+		TeamName: "backend",
+		Settings: &models.TeamSettingsUpdate{RequiredReviewers: &tooMany},
+	})
+	if !errors.Is(err, ErrInvalidTeamSettings) {
+		t.Fatalf("err = %v, want ErrInvalidTeamSettings", err)
+	}
+}
+
+// команду удалили между обновлением и чтением результата
+func TestUpdateTeamDeletedAfterUpdate(t *testing.T) {
+	teams, mock := newTestTeamService(t)
+
+	mock.ExpectQuery("SELECT COALESCE\\(parent_team, ''\\)").
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"parent_team"}).AddRow(""))
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE teams").
+		WillReturnRows(sqlmock.NewRows([]string{"required_reviewers", "reviewer_fallback"}).AddRow(2, false))
+	mock.ExpectExec("INSERT INTO outbox_events").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT COALESCE\\(parent_team, ''\\), required_reviewers").
+		WithArgs("platform").
+		WillReturnRows(sqlmock.NewRows([]string{"parent_team", "required_reviewers", "reviewer_fallback"}))
+
+	_, err := teams.UpdateTeam(context.Background(), &models.UpdateTeamRequest{TeamName: "backend", NewTeamName: "platform"})
+	if !errors.Is(err, ErrTeamNotFound) {
+		t.Fatalf("err = %v, want ErrTeamNotFound", err)
+	}
+}
+
+func TestGetTeamWithDescendants(t *testing.T) {
+	teams, mock := newTestTeamService(t)
+
+	mock.ExpectQuery("FROM teams\\s+WHERE team_name = \\$1").
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"parent_team", "required_reviewers", "reviewer_fallback"}).AddRow("", 2, false))
+	mock.ExpectQuery("FROM users\\s+WHERE team_name = \\$1").
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "is_active"}).AddRow("u1", "Alice", true))
+	// всё поддерево - одним запросом, команда без участников приходит строкой с NULL
+	mock.ExpectQuery("WITH RECURSIVE subtree").
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"team_name", "parent_team", "required_reviewers", "reviewer_fallback", "user_id", "username", "is_active"}).
+			AddRow("backend-api", "backend", 1, true, "u2", "Bob", true).
+			AddRow("backend-api", "backend", 1, true, "u3", "Carol", false).
+			AddRow("backend-db", "backend", 2, false, nil, nil, nil).
+			AddRow("backend-api-v2", "backend-api", 2, false, "u4", "Dave", true))
+
+	team, err := teams.GetTeam(context.Background(), "backend", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name    string
+		parent  string
+		members int
+	}{
+		{"backend-api", "backend", 2},
+		{"backend-db", "backend", 0},
+		{"backend-api-v2", "backend-api", 1},
+	}
+	if len(team.Descendants) != len(want) {
+		t.Fatalf("descendants = %+v, want %d teams", team.Descendants, len(want))
+	}
+	for i, w := range want {
+		got := team.Descendants[i]
+		if got.TeamName != w.name || got.ParentTeam != w.parent || len(got.Members) != w.members || got.Members == nil {
+			t.Errorf("descendant %d = %+v, want %s under %s with %d members", i, got, w.name, w.parent, w.members)
+		}
+	}
+	if s := team.Descendants[0].Settings; s.RequiredReviewers != 1 || !s.ReviewerFallback {
+		t.Errorf("backend-api settings = %+v, want required_reviewers 1 and reviewer_fallback", s)
+	}
+}
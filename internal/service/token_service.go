@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"antonvedaet/internship_task/internal/auth"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+	"antonvedaet/internship_task/internal/tracing"
+)
+
+const bootstrapTokenName = "bootstrap"
+
+type tokenService struct {
+	db     *store.DB
+	logger *slog.Logger
+}
+
+func NewTokenService(db *store.DB, logger *slog.Logger) TokenService {
+	return &tokenService{db: db, logger: logger}
+}
+
+func (s *tokenService) CreateToken(ctx context.Context, req *models.CreateTokenRequest) (*models.CreateTokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.CreateToken")
+	defer span.End()
+
+	if !auth.ValidRole(req.Role) {
+		return nil, ErrInvalidTokenRole
+	}
+	if (req.Role == auth.RoleUser) != (req.UserID != "") {
+		return nil, ErrInvalidTokenUser
+	}
+
+	secret, hash, err := auth.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	tokenID, err := auth.NewTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	token := &models.APIToken{
+		TokenID: tokenID,
+		Name:    req.Name,
+		Role:    req.Role,
+		UserID:  req.UserID,
+	}
+	if err := s.db.CreateAPIToken(ctx, token, hash); err != nil {
+		if errors.Is(err, store.ErrReferenceViolation) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "api token created", "token_id", token.TokenID, "role", token.Role, "user_id", token.UserID)
+	return &models.CreateTokenResponse{Token: token, Secret: secret}, nil
+}
+
+func (s *tokenService) ListTokens(ctx context.Context) ([]models.APIToken, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.ListTokens")
+	defer span.End()
+
+	return s.db.ListAPITokens(ctx)
+}
+
+func (s *tokenService) RevokeToken(ctx context.Context, tokenID string) (*models.APIToken, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.RevokeToken")
+	defer span.End()
+
+	token, err := s.db.RevokeAPIToken(ctx, tokenID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "api token revoked", "token_id", tokenID)
+	return token, nil
+}
+
+func (s *tokenService) Authenticate(ctx context.Context, token string) (*models.Principal, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.Authenticate")
+	defer span.End()
+
+	if token == "" {
+		return nil, ErrUnauthorized
+	}
+
+	stored, err := s.db.GetActiveAPIToken(ctx, auth.HashToken(token))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Principal{
+		TokenID: stored.TokenID,
+		Name:    stored.Name,
+		Role:    stored.Role,
+		UserID:  stored.UserID,
+	}, nil
+}
+
+// EnsureBootstrapToken регистрирует админский токен из конфигурации,
+// чтобы после первого запуска было чем вызвать /tokens/create
+func (s *tokenService) EnsureBootstrapToken(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "TokenService.EnsureBootstrapToken")
+	defer span.End()
+
+	tokenID, err := auth.NewTokenID()
+	if err != nil {
+		return err
+	}
+
+	created, err := s.db.EnsureAPIToken(ctx, &models.APIToken{
+		TokenID: tokenID,
+		Name:    bootstrapTokenName,
+		Role:    auth.RoleAdmin,
+	}, auth.HashToken(token))
+	if err != nil {
+		return err
+	}
+
+	if created {
+		s.logger.InfoContext(ctx, "bootstrap admin token registered", "token_id", tokenID)
+	}
+	return nil
+}
@@ -1,32 +1,134 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
 	"antonvedaet/internship_task/internal/models"
 	"antonvedaet/internship_task/internal/store"
+	"antonvedaet/internship_task/internal/tracing"
 )
 
 type userService struct {
-	db *store.DB
+	db     *store.DB
+	logger *slog.Logger
 }
 
-func NewUserService(db *store.DB) UserService {
-	return &userService{db: db}
+func NewUserService(db *store.DB, logger *slog.Logger) UserService {
+	return &userService{db: db, logger: logger}
 }
 
-func (s *userService) SetUserActive(userID string, isActive bool) (*models.User, error) {
-	user, err := s.db.GetUser(userID)
+func (s *userService) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUser")
+	defer span.End()
+
+	user, err := s.db.GetUser(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *userService) ListUsers(ctx context.Context, query models.UserListQuery) ([]models.User, string, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ListUsers")
+	defer span.End()
+
+	filter := models.UserFilter{
+		TeamName: query.TeamName,
+		IsActive: query.IsActive,
+		Username: query.Username,
+		Limit:    normalizeLimit(query.Limit),
+	}
+
+	if query.Cursor != "" {
+		parts, err := decodeCursor(query.Cursor, 1)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.AfterID = parts[0]
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+
+	users, err := s.db.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(users) > limit {
+		users = users[:limit]
+		nextCursor = encodeCursor(users[limit-1].UserID)
+	}
+
+	return users, nextCursor, nil
+}
+
+func (s *userService) SetUserActive(ctx context.Context, userID string, isActive bool) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.SetUserActive")
+	defer span.End()
+
+	user, err := s.db.GetUser(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	user.IsActive = isActive
-	if err := s.db.UpdateUser(user); err != nil {
+	if err := s.db.UpdateUser(ctx, user); err != nil {
 		return nil, err
 	}
 
+	s.logger.InfoContext(ctx, "user activity changed", "user_id", userID, "is_active", isActive)
+
 	return user, nil
 }
 
-func (s *userService) GetUserReviewPRs(userID string) ([]models.PullRequest, error) {
-	return s.db.GetPRsByReviewer(userID)
+func (s *userService) GetUserReviewPRs(ctx context.Context, userID string, query models.ReviewQuery) ([]models.PullRequest, string, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserReviewPRs")
+	defer span.End()
+
+	filter := models.ReviewFilter{
+		Status: query.Status,
+		Limit:  normalizeLimit(query.Limit),
+	}
+
+	if query.Cursor != "" {
+		parts, err := decodeCursor(query.Cursor, 2)
+		if err != nil {
+			return nil, "", err
+		}
+		createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		filter.AfterCreatedAt = &createdAt
+		filter.AfterID = parts[1]
+	}
+
+	// запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+
+	prs, err := s.db.GetPRsByReviewer(ctx, userID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(prs) > limit {
+		prs = prs[:limit]
+		last := prs[limit-1]
+		nextCursor = encodeCursor(last.CreatedAt.Format(time.RFC3339Nano), last.PullRequestID)
+	}
+
+	return prs, nextCursor, nil
 }
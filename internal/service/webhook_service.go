@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+	"antonvedaet/internship_task/internal/tracing"
+)
+
+const minWebhookSecretLength = 16
+
+type webhookService struct {
+	db     *store.DB
+	logger *slog.Logger
+}
+
+func NewWebhookService(db *store.DB, logger *slog.Logger) WebhookService {
+	return &webhookService{db: db, logger: logger}
+}
+
+func (s *webhookService) CreateSubscription(ctx context.Context, req *models.CreateWebhookRequest) (*models.CreateWebhookResponse, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.CreateSubscription")
+	defer span.End()
+
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+
+	events := []string{}
+	for _, event := range req.Events {
+		if !slices.Contains(models.EventTypes, event) {
+			return nil, ErrInvalidWebhookEvent
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = randomID("whsec_", 24); err != nil {
+			return nil, err
+		}
+	}
+	if len(secret) < minWebhookSecretLength {
+		return nil, ErrInvalidWebhookSecret
+	}
+
+	subscriptionID, err := randomID("wh_", 8)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &models.WebhookSubscription{
+		SubscriptionID: subscriptionID,
+		URL:            target.String(),
+		Events:         events,
+	}
+	if err := s.db.CreateWebhookSubscription(ctx, sub, secret); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "webhook subscription created", "subscription_id", sub.SubscriptionID, "url", sub.URL, "events", sub.Events)
+	return &models.CreateWebhookResponse{Subscription: sub, Secret: secret}, nil
+}
+
+func (s *webhookService) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListSubscriptions")
+	defer span.End()
+
+	return s.db.ListWebhookSubscriptions(ctx)
+}
+
+func (s *webhookService) DeleteSubscription(ctx context.Context, subscriptionID string) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeleteSubscription")
+	defer span.End()
+
+	err := s.db.DeleteWebhookSubscription(ctx, subscriptionID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrWebhookNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "webhook subscription deleted", "subscription_id", subscriptionID)
+	return nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, query models.WebhookDeliveryQuery) ([]models.WebhookDelivery, string, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListDeliveries")
+	defer span.End()
+
+	switch query.Status {
+	case "", "pending", "delivered", "failed":
+	default:
+		return nil, "", ErrInvalidDeliveryState
+	}
+
+	filter := models.WebhookDeliveryFilter{
+		SubscriptionID: query.SubscriptionID,
+		Status:         query.Status,
+		Limit:          normalizeLimit(query.Limit),
+	}
+
+	if query.Cursor != "" {
+		parts, err := decodeCursor(query.Cursor, 1)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.BeforeID, err = strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || filter.BeforeID <= 0 {
+			return nil, "", ErrInvalidCursor
+		}
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+
+	deliveries, err := s.db.ListWebhookDeliveries(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		nextCursor = encodeCursor(strconv.FormatInt(deliveries[limit-1].DeliveryID, 10))
+	}
+
+	return deliveries, nextCursor, nil
+}
+
+func randomID(prefix string, size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
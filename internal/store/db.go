@@ -1,42 +1,141 @@
 package store
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
-	"os"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
 
 	_ "github.com/lib/pq"
+
+	"antonvedaet/internship_task/internal/config"
 )
 
+// SchemaVersion - номер последней миграции из migrations/, с которой совместим код
+const SchemaVersion = 15
+
 type DB struct {
 	*sql.DB
+	queryTimeout time.Duration
 }
 
-func New() (*DB, error) {
-	db, err := sql.Open("postgres", getConnString())
+func New(cfg config.DatabaseConfig) (*DB, error) {
+	db, err := sql.Open("postgres", connString(cfg))
 	if err != nil {
 		return nil, err
 	}
-	if err := db.Ping(); err != nil {
-		return nil, err
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping %s:%d/%s: %w", cfg.Host, cfg.Port, cfg.Name, err)
+	}
+	return Wrap(db, cfg.QueryTimeout), nil
+}
+
+// Wrap оборачивает уже открытое соединение: так тесты подставляют sqlmock вместо Postgres
+func Wrap(db *sql.DB, queryTimeout time.Duration) *DB {
+	return &DB{DB: db, queryTimeout: queryTimeout}
+}
+
+// startQuery открывает спан операции и ограничивает её время queryTimeout.
+// Отмена запроса клиентом тоже прерывает запрос к БД, так как ctx наследуется от http.Request.
+func (db *DB) startQuery(ctx context.Context, operation string) (context.Context, func()) {
+	ctx, span := startSpan(ctx, operation)
+	ctx, cancel := context.WithTimeout(ctx, db.queryTimeout)
+	return ctx, func() {
+		cancel()
+		span.End()
 	}
-	return &DB{db}, nil
 }
 
-func getEnv(key string) (string, error) {
-	if value := os.Getenv(key); value != "" {
-		return value, nil
+func (db *DB) GetSchemaVersion(ctx context.Context) (int, error) {
+	ctx, done := db.startQuery(ctx, "GetSchemaVersion")
+	defer done()
+
+	var version int
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+func connString(cfg config.DatabaseConfig) string {
+	query := url.Values{}
+	query.Set("sslmode", cfg.SSLMode)
+	query.Set("connect_timeout", strconv.Itoa(max(1, int(cfg.ConnectTimeout.Seconds()))))
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     "/" + cfg.Name,
+		RawQuery: query.Encode(),
 	}
-	return "", fmt.Errorf("key %s not found", key)
+	return dsn.String()
 }
 
-func getConnString() string {
-	host, _ := getEnv("DB_HOST")
-	port, _ := getEnv("DB_PORT")
-	user, _ := getEnv("DB_USER")
-	password, _ := getEnv("DB_PASSWORD")
-	dbname, _ := getEnv("DB_NAME")
+// TryLock берёт блокировку key на ttl, если она свободна или истекла, иначе возвращает ErrLocked.
+// Запись идёт в короткой транзакции под pg_try_advisory_xact_lock, поэтому соединение не держится,
+// пока вызывающий ходит в сеть. Вызывающий должен уложиться в ttl: после него блокировку возьмут другие.
+func (db *DB) TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), err error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	holder := hex.EncodeToString(buf)
+
+	queryCtx, done := db.startQuery(ctx, "TryLock")
+	defer done()
+
+	tx, err := db.BeginTx(queryCtx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// две реплики не ждут строку друг друга: вторая сразу получает ErrLocked
+	var locked bool
+	if err := tx.QueryRowContext(queryCtx, "SELECT pg_try_advisory_xact_lock(hashtext($1))", key).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, ErrLocked
+	}
+
+	result, err := tx.ExecContext(queryCtx, `
+        INSERT INTO locks (lock_key, holder, expires_at)
+        VALUES ($1, $2, CURRENT_TIMESTAMP + $3::float8 * INTERVAL '1 millisecond')
+        ON CONFLICT (lock_key) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+        WHERE locks.expires_at <= CURRENT_TIMESTAMP
+    `, key, holder, ttl.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrLocked
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
+	return func() {
+		// неснятая блокировка истечёт через ttl
+		ctx, done := db.startQuery(context.WithoutCancel(ctx), "Unlock")
+		defer done()
+		db.ExecContext(ctx, "DELETE FROM locks WHERE lock_key = $1 AND holder = $2", key, holder)
+	}, nil
 }
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound           = errors.New("record not found")
+	ErrConflict           = errors.New("record already exists")
+	ErrReferenceViolation = errors.New("referenced record does not exist")
+	// ErrStale - запись изменилась после того, как сервис её прочитал, и операция неприменима
+	ErrStale = errors.New("record changed concurrently")
+	// ErrHasChildren - у команды есть дочерние команды
+	ErrHasChildren = errors.New("team has child teams")
+	// ErrHasOpenPRs - участники команды - авторы или ревьюеры открытых PR
+	ErrHasOpenPRs = errors.New("team members have open pull requests")
+	// ErrInOtherTeam - пользователь уже состоит в другой команде
+	ErrInOtherTeam = errors.New("user belongs to another team")
+	// ErrLocked - блокировку держит другой процесс
+	ErrLocked = errors.New("lock is held by another process")
+)
+
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgForeignKeyViolation pq.ErrorCode = "23503"
+	pgUniqueViolation     pq.ErrorCode = "23505"
+)
+
+// translateError переводит ошибки драйвера в ошибки пакета store, чтобы сервисы
+// не зависели от database/sql и lib/pq. Исходная ошибка остаётся в цепочке.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pgUniqueViolation:
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		case pgForeignKeyViolation:
+			return fmt.Errorf("%w: %w", ErrReferenceViolation, err)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"antonvedaet/internship_task/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// UpsertCodeHostAccount связывает логин с пользователем; уже связанный логин переходит к новому пользователю
+func (db *DB) UpsertCodeHostAccount(ctx context.Context, account *models.CodeHostAccount) error {
+	ctx, done := db.startQuery(ctx, "UpsertCodeHostAccount")
+	defer done()
+
+	err := db.QueryRowContext(ctx, `
+        INSERT INTO code_host_accounts (provider, login, user_id)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (provider, login) DO UPDATE
+        SET user_id = EXCLUDED.user_id, created_at = CURRENT_TIMESTAMP
+        RETURNING created_at
+    `, account.Provider, account.Login, account.UserID).Scan(&account.CreatedAt)
+	return translateError(err)
+}
+
+func (db *DB) GetCodeHostUserID(ctx context.Context, provider, login string) (string, error) {
+	ctx, done := db.startQuery(ctx, "GetCodeHostUserID")
+	defer done()
+
+	var userID string
+	err := db.QueryRowContext(ctx, `
+        SELECT user_id FROM code_host_accounts WHERE provider = $1 AND login = $2
+    `, provider, login).Scan(&userID)
+	if err != nil {
+		return "", translateError(err)
+	}
+	return userID, nil
+}
+
+// GetCodeHostLogins возвращает логины пользователей на хостинге; если логинов несколько, берётся
+// последний связанный. Пользователи без логина в результат не попадают.
+func (db *DB) GetCodeHostLogins(ctx context.Context, provider string, userIDs []string) (map[string]string, error) {
+	ctx, done := db.startQuery(ctx, "GetCodeHostLogins")
+	defer done()
+
+	rows, err := db.QueryContext(ctx, `
+        SELECT DISTINCT ON (user_id) user_id, login
+        FROM code_host_accounts
+        WHERE provider = $1 AND user_id = ANY($2)
+        ORDER BY user_id, created_at DESC
+    `, provider, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logins := make(map[string]string, len(userIDs))
+	for rows.Next() {
+		var userID, login string
+		if err := rows.Scan(&userID, &login); err != nil {
+			return nil, err
+		}
+		logins[userID] = login
+	}
+
+	return logins, rows.Err()
+}
+
+func (db *DB) ListCodeHostAccounts(ctx context.Context, filter models.CodeHostAccountQuery) ([]models.CodeHostAccount, error) {
+	ctx, done := db.startQuery(ctx, "ListCodeHostAccounts")
+	defer done()
+
+	query := `
+        SELECT provider, login, user_id, created_at
+        FROM code_host_accounts
+        WHERE true
+    `
+	var args []interface{}
+
+	if filter.Provider != "" {
+		args = append(args, filter.Provider)
+		query += fmt.Sprintf(" AND provider = $%d", len(args))
+	}
+
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		query += fmt.Sprintf(" AND user_id = $%d", len(args))
+	}
+
+	query += " ORDER BY provider, login"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []models.CodeHostAccount{}
+	for rows.Next() {
+		var account models.CodeHostAccount
+		if err := rows.Scan(&account.Provider, &account.Login, &account.UserID, &account.CreatedAt); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+func (db *DB) DeleteCodeHostAccount(ctx context.Context, provider, login string) error {
+	ctx, done := db.startQuery(ctx, "DeleteCodeHostAccount")
+	defer done()
+
+	result, err := db.ExecContext(ctx, `
+        DELETE FROM code_host_accounts WHERE provider = $1 AND login = $2
+    `, provider, login)
+	if err != nil {
+		return translateError(err)
+	}
+
+	count, _ := result.RowsAffected()
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
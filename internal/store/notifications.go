@@ -0,0 +1,351 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+func (db *DB) CreateNotificationChannel(ctx context.Context, channel *models.NotificationChannel) error {
+	ctx, done := db.startQuery(ctx, "CreateNotificationChannel")
+	defer done()
+
+	err := db.QueryRowContext(ctx, `
+        INSERT INTO notification_channels (channel_id, team_name, user_id, url, format, kinds)
+        VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6)
+        RETURNING is_active, created_at
+    `, channel.ChannelID, channel.TeamName, channel.UserID, channel.URL, channel.Format, pq.Array(channel.Kinds),
+	).Scan(&channel.IsActive, &channel.CreatedAt)
+	return translateError(err)
+}
+
+func (db *DB) ListNotificationChannels(ctx context.Context, filter models.NotificationChannelQuery) ([]models.NotificationChannel, error) {
+	ctx, done := db.startQuery(ctx, "ListNotificationChannels")
+	defer done()
+
+	query := `
+        SELECT channel_id, COALESCE(team_name, ''), COALESCE(user_id, ''), url, format, kinds, is_active, created_at
+        FROM notification_channels
+        WHERE true
+    `
+	var args []interface{}
+
+	if filter.TeamName != "" {
+		args = append(args, filter.TeamName)
+		query += fmt.Sprintf(" AND team_name = $%d", len(args))
+	}
+
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		query += fmt.Sprintf(" AND user_id = $%d", len(args))
+	}
+
+	query += " ORDER BY created_at, channel_id"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := []models.NotificationChannel{}
+	for rows.Next() {
+		var c models.NotificationChannel
+		if err := rows.Scan(&c.ChannelID, &c.TeamName, &c.UserID, &c.URL, &c.Format, pq.Array(&c.Kinds), &c.IsActive, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		channels = append(channels, c)
+	}
+
+	return channels, rows.Err()
+}
+
+// DeleteNotificationChannel удаляет канал вместе с его журналом сообщений
+func (db *DB) DeleteNotificationChannel(ctx context.Context, channelID string) error {
+	ctx, done := db.startQuery(ctx, "DeleteNotificationChannel")
+	defer done()
+
+	result, err := db.ExecContext(ctx, `
+        DELETE FROM notification_channels WHERE channel_id = $1
+    `, channelID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	count, _ := result.RowsAffected()
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetNotificationRecipients возвращает пользователей с их активными каналами: личными и каналами
+// команды. Пользователи без каналов тоже возвращаются - их имена нужны в тексте сообщений.
+func (db *DB) GetNotificationRecipients(ctx context.Context, userIDs []string) ([]models.NotificationRecipient, error) {
+	ctx, done := db.startQuery(ctx, "GetNotificationRecipients")
+	defer done()
+
+	rows, err := db.QueryContext(ctx, `
+        SELECT u.user_id, u.username, c.channel_id, COALESCE(c.team_name, ''), COALESCE(c.user_id, ''),
+               COALESCE(c.url, ''), COALESCE(c.format, ''), COALESCE(c.kinds, '{}')
+        FROM users u
+        LEFT JOIN notification_channels c
+            ON c.is_active AND (c.user_id = u.user_id OR c.team_name = u.team_name)
+        WHERE u.user_id = ANY($1)
+        ORDER BY u.user_id, c.created_at, c.channel_id
+    `, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []models.NotificationRecipient
+	for rows.Next() {
+		var userID, username string
+		var channelID sql.NullString
+		var c models.NotificationChannel
+		if err := rows.Scan(&userID, &username, &channelID, &c.TeamName, &c.UserID, &c.URL, &c.Format, pq.Array(&c.Kinds)); err != nil {
+			return nil, err
+		}
+
+		if len(recipients) == 0 || recipients[len(recipients)-1].UserID != userID {
+			recipients = append(recipients, models.NotificationRecipient{UserID: userID, Username: username})
+		}
+		if channelID.Valid {
+			c.ChannelID, c.IsActive = channelID.String, true
+			last := &recipients[len(recipients)-1]
+			last.Channels = append(last.Channels, c)
+		}
+	}
+
+	return recipients, rows.Err()
+}
+
+// EnqueueNotifications ставит сообщения в очередь. Повторная постановка сообщений того же события
+// (повтор публикации из outbox) ничего не добавляет.
+func (db *DB) EnqueueNotifications(ctx context.Context, deliveries []models.NotificationDelivery) (int, error) {
+	ctx, done := db.startQuery(ctx, "EnqueueNotifications")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var enqueued int
+	for _, d := range deliveries {
+		result, err := tx.ExecContext(ctx, `
+            INSERT INTO notification_deliveries (channel_id, event_id, recipient_id, kind, payload)
+            VALUES ($1, $2, $3, $4, $5::jsonb)
+            ON CONFLICT (channel_id, event_id, recipient_id) DO NOTHING
+        `, d.ChannelID, d.EventID, d.RecipientID, d.Kind, string(d.Payload))
+		if err != nil {
+			return 0, translateError(err)
+		}
+		count, _ := result.RowsAffected()
+		enqueued += int(count)
+	}
+
+	return enqueued, tx.Commit()
+}
+
+// ClaimNotificationDeliveries забирает до limit готовых к отправке сообщений и откладывает их на lease,
+// как ClaimWebhookDeliveries
+func (db *DB) ClaimNotificationDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.NotificationTask, error) {
+	ctx, done := db.startQuery(ctx, "ClaimNotificationDeliveries")
+	defer done()
+
+	rows, err := db.QueryContext(ctx, `
+        WITH due AS (
+            SELECT delivery_id
+            FROM notification_deliveries
+            WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+            ORDER BY next_attempt_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        UPDATE notification_deliveries d
+        SET next_attempt_at = CURRENT_TIMESTAMP + $2::float8 * INTERVAL '1 millisecond'
+        FROM due, notification_channels c
+        WHERE d.delivery_id = due.delivery_id AND c.channel_id = d.channel_id
+        RETURNING d.delivery_id, d.kind, d.payload, d.attempts, c.url
+    `, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []models.NotificationTask
+	for rows.Next() {
+		var task models.NotificationTask
+		if err := rows.Scan(&task.DeliveryID, &task.Kind, &task.Payload, &task.Attempts, &task.URL); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+func (db *DB) MarkNotificationDelivered(ctx context.Context, deliveryID int64, statusCode int) error {
+	ctx, done := db.startQuery(ctx, "MarkNotificationDelivered")
+	defer done()
+
+	_, err := db.ExecContext(ctx, `
+        UPDATE notification_deliveries
+        SET status = 'delivered', attempts = attempts + 1, last_attempt_at = CURRENT_TIMESTAMP,
+            delivered_at = CURRENT_TIMESTAMP, last_status_code = $2, last_error = NULL
+        WHERE delivery_id = $1
+    `, deliveryID, statusCode)
+	return err
+}
+
+// MarkNotificationAttemptFailed записывает неудачную попытку. nextAttemptAt == nil
+// означает, что попытки исчерпаны и сообщение переходит в failed.
+func (db *DB) MarkNotificationAttemptFailed(ctx context.Context, deliveryID int64, statusCode int, attemptErr string, nextAttemptAt *time.Time) error {
+	ctx, done := db.startQuery(ctx, "MarkNotificationAttemptFailed")
+	defer done()
+
+	_, err := db.ExecContext(ctx, `
+        UPDATE notification_deliveries
+        SET status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+            attempts = attempts + 1, last_attempt_at = CURRENT_TIMESTAMP,
+            last_status_code = NULLIF($2, 0), last_error = $3,
+            next_attempt_at = COALESCE($4, next_attempt_at)
+        WHERE delivery_id = $1
+    `, deliveryID, statusCode, attemptErr, nextAttemptAt)
+	return err
+}
+
+// ListNotificationDeliveries возвращает журнал сообщений, новые записи первыми
+func (db *DB) ListNotificationDeliveries(ctx context.Context, filter models.NotificationDeliveryFilter) ([]models.NotificationDelivery, error) {
+	ctx, done := db.startQuery(ctx, "ListNotificationDeliveries")
+	defer done()
+
+	query := `
+        SELECT delivery_id, channel_id, event_id, recipient_id, kind, payload, status, attempts,
+               CASE WHEN status = 'pending' THEN next_attempt_at END,
+               last_attempt_at, last_status_code, COALESCE(last_error, ''), created_at, delivered_at
+        FROM notification_deliveries
+        WHERE true
+    `
+	var args []interface{}
+
+	if filter.ChannelID != "" {
+		args = append(args, filter.ChannelID)
+		query += fmt.Sprintf(" AND channel_id = $%d", len(args))
+	}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	if filter.BeforeID > 0 {
+		args = append(args, filter.BeforeID)
+		query += fmt.Sprintf(" AND delivery_id < $%d", len(args))
+	}
+
+	query += " ORDER BY delivery_id DESC"
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.NotificationDelivery{}
+	for rows.Next() {
+		var d models.NotificationDelivery
+		var payload []byte
+		if err := rows.Scan(
+			&d.DeliveryID, &d.ChannelID, &d.EventID, &d.RecipientID, &d.Kind, &payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// MarkOverdueReviews находит до limit назначений в открытых PR, сделанных раньше assignedBefore,
+// и записывает по каждому событие pull_request.review_overdue. Назначение отмечается в той же
+// транзакции, поэтому событие пишется один раз; SKIP LOCKED разводит реплики.
+func (db *DB) MarkOverdueReviews(ctx context.Context, assignedBefore time.Time, limit int) (int, error) {
+	ctx, done := db.startQuery(ctx, "MarkOverdueReviews")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT ra.id, ra.reviewer_id, ra.assigned_at,
+               pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status,
+               pr.assigned_reviewers, pr.created_at, pr.merged_at
+        FROM pull_requests pr
+        JOIN review_assignments ra ON ra.pull_request_id = pr.pull_request_id
+        WHERE pr.status = 'OPEN' AND ra.unassigned_at IS NULL
+          AND ra.overdue_notified_at IS NULL AND ra.assigned_at <= $1
+        ORDER BY ra.assigned_at
+        LIMIT $2
+        FOR UPDATE OF ra SKIP LOCKED
+    `, assignedBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	type overdue struct {
+		assignmentID int64
+		data         models.ReviewOverdueData
+	}
+	var found []overdue
+	for rows.Next() {
+		var o overdue
+		pr := &models.PullRequest{}
+		if err := rows.Scan(
+			&o.assignmentID, &o.data.ReviewerID, &o.data.AssignedAt,
+			&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status,
+			pq.Array(&pr.AssignedReviewers), &pr.CreatedAt, &pr.MergedAt,
+		); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		o.data.PR = pr
+		found = append(found, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, o := range found {
+		_, err := tx.ExecContext(ctx, `
+            UPDATE review_assignments SET overdue_notified_at = CURRENT_TIMESTAMP WHERE id = $1
+        `, o.assignmentID)
+		if err != nil {
+			return 0, err
+		}
+		if err := insertEvent(ctx, tx, models.EventReviewOverdue, o.data); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(found), tx.Commit()
+}
@@ -0,0 +1,232 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+// insertEvent записывает доменное событие в outbox. Вызывается внутри транзакции
+// изменения, поэтому событие сохраняется тогда и только тогда, когда сохранено изменение.
+func insertEvent(ctx context.Context, tx *sql.Tx, eventType string, data any) error {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+
+	event := models.Event{
+		EventID:    "evt_" + hex.EncodeToString(buf),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO outbox_events (event_id, event_type, payload)
+        VALUES ($1, $2, $3::jsonb)
+    `, event.EventID, event.Type, string(payload))
+	return err
+}
+
+// ClaimOutboxEvents забирает до limit готовых к публикации событий в порядке записи
+// и откладывает их на lease, как ClaimWebhookDeliveries
+func (db *DB) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	ctx, done := db.startQuery(ctx, "ClaimOutboxEvents")
+	defer done()
+
+	rows, err := db.QueryContext(ctx, `
+        WITH due AS (
+            SELECT seq
+            FROM outbox_events
+            WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+            ORDER BY seq
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        UPDATE outbox_events e
+        SET next_attempt_at = CURRENT_TIMESTAMP + $2::float8 * INTERVAL '1 millisecond'
+        FROM due
+        WHERE e.seq = due.seq
+        RETURNING e.seq, e.event_id, e.event_type, e.payload, e.attempts, e.published_sinks, e.created_at
+    `, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var event models.OutboxEvent
+		var payload []byte
+		if err := rows.Scan(
+			&event.Seq, &event.EventID, &event.EventType, &payload, &event.Attempts,
+			pq.Array(&event.PublishedSinks), &event.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// ExtendOutboxLease продлевает аренду ещё не опубликованных событий пачки
+func (db *DB) ExtendOutboxLease(ctx context.Context, seqs []int64, lease time.Duration) error {
+	ctx, done := db.startQuery(ctx, "ExtendOutboxLease")
+	defer done()
+
+	_, err := db.ExecContext(ctx, `
+        UPDATE outbox_events
+        SET next_attempt_at = CURRENT_TIMESTAMP + $2::float8 * INTERVAL '1 millisecond'
+        WHERE seq = ANY($1) AND status = 'pending'
+    `, pq.Array(seqs), lease.Milliseconds())
+	return err
+}
+
+func (db *DB) MarkOutboxPublished(ctx context.Context, seq int64, sinks []string) error {
+	ctx, done := db.startQuery(ctx, "MarkOutboxPublished")
+	defer done()
+
+	_, err := db.ExecContext(ctx, `
+        UPDATE outbox_events
+        SET status = 'published', attempts = attempts + 1, published_sinks = $2,
+            published_at = CURRENT_TIMESTAMP, last_error = NULL
+        WHERE seq = $1
+    `, seq, pq.Array(sinks))
+	return err
+}
+
+// MarkOutboxAttemptFailed сохраняет приёмники, которые уже приняли событие, чтобы повтор
+// не отправлял его туда снова. nextAttemptAt == nil переводит событие в dead.
+func (db *DB) MarkOutboxAttemptFailed(ctx context.Context, seq int64, sinks []string, attemptErr string, nextAttemptAt *time.Time) error {
+	ctx, done := db.startQuery(ctx, "MarkOutboxAttemptFailed")
+	defer done()
+
+	_, err := db.ExecContext(ctx, `
+        UPDATE outbox_events
+        SET status = CASE WHEN $4::timestamptz IS NULL THEN 'dead' ELSE 'pending' END,
+            attempts = attempts + 1, published_sinks = $2, last_error = $3,
+            next_attempt_at = COALESCE($4, next_attempt_at)
+        WHERE seq = $1
+    `, seq, pq.Array(sinks), attemptErr, nextAttemptAt)
+	return err
+}
+
+// RequeueOutboxEvent возвращает событие из dead в очередь с обнулённым счётчиком попыток.
+// Приёмники, которые уже приняли событие, повторно его не получат.
+func (db *DB) RequeueOutboxEvent(ctx context.Context, eventID string) (*models.OutboxEvent, error) {
+	ctx, done := db.startQuery(ctx, "RequeueOutboxEvent")
+	defer done()
+
+	event := models.OutboxEvent{PublishedSinks: []string{}}
+	var payload []byte
+	err := db.QueryRowContext(ctx, `
+        UPDATE outbox_events
+        SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+        WHERE event_id = $1 AND status = 'dead'
+        RETURNING seq, event_id, event_type, payload, status, attempts, published_sinks,
+                  next_attempt_at, COALESCE(last_error, ''), created_at, published_at
+    `, eventID).Scan(
+		&event.Seq, &event.EventID, &event.EventType, &payload, &event.Status, &event.Attempts,
+		pq.Array(&event.PublishedSinks), &event.NextAttemptAt, &event.LastError, &event.CreatedAt, &event.PublishedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	event.Payload = payload
+	return &event, nil
+}
+
+func (db *DB) OutboxEventExists(ctx context.Context, eventID string) (bool, error) {
+	ctx, done := db.startQuery(ctx, "OutboxEventExists")
+	defer done()
+
+	var exists bool
+	err := db.QueryRowContext(ctx, `
+        SELECT EXISTS(SELECT 1 FROM outbox_events WHERE event_id = $1)
+    `, eventID).Scan(&exists)
+	return exists, err
+}
+
+// DeletePublishedOutboxEvents удаляет опубликованные события старше before; dead-события остаются
+func (db *DB) DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int, error) {
+	ctx, done := db.startQuery(ctx, "DeletePublishedOutboxEvents")
+	defer done()
+
+	result, err := db.ExecContext(ctx, `
+        DELETE FROM outbox_events WHERE status = 'published' AND published_at < $1
+    `, before)
+	if err != nil {
+		return 0, err
+	}
+
+	count, _ := result.RowsAffected()
+	return int(count), nil
+}
+
+// ListOutboxEvents возвращает события outbox, новые первыми
+func (db *DB) ListOutboxEvents(ctx context.Context, filter models.OutboxEventFilter) ([]models.OutboxEvent, error) {
+	ctx, done := db.startQuery(ctx, "ListOutboxEvents")
+	defer done()
+
+	query := `
+        SELECT seq, event_id, event_type, payload, status, attempts, published_sinks,
+               CASE WHEN status = 'pending' THEN next_attempt_at END,
+               COALESCE(last_error, ''), created_at, published_at
+        FROM outbox_events
+        WHERE true
+    `
+	var args []interface{}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	if filter.BeforeSeq > 0 {
+		args = append(args, filter.BeforeSeq)
+		query += fmt.Sprintf(" AND seq < $%d", len(args))
+	}
+
+	query += " ORDER BY seq DESC"
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		event := models.OutboxEvent{PublishedSinks: []string{}}
+		var payload []byte
+		if err := rows.Scan(
+			&event.Seq, &event.EventID, &event.EventType, &payload, &event.Status, &event.Attempts,
+			pq.Array(&event.PublishedSinks), &event.NextAttemptAt, &event.LastError, &event.CreatedAt, &event.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
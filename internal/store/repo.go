@@ -1,110 +1,650 @@
 package store
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"antonvedaet/internship_task/internal/models"
 
 	"github.com/lib/pq"
 )
 
-func (db *DB) CreateTeam(team *models.Team) error {
-	tx, err := db.Begin()
+func (db *DB) CreateTeam(ctx context.Context, team *models.Team) error {
+	ctx, done := db.startQuery(ctx, "CreateTeam")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec("INSERT INTO teams (team_name) VALUES ($1) ON CONFLICT (team_name) DO NOTHING", team.TeamName)
+	settings := models.TeamSettings{RequiredReviewers: 2}
+	if team.Settings != nil {
+		settings = *team.Settings
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO teams (team_name, parent_team, required_reviewers, reviewer_fallback) 
+        VALUES ($1, NULLIF($2, ''), $3, $4)
+    `, team.TeamName, team.ParentTeam, settings.RequiredReviewers, settings.ReviewerFallback)
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 
+	// существующий пользователь без команды входит в новую команду, а участник другой команды
+	// не переводится: строка не обновляется, и создание отменяется с ErrInOtherTeam
 	for _, member := range team.Members {
-		_, err = tx.Exec(`
+		result, err := tx.ExecContext(ctx, `
             INSERT INTO users (user_id, username, team_name, is_active) 
             VALUES ($1, $2, $3, $4)
             ON CONFLICT (user_id) DO UPDATE SET
                 username = EXCLUDED.username,
                 team_name = EXCLUDED.team_name,
                 is_active = EXCLUDED.is_active
+            WHERE users.team_name IS NULL
         `, member.UserID, member.Username, team.TeamName, member.IsActive)
 		if err != nil {
-			return err
+			return translateError(err)
+		}
+		if count, _ := result.RowsAffected(); count == 0 {
+			return ErrInOtherTeam
 		}
 	}
 
-	return tx.Commit()
+	created := *team
+	created.Settings = &settings
+	if err := insertEvent(ctx, tx, models.EventTeamCreated, models.TeamCreatedData{Team: &created}); err != nil {
+		return err
+	}
+
+	return translateError(tx.Commit())
 }
 
-func (db *DB) GetTeam(teamName string) (*models.Team, error) {
-	var team models.Team
-	team.TeamName = teamName
+func (db *DB) GetTeam(ctx context.Context, teamName string) (*models.Team, error) {
+	ctx, done := db.startQuery(ctx, "GetTeam")
+	defer done()
+
+	team := models.Team{
+		TeamName: teamName,
+		Members:  []models.TeamMember{},
+		Settings: &models.TeamSettings{},
+	}
+
+	err := db.QueryRowContext(ctx, `
+        SELECT COALESCE(parent_team, ''), required_reviewers, reviewer_fallback 
+        FROM teams 
+        WHERE team_name = $1
+    `, teamName).Scan(&team.ParentTeam, &team.Settings.RequiredReviewers, &team.Settings.ReviewerFallback)
+	if err != nil {
+		return nil, translateError(err)
+	}
 
-	rows, err := db.Query(`
+	rows, err := db.QueryContext(ctx, `
         SELECT user_id, username, is_active 
         FROM users 
         WHERE team_name = $1
     `, teamName)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var member models.TeamMember
 		if err := rows.Scan(&member.UserID, &member.Username, &member.IsActive); err != nil {
-			return nil, err
+			return nil, translateError(err)
 		}
 		team.Members = append(team.Members, member)
 	}
 
-	if len(team.Members) == 0 {
-		return nil, fmt.Errorf("team not found")
+	return &team, rows.Err()
+}
+
+func (db *DB) GetTeamSettings(ctx context.Context, teamName string) (*models.TeamSettings, error) {
+	ctx, done := db.startQuery(ctx, "GetTeamSettings")
+	defer done()
+
+	var settings models.TeamSettings
+	err := db.QueryRowContext(ctx, `
+        SELECT required_reviewers, reviewer_fallback 
+        FROM teams 
+        WHERE team_name = $1
+    `, teamName).Scan(&settings.RequiredReviewers, &settings.ReviewerFallback)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &settings, nil
+}
+
+func (db *DB) GetTeamParent(ctx context.Context, teamName string) (string, error) {
+	ctx, done := db.startQuery(ctx, "GetTeamParent")
+	defer done()
+
+	var parent string
+	err := db.QueryRowContext(ctx, `
+        SELECT COALESCE(parent_team, '') 
+        FROM teams 
+        WHERE team_name = $1
+    `, teamName).Scan(&parent)
+	return parent, translateError(err)
+}
+
+// UpdateTeam переименовывает команду, меняет родителя и те настройки, что заданы в settings
+// (nil - не менять ни одной). Незаданные поля сливаются с текущими значениями в самом UPDATE,
+// поэтому параллельные изменения разных полей не затирают друг друга.
+func (db *DB) UpdateTeam(ctx context.Context, teamName, newTeamName, parentTeam string, settings *models.TeamSettingsUpdate) (*models.TeamSettings, error) {
+	ctx, done := db.startQuery(ctx, "UpdateTeam")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer tx.Rollback()
+
+	if settings == nil {
+		settings = &models.TeamSettingsUpdate{}
+	}
+
+	var updated models.TeamSettings
+	err = tx.QueryRowContext(ctx, `
+        UPDATE teams 
+        SET team_name = $1, parent_team = NULLIF($2, ''),
+            required_reviewers = COALESCE($3, required_reviewers),
+            reviewer_fallback = COALESCE($4, reviewer_fallback)
+        WHERE team_name = $5
+        RETURNING required_reviewers, reviewer_fallback
+    `, newTeamName, parentTeam, settings.RequiredReviewers, settings.ReviewerFallback, teamName).
+		Scan(&updated.RequiredReviewers, &updated.ReviewerFallback)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	err = insertEvent(ctx, tx, models.EventTeamUpdated, models.TeamUpdatedData{
+		TeamName:    teamName,
+		NewTeamName: newTeamName,
+		ParentTeam:  parentTeam,
+		Settings:    &updated,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, translateError(tx.Commit())
+}
+
+func (db *DB) GetTeamDescendants(ctx context.Context, teamName string) ([]string, error) {
+	ctx, done := db.startQuery(ctx, "GetTeamDescendants")
+	defer done()
+
+	return db.queryTeamNames(ctx, `
+        WITH RECURSIVE subtree AS (
+            SELECT team_name, 1 AS depth FROM teams WHERE parent_team = $1
+            UNION ALL
+            SELECT t.team_name, s.depth + 1 FROM teams t JOIN subtree s ON t.parent_team = s.team_name
+        )
+        SELECT team_name FROM subtree ORDER BY depth, team_name
+    `, teamName)
+}
+
+// GetDescendantTeams одним запросом загружает все команды поддерева teamName
+// с участниками и настройками - от ближайших детей вглубь
+func (db *DB) GetDescendantTeams(ctx context.Context, teamName string) ([]models.Team, error) {
+	ctx, done := db.startQuery(ctx, "GetDescendantTeams")
+	defer done()
+
+	rows, err := db.QueryContext(ctx, `
+        WITH RECURSIVE subtree AS (
+            SELECT team_name, 1 AS depth FROM teams WHERE parent_team = $1
+            UNION ALL
+            SELECT t.team_name, s.depth + 1 FROM teams t JOIN subtree s ON t.parent_team = s.team_name
+        )
+        SELECT t.team_name, COALESCE(t.parent_team, ''), t.required_reviewers, t.reviewer_fallback,
+               u.user_id, u.username, u.is_active
+        FROM subtree s
+        JOIN teams t ON t.team_name = s.team_name
+        LEFT JOIN users u ON u.team_name = t.team_name
+        ORDER BY s.depth, t.team_name, u.user_id
+    `, teamName)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	teams := []models.Team{}
+	for rows.Next() {
+		var (
+			team     = models.Team{Settings: &models.TeamSettings{}}
+			userID   sql.NullString
+			username sql.NullString
+			isActive sql.NullBool
+		)
+		err := rows.Scan(&team.TeamName, &team.ParentTeam, &team.Settings.RequiredReviewers, &team.Settings.ReviewerFallback,
+			&userID, &username, &isActive)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		// строки одной команды идут подряд, по строке на участника
+		if len(teams) == 0 || teams[len(teams)-1].TeamName != team.TeamName {
+			team.Members = []models.TeamMember{}
+			teams = append(teams, team)
+		}
+		if userID.Valid {
+			last := &teams[len(teams)-1]
+			last.Members = append(last.Members, models.TeamMember{UserID: userID.String, Username: username.String, IsActive: isActive.Bool})
+		}
 	}
 
-	return &team, nil
+	return teams, translateError(rows.Err())
+}
+
+// GetTeamAncestors возвращает цепочку родителей teamName от ближайшего к корню
+func (db *DB) GetTeamAncestors(ctx context.Context, teamName string) ([]string, error) {
+	ctx, done := db.startQuery(ctx, "GetTeamAncestors")
+	defer done()
+
+	return db.queryTeamNames(ctx, `
+        WITH RECURSIVE ancestors AS (
+            SELECT parent_team AS team_name, 1 AS depth FROM teams WHERE team_name = $1 AND parent_team IS NOT NULL
+            UNION ALL
+            SELECT t.parent_team, a.depth + 1 FROM teams t JOIN ancestors a ON t.team_name = a.team_name
+            WHERE t.parent_team IS NOT NULL
+        )
+        SELECT team_name FROM ancestors ORDER BY depth
+    `, teamName)
 }
 
-func (db *DB) DeactivateTeamUsers(teamName string) (int, error) {
-	result, err := db.Exec(`
+func (db *DB) queryTeamNames(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	names := []string{}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// DeleteTeam удаляет команду вместе с участниками (ON DELETE CASCADE).
+// Участники, которые являются авторами PR, не могут быть удалены из-за FK
+// pull_requests.author_id, поэтому они отвязываются от команды и деактивируются.
+// Строки команды и участников блокируются до проверок: пока транзакция идёт, к команде
+// не добавить участника или дочернюю команду, а участник не станет автором нового PR.
+// Удалённые участники убираются из assigned_reviewers смерженных PR; в открытых PR их нет.
+func (db *DB) DeleteTeam(ctx context.Context, teamName string) (deleted int, detached int, err error) {
+	ctx, done := db.startQuery(ctx, "DeleteTeam")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, translateError(err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, "SELECT team_name FROM teams WHERE team_name = $1 FOR UPDATE", teamName).Scan(&teamName)
+	if err != nil {
+		return 0, 0, translateError(err)
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT user_id FROM users WHERE team_name = $1 FOR UPDATE", teamName); err != nil {
+		return 0, 0, translateError(err)
+	}
+
+	var hasChildren, hasOpenPRs bool
+	err = tx.QueryRowContext(ctx, `
+        SELECT
+            EXISTS (SELECT 1 FROM teams WHERE parent_team = $1),
+            EXISTS (
+                SELECT 1
+                FROM pull_requests
+                WHERE status = 'OPEN' AND (
+                    author_id IN (SELECT user_id FROM users WHERE team_name = $1)
+                    OR assigned_reviewers && ARRAY(SELECT user_id::text FROM users WHERE team_name = $1)
+                )
+            )
+    `, teamName).Scan(&hasChildren, &hasOpenPRs)
+	if err != nil {
+		return 0, 0, translateError(err)
+	}
+	if hasChildren {
+		return 0, 0, ErrHasChildren
+	}
+	if hasOpenPRs {
+		return 0, 0, ErrHasOpenPRs
+	}
+
+	result, err := tx.ExecContext(ctx, `
         UPDATE users 
-        SET is_active = false 
-        WHERE team_name = $1 AND is_active = true
+        SET team_name = NULL, is_active = false 
+        WHERE team_name = $1 AND user_id IN (SELECT author_id FROM pull_requests)
+    `, teamName)
+	if err != nil {
+		return 0, 0, translateError(err)
+	}
+	detachedCount, _ := result.RowsAffected()
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE pull_requests
+        SET assigned_reviewers = ARRAY(
+            SELECT reviewer FROM unnest(assigned_reviewers) AS reviewer
+            WHERE reviewer NOT IN (SELECT user_id FROM users WHERE team_name = $1)
+        )
+        WHERE assigned_reviewers && ARRAY(SELECT user_id::text FROM users WHERE team_name = $1)
     `, teamName)
+	if err != nil {
+		return 0, 0, translateError(err)
+	}
+
+	result, err = tx.ExecContext(ctx, "DELETE FROM users WHERE team_name = $1", teamName)
+	if err != nil {
+		return 0, 0, translateError(err)
+	}
+	deletedCount, _ := result.RowsAffected()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM teams WHERE team_name = $1", teamName); err != nil {
+		return 0, 0, translateError(err)
+	}
+
+	err = insertEvent(ctx, tx, models.EventTeamDeleted, models.TeamDeletedData{
+		TeamName:      teamName,
+		DeletedUsers:  int(deletedCount),
+		DetachedUsers: int(detachedCount),
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, translateError(err)
+	}
+
+	return int(deletedCount), int(detachedCount), nil
+}
+
+func (db *DB) ListTeams(ctx context.Context, filter models.TeamFilter) ([]models.TeamSummary, error) {
+	ctx, done := db.startQuery(ctx, "ListTeams")
+	defer done()
+
+	teams := []models.TeamSummary{}
+	query := `
+        SELECT t.team_name, COALESCE(t.parent_team, ''), COUNT(u.user_id), COUNT(u.user_id) FILTER (WHERE u.is_active)
+        FROM teams t
+        LEFT JOIN users u ON u.team_name = t.team_name
+    `
+	var args []interface{}
+
+	if filter.AfterName != "" {
+		args = append(args, filter.AfterName)
+		query += fmt.Sprintf(" WHERE t.team_name > $%d", len(args))
+	}
+
+	query += " GROUP BY t.team_name ORDER BY t.team_name"
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var team models.TeamSummary
+		if err := rows.Scan(&team.TeamName, &team.ParentTeam, &team.MemberCount, &team.ActiveCount); err != nil {
+			return nil, err
+		}
+		teams = append(teams, team)
+	}
+
+	return teams, rows.Err()
+}
+
+func (db *DB) TeamExists(ctx context.Context, teamName string) (bool, error) {
+	ctx, done := db.startQuery(ctx, "TeamExists")
+	defer done()
+
+	var exists bool
+	err := db.QueryRowContext(ctx, `
+        SELECT EXISTS(SELECT 1 FROM teams WHERE team_name = $1)
+    `, teamName).Scan(&exists)
+	return exists, translateError(err)
+}
+
+func (db *DB) DeactivateTeamUsers(ctx context.Context, teamNames []string) (int, error) {
+	ctx, done := db.startQuery(ctx, "DeactivateTeamUsers")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	defer tx.Rollback()
 
+	rows, err := tx.QueryContext(ctx, `
+        UPDATE users 
+        SET is_active = false 
+        WHERE team_name = ANY($1) AND is_active = true
+        RETURNING user_id
+    `, pq.Array(teamNames))
 	if err != nil {
-		return 0, err
+		return 0, translateError(err)
+	}
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, translateError(err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, translateError(err)
+	}
+
+	if len(userIDs) > 0 {
+		err = insertEvent(ctx, tx, models.EventUsersDeactivated, models.UsersDeactivatedData{
+			TeamNames: teamNames,
+			UserIDs:   userIDs,
+		})
+		if err != nil {
+			return 0, translateError(err)
+		}
 	}
 
-	count, _ := result.RowsAffected()
-	return int(count), nil
+	if err := tx.Commit(); err != nil {
+		return 0, translateError(err)
+	}
+	return len(userIDs), nil
 }
 
 // User
-func (db *DB) GetUser(userID string) (*models.User, error) {
+func (db *DB) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	ctx, done := db.startQuery(ctx, "GetUser")
+	defer done()
+
 	var user models.User
-	err := db.QueryRow(`
-        SELECT user_id, username, team_name, is_active 
+	err := db.QueryRowContext(ctx, `
+        SELECT user_id, username, COALESCE(team_name, ''), is_active 
         FROM users 
         WHERE user_id = $1
     `, userID).Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 	return &user, nil
 }
 
-func (db *DB) UpdateUser(user *models.User) error {
-	_, err := db.Exec(`
+func (db *DB) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	ctx, done := db.startQuery(ctx, "ListUsers")
+	defer done()
+
+	users := []models.User{}
+	query := `
+        SELECT user_id, username, COALESCE(team_name, ''), is_active 
+        FROM users 
+        WHERE true
+    `
+	var args []interface{}
+
+	if filter.TeamName != "" {
+		args = append(args, filter.TeamName)
+		query += fmt.Sprintf(" AND team_name = $%d", len(args))
+	}
+
+	if filter.IsActive != nil {
+		args = append(args, *filter.IsActive)
+		query += fmt.Sprintf(" AND is_active = $%d", len(args))
+	}
+
+	if filter.Username != "" {
+		args = append(args, likePattern(filter.Username))
+		query += fmt.Sprintf(" AND username ILIKE $%d", len(args))
+	}
+
+	if filter.AfterID != "" {
+		args = append(args, filter.AfterID)
+		query += fmt.Sprintf(" AND user_id > $%d", len(args))
+	}
+
+	query += " ORDER BY user_id"
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func likePattern(substring string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return "%" + replacer.Replace(substring) + "%"
+}
+
+func (db *DB) CreateUser(ctx context.Context, user *models.User) error {
+	ctx, done := db.startQuery(ctx, "CreateUser")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO users (user_id, username, team_name, is_active) 
+        VALUES ($1, $2, NULLIF($3, ''), $4)
+    `, user.UserID, user.Username, user.TeamName, user.IsActive)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if err := insertEvent(ctx, tx, models.EventUserCreated, models.UserEventData{User: user}); err != nil {
+		return err
+	}
+
+	return translateError(tx.Commit())
+}
+
+func (db *DB) UpdateUser(ctx context.Context, user *models.User) error {
+	ctx, done := db.startQuery(ctx, "UpdateUser")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+	defer tx.Rollback()
+
+	if err := updateUser(ctx, tx, user); err != nil {
+		return err
+	}
+
+	return translateError(tx.Commit())
+}
+
+// UpdateUserReassigning сохраняет user и в той же транзакции заменяет его в PR из reviews
+// на ReplacedBy (пустой - снимает с PR). PR, которые успели смержить или где user уже
+// не ревьювер, пропускаются; возвращаются применённые замены.
+func (db *DB) UpdateUserReassigning(ctx context.Context, user *models.User, reviews []models.ReassignedReview) ([]models.ReassignedReview, error) {
+	ctx, done := db.startQuery(ctx, "UpdateUserReassigning")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer tx.Rollback()
+
+	applied := []models.ReassignedReview{}
+	for _, review := range reviews {
+		_, err := reassignPR(ctx, tx, review.PullRequestID, user.UserID, review.ReplacedBy)
+		if errors.Is(err, ErrStale) || errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		applied = append(applied, review)
+	}
+
+	if err := updateUser(ctx, tx, user); err != nil {
+		return nil, err
+	}
+
+	return applied, translateError(tx.Commit())
+}
+
+func updateUser(ctx context.Context, tx *sql.Tx, user *models.User) error {
+	_, err := tx.ExecContext(ctx, `
         UPDATE users 
-        SET username = $1, team_name = $2, is_active = $3 
+        SET username = $1, team_name = NULLIF($2, ''), is_active = $3 
         WHERE user_id = $4
     `, user.Username, user.TeamName, user.IsActive, user.UserID)
-	return err
+	if err != nil {
+		return translateError(err)
+	}
+
+	return insertEvent(ctx, tx, models.EventUserUpdated, models.UserEventData{User: user})
 }
 
-func (db *DB) GetActiveTeamUsers(teamName, excludeUserID string) ([]models.User, error) {
+func (db *DB) GetActiveTeamUsers(ctx context.Context, teamName, excludeUserID string) ([]models.User, error) {
+	ctx, done := db.startQuery(ctx, "GetActiveTeamUsers")
+	defer done()
+
 	var users []models.User
 	query := `
         SELECT user_id, username, team_name, is_active 
@@ -118,7 +658,7 @@ func (db *DB) GetActiveTeamUsers(teamName, excludeUserID string) ([]models.User,
 		args = append(args, excludeUserID)
 	}
 
-	rows, err := db.Query(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -135,19 +675,79 @@ func (db *DB) GetActiveTeamUsers(teamName, excludeUserID string) ([]models.User,
 	return users, nil
 }
 
+func (db *DB) GetActiveUsersInTeams(ctx context.Context, teamNames []string, excludeUserIDs []string) ([]models.User, error) {
+	ctx, done := db.startQuery(ctx, "GetActiveUsersInTeams")
+	defer done()
+
+	var users []models.User
+	if excludeUserIDs == nil {
+		excludeUserIDs = []string{}
+	}
+
+	rows, err := db.QueryContext(ctx, `
+        SELECT user_id, username, team_name, is_active 
+        FROM users 
+        WHERE team_name = ANY($1) AND is_active = true AND NOT (user_id = ANY($2))
+    `, pq.Array(teamNames), pq.Array(excludeUserIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
 // PullRequest
-func (db *DB) CreatePR(pr *models.PullRequest) error {
-	_, err := db.Exec(`
+func (db *DB) CreatePR(ctx context.Context, pr *models.PullRequest) error {
+	ctx, done := db.startQuery(ctx, "CreatePR")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
         INSERT INTO pull_requests 
         (pull_request_id, pull_request_name, author_id, status, assigned_reviewers, created_at) 
         VALUES ($1, $2, $3, $4, $5, $6)
     `, pr.PullRequestID, pr.PullRequestName, pr.AuthorID, pr.Status, pq.Array(pr.AssignedReviewers), pr.CreatedAt)
-	return err
+	if err != nil {
+		return translateError(err)
+	}
+
+	for _, reviewerID := range pr.AssignedReviewers {
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO review_assignments (pull_request_id, reviewer_id, assigned_at) 
+            VALUES ($1, $2, $3)
+        `, pr.PullRequestID, reviewerID, pr.CreatedAt)
+		if err != nil {
+			return translateError(err)
+		}
+	}
+
+	if err := insertEvent(ctx, tx, models.EventPRCreated, models.PRCreatedData{PR: pr}); err != nil {
+		return err
+	}
+
+	return translateError(tx.Commit())
 }
 
-func (db *DB) GetPR(prID string) (*models.PullRequest, error) {
+func (db *DB) GetPR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	ctx, done := db.startQuery(ctx, "GetPR")
+	defer done()
+
 	var pr models.PullRequest
-	err := db.QueryRow(`
+	err := db.QueryRowContext(ctx, `
         SELECT pull_request_id, pull_request_name, author_id, status, assigned_reviewers, created_at, merged_at
         FROM pull_requests 
         WHERE pull_request_id = $1
@@ -156,27 +756,228 @@ func (db *DB) GetPR(prID string) (*models.PullRequest, error) {
 		pq.Array(&pr.AssignedReviewers), &pr.CreatedAt, &pr.MergedAt,
 	)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 	return &pr, nil
 }
 
-func (db *DB) UpdatePR(pr *models.PullRequest) error {
-	_, err := db.Exec(`
+// UpdatePR сохраняет статус PR. Строка блокируется и перечитывается: список ревьюверов меняет только
+// ReassignPR, поэтому устаревший assigned_reviewers из pr не записывается, а заменяется актуальным.
+// Событие pull_request.merged пишется только при переходе в MERGED, так что из двух одновременных
+// мержей событие создаст один, а merged_at останется от первого. changed сообщает, изменил ли
+// статус этот вызов: проигравший гонку получает false и актуальную строку в pr.
+func (db *DB) UpdatePR(ctx context.Context, pr *models.PullRequest) (changed bool, err error) {
+	ctx, done := db.startQuery(ctx, "UpdatePR")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockPR(ctx, tx, pr.PullRequestID)
+	if err != nil {
+		return false, err
+	}
+
+	if current.Status == pr.Status {
+		*pr = *current
+		return false, tx.Commit()
+	}
+
+	_, err = tx.ExecContext(ctx, `
         UPDATE pull_requests 
-        SET status = $1, assigned_reviewers = $2, merged_at = $3 
-        WHERE pull_request_id = $4
-    `, pr.Status, pq.Array(pr.AssignedReviewers), pr.MergedAt, pr.PullRequestID)
-	return err
+        SET status = $1, merged_at = $2 
+        WHERE pull_request_id = $3
+    `, pr.Status, pr.MergedAt, pr.PullRequestID)
+	if err != nil {
+		return false, err
+	}
+
+	current.Status, current.MergedAt = pr.Status, pr.MergedAt
+	*pr = *current
+
+	if pr.Status == "MERGED" {
+		err = insertEvent(ctx, tx, models.EventPRMerged, models.PRMergedData{PR: pr})
+	} else {
+		err = insertEvent(ctx, tx, models.EventPRUpdated, models.PRUpdatedData{PR: pr})
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-func (db *DB) GetPRsByReviewer(userID string) ([]models.PullRequest, error) {
+// DeletePR удаляет открытый PR вместе с историей назначений, например закрытый на хостинге без мержа.
+// Смерженный PR не удаляется: под блокировкой строки проверяется статус, иначе возвращается ErrStale.
+func (db *DB) DeletePR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	ctx, done := db.startQuery(ctx, "DeletePR")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pr, err := lockPR(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Status != "OPEN" {
+		return nil, ErrStale
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM pull_requests WHERE pull_request_id = $1`, prID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	err = insertEvent(ctx, tx, models.EventPRDeleted, models.PRDeletedData{PR: pr})
+	if err != nil {
+		return nil, err
+	}
+
+	return pr, tx.Commit()
+}
+
+// ReassignPR заменяет oldReviewerID на newReviewerID и фиксирует замену в истории назначений.
+// Пустой newReviewerID означает снятие ревьювера. Сервис выбирает замену по прочитанной раньше
+// копии PR, поэтому под блокировкой строки проверяется, что PR ещё открыт, oldReviewerID всё ещё
+// назначен, а newReviewerID - ещё нет; иначе возвращается ErrStale.
+func (db *DB) ReassignPR(ctx context.Context, prID, oldReviewerID, newReviewerID string) (*models.PullRequest, error) {
+	ctx, done := db.startQuery(ctx, "ReassignPR")
+	defer done()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pr, err := reassignPR(ctx, tx, prID, oldReviewerID, newReviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pr, tx.Commit()
+}
+
+func reassignPR(ctx context.Context, tx *sql.Tx, prID, oldReviewerID, newReviewerID string) (*models.PullRequest, error) {
+	pr, err := lockPR(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	if pr.Status != "OPEN" || !slices.Contains(pr.AssignedReviewers, oldReviewerID) ||
+		(newReviewerID != "" && slices.Contains(pr.AssignedReviewers, newReviewerID)) {
+		return nil, ErrStale
+	}
+
+	reviewers := make([]string, 0, len(pr.AssignedReviewers))
+	for _, reviewer := range pr.AssignedReviewers {
+		switch {
+		case reviewer != oldReviewerID:
+			reviewers = append(reviewers, reviewer)
+		case newReviewerID != "":
+			reviewers = append(reviewers, newReviewerID)
+		}
+	}
+	pr.AssignedReviewers = reviewers
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE pull_requests 
+        SET assigned_reviewers = $1 
+        WHERE pull_request_id = $2
+    `, pq.Array(pr.AssignedReviewers), pr.PullRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE review_assignments 
+        SET unassigned_at = CURRENT_TIMESTAMP, replaced_by = NULLIF($1, '') 
+        WHERE pull_request_id = $2 AND reviewer_id = $3 AND unassigned_at IS NULL
+    `, newReviewerID, pr.PullRequestID, oldReviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if newReviewerID != "" {
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO review_assignments (pull_request_id, reviewer_id, reassigned_from) 
+            VALUES ($1, $2, $3)
+        `, pr.PullRequestID, newReviewerID, oldReviewerID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = insertEvent(ctx, tx, models.EventReassigned, models.ReassignedData{
+		PR:            pr,
+		OldReviewerID: oldReviewerID,
+		NewReviewerID: newReviewerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+// lockPR читает PR и блокирует строку до конца транзакции
+func lockPR(ctx context.Context, tx *sql.Tx, prID string) (*models.PullRequest, error) {
+	var pr models.PullRequest
+	err := tx.QueryRowContext(ctx, `
+        SELECT pull_request_id, pull_request_name, author_id, status, assigned_reviewers, created_at, merged_at
+        FROM pull_requests 
+        WHERE pull_request_id = $1
+        FOR UPDATE
+    `, prID).Scan(
+		&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status,
+		pq.Array(&pr.AssignedReviewers), &pr.CreatedAt, &pr.MergedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &pr, nil
+}
+
+func (db *DB) GetPRsByReviewer(ctx context.Context, userID string, filter models.ReviewFilter) ([]models.PullRequest, error) {
+	ctx, done := db.startQuery(ctx, "GetPRsByReviewer")
+	defer done()
+
 	var prs []models.PullRequest
-	rows, err := db.Query(`
+	query := `
         SELECT pull_request_id, pull_request_name, author_id, status, assigned_reviewers, created_at, merged_at
         FROM pull_requests 
-        WHERE $1 = ANY(assigned_reviewers)
-    `, userID)
+        WHERE assigned_reviewers @> ARRAY[$1]::text[]
+    `
+	args := []interface{}{userID}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	if filter.AfterCreatedAt != nil {
+		args = append(args, *filter.AfterCreatedAt, filter.AfterID)
+		query += fmt.Sprintf(" AND (created_at, pull_request_id) > ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	query += " ORDER BY created_at, pull_request_id"
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -193,12 +994,15 @@ func (db *DB) GetPRsByReviewer(userID string) ([]models.PullRequest, error) {
 		prs = append(prs, pr)
 	}
 
-	return prs, nil
+	return prs, rows.Err()
 }
 
-func (db *DB) PRExists(prID string) (bool, error) {
+func (db *DB) PRExists(ctx context.Context, prID string) (bool, error) {
+	ctx, done := db.startQuery(ctx, "PRExists")
+	defer done()
+
 	var exists bool
-	err := db.QueryRow(`
+	err := db.QueryRowContext(ctx, `
         SELECT EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id = $1)
     `, prID).Scan(&exists)
 	return exists, err
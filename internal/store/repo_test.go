@@ -0,0 +1,464 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+func newMockDB(t *testing.T) (*DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return Wrap(sqlDB, time.Second), mock
+}
+
+func expectLockedPR(mock sqlmock.Sqlmock, status string, reviewers ...string) {
+	mock.ExpectQuery(`FROM pull_requests\s+WHERE pull_request_id = \$1\s+FOR UPDATE`).
+		WithArgs("pr-1").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"pull_request_id", "pull_request_name", "author_id", "status", "assigned_reviewers", "created_at", "merged_at",
+		}).AddRow("pr-1", "Add search", "u1", status, pq.StringArray(reviewers), time.Now(), nil))
+}
+
+func TestReassignPRRechecksLockedRow(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		reviewers []string
+		newID     string
+	}{
+		{"merged meanwhile", "MERGED", []string{"u2", "u3"}, "u4"},
+		{"old reviewer already replaced", "OPEN", []string{"u5", "u3"}, "u4"},
+		{"new reviewer assigned meanwhile", "OPEN", []string{"u2", "u4"}, "u4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectBegin()
+			expectLockedPR(mock, tt.status, tt.reviewers...)
+			mock.ExpectRollback()
+
+			if _, err := db.ReassignPR(context.Background(), "pr-1", "u2", tt.newID); !errors.Is(err, ErrStale) {
+				t.Fatalf("err = %v, want ErrStale", err)
+			}
+		})
+	}
+}
+
+func TestReassignPRMergesIntoLockedRow(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	// после чтения сервисом u3 заменили на u5: замена u2 не должна вернуть u3
+	expectLockedPR(mock, "OPEN", "u2", "u5")
+	mock.ExpectExec("UPDATE pull_requests").
+		WithArgs(pq.Array([]string{"u4", "u5"}), "pr-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE review_assignments").
+		WithArgs("u4", "pr-1", "u2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO review_assignments").
+		WithArgs("pr-1", "u4", "u2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox_events").
+		WithArgs(sqlmock.AnyArg(), models.EventReassigned, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	pr, err := db.ReassignPR(context.Background(), "pr-1", "u2", "u4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pr.AssignedReviewers; len(got) != 2 || got[0] != "u4" || got[1] != "u5" {
+		t.Errorf("assigned_reviewers = %v, want [u4 u5]", got)
+	}
+}
+
+func TestUpdatePRKeepsLockedReviewers(t *testing.T) {
+	db, mock := newMockDB(t)
+	mergedAt := time.Now()
+
+	mock.ExpectBegin()
+	expectLockedPR(mock, "OPEN", "u4", "u5")
+	mock.ExpectExec(`SET status = \$1, merged_at = \$2`).
+		WithArgs("MERGED", mergedAt, "pr-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox_events").
+		WithArgs(sqlmock.AnyArg(), models.EventPRMerged, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// копия PR прочитана до переназначения u2 на u4
+	pr := &models.PullRequest{PullRequestID: "pr-1", Status: "MERGED", AssignedReviewers: []string{"u2", "u5"}, MergedAt: &mergedAt}
+	changed, err := db.UpdatePR(context.Background(), pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("changed = false, want true")
+	}
+	if got := pr.AssignedReviewers; len(got) != 2 || got[0] != "u4" || got[1] != "u5" {
+		t.Errorf("assigned_reviewers = %v, want [u4 u5]", got)
+	}
+}
+
+func TestUpdatePRAlreadyMerged(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectLockedPR(mock, "MERGED", "u2")
+	mock.ExpectCommit()
+
+	mergedAt := time.Now()
+	pr := &models.PullRequest{PullRequestID: "pr-1", Status: "MERGED", MergedAt: &mergedAt}
+	changed, err := db.UpdatePR(context.Background(), pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Error("changed = true, want false")
+	}
+}
+
+func TestDeletePR(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectLockedPR(mock, "OPEN", "u2")
+	mock.ExpectExec("DELETE FROM pull_requests").
+		WithArgs("pr-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox_events").
+		WithArgs(sqlmock.AnyArg(), models.EventPRDeleted, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	pr, err := db.DeletePR(context.Background(), "pr-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Status != "OPEN" || len(pr.AssignedReviewers) != 1 {
+		t.Errorf("deleted pr = %+v, want the locked row", pr)
+	}
+}
+
+// смерженный PR остаётся в истории, даже если хостинг прислал закрытие позже мержа
+func TestDeletePRKeepsMerged(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectLockedPR(mock, "MERGED", "u2")
+	mock.ExpectRollback()
+
+	if _, err := db.DeletePR(context.Background(), "pr-1"); !errors.Is(err, ErrStale) {
+		t.Fatalf("err = %v, want ErrStale", err)
+	}
+}
+
+// страницы идут по ключу (created_at, pull_request_id): PR с одинаковым временем не теряются и не повторяются
+func TestGetPRsByReviewerPage(t *testing.T) {
+	createdAt := time.Date(2025, 10, 24, 12, 34, 56, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter models.ReviewFilter
+		query  string
+		args   []driver.Value
+	}{
+		{
+			name:   "first page",
+			filter: models.ReviewFilter{Limit: 3},
+			query:  `WHERE assigned_reviewers @> ARRAY\[\$1\]::text\[\]\s+ORDER BY created_at, pull_request_id LIMIT \$2$`,
+			args:   []driver.Value{"u2", 3},
+		},
+		{
+			name:   "next page",
+			filter: models.ReviewFilter{Limit: 3, AfterCreatedAt: &createdAt, AfterID: "pr-2"},
+			query:  `AND \(created_at, pull_request_id\) > \(\$2, \$3\) ORDER BY created_at, pull_request_id LIMIT \$4$`,
+			args:   []driver.Value{"u2", createdAt, "pr-2", 3},
+		},
+		{
+			name:   "next page by status",
+			filter: models.ReviewFilter{Status: "OPEN", Limit: 3, AfterCreatedAt: &createdAt, AfterID: "pr-2"},
+			query:  `AND status = \$2 AND \(created_at, pull_request_id\) > \(\$3, \$4\) ORDER BY created_at, pull_request_id LIMIT \$5$`,
+			args:   []driver.Value{"u2", "OPEN", createdAt, "pr-2", 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectQuery(tt.query).WithArgs(tt.args...).WillReturnRows(sqlmock.NewRows([]string{
+				"pull_request_id", "pull_request_name", "author_id", "status", "assigned_reviewers", "created_at", "merged_at",
+			}).AddRow("pr-3", "Add search", "u1", "OPEN", pq.StringArray{"u2"}, createdAt, nil))
+
+			prs, err := db.GetPRsByReviewer(context.Background(), "u2", tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(prs) != 1 || prs[0].PullRequestID != "pr-3" {
+				t.Errorf("prs = %+v, want pr-3", prs)
+			}
+		})
+	}
+}
+
+func TestListUsersPage(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(`AND team_name = \$1 AND user_id > \$2 ORDER BY user_id LIMIT \$3$`).
+		WithArgs("backend", "u2", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active"}).AddRow("u3", "Carol", "backend", true))
+
+	users, err := db.ListUsers(context.Background(), models.UserFilter{TeamName: "backend", AfterID: "u2", Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].UserID != "u3" {
+		t.Errorf("users = %+v, want u3", users)
+	}
+}
+
+func TestUpdateUserReassigningInOneTransaction(t *testing.T) {
+	db, mock := newMockDB(t)
+	columns := []string{"pull_request_id", "pull_request_name", "author_id", "status", "assigned_reviewers", "created_at", "merged_at"}
+
+	mock.ExpectBegin()
+	// pr-1 смержили после чтения сервисом - пропускается
+	expectLockedPR(mock, "MERGED", "u2", "u3")
+	mock.ExpectQuery(`FROM pull_requests\s+WHERE pull_request_id = \$1\s+FOR UPDATE`).
+		WithArgs("pr-2").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("pr-2", "Fix login", "u1", "OPEN", pq.StringArray{"u2"}, time.Now(), nil))
+	mock.ExpectExec("UPDATE pull_requests").
+		WithArgs(pq.Array([]string{}), "pr-2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE review_assignments").
+		WithArgs("", "pr-2", "u2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox_events").
+		WithArgs(sqlmock.AnyArg(), models.EventReassigned, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE users").
+		WithArgs("Bob", "", true, "u2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox_events").
+		WithArgs(sqlmock.AnyArg(), models.EventUserUpdated, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	user := &models.User{UserID: "u2", Username: "Bob", IsActive: true}
+	reviews := []models.ReassignedReview{{PullRequestID: "pr-1", ReplacedBy: "u4"}, {PullRequestID: "pr-2"}}
+	applied, err := db.UpdateUserReassigning(context.Background(), user, reviews)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || applied[0].PullRequestID != "pr-2" {
+		t.Errorf("applied = %v, want only pr-2", applied)
+	}
+}
+
+func TestUpdateUserReassigningRollsBackReassignments(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	expectLockedPR(mock, "OPEN", "u2")
+	mock.ExpectExec("UPDATE pull_requests").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE review_assignments").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox_events").WillReturnResult(sqlmock.NewResult(0, 1))
+	// команду удалили, пока выбирались замены: переназначение не должно остаться без перевода
+	mock.ExpectExec("UPDATE users").WillReturnError(&pq.Error{Code: "23503"})
+	mock.ExpectRollback()
+
+	user := &models.User{UserID: "u2", Username: "Bob", TeamName: "gone", IsActive: true}
+	_, err := db.UpdateUserReassigning(context.Background(), user, []models.ReassignedReview{{PullRequestID: "pr-1"}})
+	if !errors.Is(err, ErrReferenceViolation) {
+		t.Fatalf("err = %v, want ErrReferenceViolation", err)
+	}
+}
+
+func TestUpdateTeamMergesSettings(t *testing.T) {
+	db, mock := newMockDB(t)
+	fallback := true
+
+	mock.ExpectBegin()
+	// required_reviewers не передан: в UPDATE уходит NULL, и COALESCE сохраняет текущее значение
+	mock.ExpectQuery(`required_reviewers = COALESCE\(\$3, required_reviewers\)`).
+		WithArgs("backend", "", nil, true, "backend").
+		WillReturnRows(sqlmock.NewRows([]string{"required_reviewers", "reviewer_fallback"}).AddRow(3, true))
+	mock.ExpectExec("INSERT INTO outbox_events").
+		WithArgs(sqlmock.AnyArg(), models.EventTeamUpdated, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	settings, err := db.UpdateTeam(context.Background(), "backend", "backend", "", &models.TeamSettingsUpdate{ReviewerFallback: &fallback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.RequiredReviewers != 3 || !settings.ReviewerFallback {
+		t.Errorf("settings = %+v, want required_reviewers 3 kept and reviewer_fallback true", settings)
+	}
+}
+
+func TestUpdateTeamNotFound(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE teams").
+		WillReturnRows(sqlmock.NewRows([]string{"required_reviewers", "reviewer_fallback"}))
+	mock.ExpectRollback()
+
+	if _, err := db.UpdateTeam(context.Background(), "gone", "gone", "", nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func expectLockedTeam(mock sqlmock.Sqlmock, hasChildren, hasOpenPRs bool) {
+	mock.ExpectQuery(`FROM teams WHERE team_name = \$1 FOR UPDATE`).
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+	mock.ExpectExec(`FROM users WHERE team_name = \$1 FOR UPDATE`).
+		WithArgs("backend").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`EXISTS \(SELECT 1 FROM teams WHERE parent_team = \$1\)`).
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"has_children", "has_open_prs"}).AddRow(hasChildren, hasOpenPRs))
+}
+
+func TestDeleteTeam(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	expectLockedTeam(mock, false, false)
+	mock.ExpectExec("SET team_name = NULL, is_active = false").
+		WithArgs("backend").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// удалённые участники не остаются ревьюерами смерженных PR
+	mock.ExpectExec(`UPDATE pull_requests\s+SET assigned_reviewers`).
+		WithArgs("backend").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("DELETE FROM users").
+		WithArgs("backend").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM teams").
+		WithArgs("backend").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox_events").
+		WithArgs(sqlmock.AnyArg(), models.EventTeamDeleted, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	deleted, detached, err := db.DeleteTeam(context.Background(), "backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 || detached != 1 {
+		t.Errorf("deleted = %d, detached = %d; want 2, 1", deleted, detached)
+	}
+}
+
+// проверки идут под блокировкой команды в той же транзакции, что и удаление
+func TestDeleteTeamRejected(t *testing.T) {
+	tests := []struct {
+		name        string
+		hasChildren bool
+		hasOpenPRs  bool
+		want        error
+	}{
+		{"child teams", true, false, ErrHasChildren},
+		{"open pull requests", false, true, ErrHasOpenPRs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectBegin()
+			expectLockedTeam(mock, tt.hasChildren, tt.hasOpenPRs)
+			mock.ExpectRollback()
+
+			if _, _, err := db.DeleteTeam(context.Background(), "backend"); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteTeamNotFound(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM teams WHERE team_name = \$1 FOR UPDATE`).
+		WithArgs("gone").
+		WillReturnRows(sqlmock.NewRows([]string{"team_name"}))
+	mock.ExpectRollback()
+
+	if _, _, err := db.DeleteTeam(context.Background(), "gone"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeactivateTeamUsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		users   []string
+		err     error
+		want    int
+		wantErr error
+	}{
+		{name: "deactivated", users: []string{"u1", "u2"}, want: 2},
+		// событие пишется, только если кто-то выключен
+		{name: "nobody active"},
+		{name: "database error", err: &pq.Error{Code: "23503"}, wantErr: ErrReferenceViolation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+
+			mock.ExpectBegin()
+			update := mock.ExpectQuery(`UPDATE users\s+SET is_active = false`).
+				WithArgs(pq.Array([]string{"backend", "backend-api"}))
+			if tt.err != nil {
+				update.WillReturnError(tt.err)
+				mock.ExpectRollback()
+			} else {
+				rows := sqlmock.NewRows([]string{"user_id"})
+				for _, id := range tt.users {
+					rows.AddRow(id)
+				}
+				update.WillReturnRows(rows)
+				if len(tt.users) > 0 {
+					mock.ExpectExec("INSERT INTO outbox_events").
+						WithArgs(sqlmock.AnyArg(), models.EventUsersDeactivated, sqlmock.AnyArg()).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+				mock.ExpectCommit()
+			}
+
+			got, err := db.DeactivateTeamUsers(context.Background(), []string{"backend", "backend-api"})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("count = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
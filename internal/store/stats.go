@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"antonvedaet/internship_task/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Во всех запросах статистики NULL в границах периода или в списке команд означает "без ограничения".
+
+func (db *DB) GetUserStats(ctx context.Context, filter models.StatsFilter) ([]models.UserStats, error) {
+	ctx, done := db.startQuery(ctx, "GetUserStats")
+	defer done()
+
+	stats := []models.UserStats{}
+	rows, err := db.QueryContext(ctx, `
+        SELECT u.user_id, COALESCE(u.team_name, ''),
+            (SELECT COUNT(*) FROM review_assignments ra
+             WHERE ra.reviewer_id = u.user_id
+               AND ($1::timestamptz IS NULL OR ra.assigned_at >= $1)
+               AND ($2::timestamptz IS NULL OR ra.assigned_at < $2)),
+            (SELECT COUNT(*) FROM pull_requests pr
+             WHERE pr.status = 'OPEN' AND pr.assigned_reviewers @> ARRAY[u.user_id::text]),
+            (SELECT COUNT(*) FROM review_assignments ra
+             WHERE ra.reviewer_id = u.user_id AND ra.reassigned_from IS NOT NULL
+               AND ($1::timestamptz IS NULL OR ra.assigned_at >= $1)
+               AND ($2::timestamptz IS NULL OR ra.assigned_at < $2)),
+            (SELECT COUNT(*) FROM review_assignments ra
+             WHERE ra.reviewer_id = u.user_id AND ra.unassigned_at IS NOT NULL
+               AND ($1::timestamptz IS NULL OR ra.unassigned_at >= $1)
+               AND ($2::timestamptz IS NULL OR ra.unassigned_at < $2))
+        FROM users u
+        WHERE $3::text[] IS NULL OR u.team_name = ANY($3)
+        ORDER BY u.user_id
+    `, filter.From, filter.To, pq.Array(filter.TeamNames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.UserStats
+		if err := rows.Scan(
+			&s.UserID, &s.TeamName, &s.TotalAssignments, &s.OpenAssignments,
+			&s.ReassignmentsReceived, &s.ReassignmentsGiven,
+		); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetTeamStats считает PR по команде автора, период применяется к времени создания PR
+func (db *DB) GetTeamStats(ctx context.Context, filter models.StatsFilter) ([]models.TeamStats, error) {
+	ctx, done := db.startQuery(ctx, "GetTeamStats")
+	defer done()
+
+	stats := []models.TeamStats{}
+	rows, err := db.QueryContext(ctx, `
+        SELECT t.team_name,
+            COUNT(pr.pull_request_id),
+            COUNT(pr.pull_request_id) FILTER (WHERE pr.status = 'OPEN'),
+            COUNT(pr.pull_request_id) FILTER (WHERE pr.status = 'MERGED'),
+            AVG(EXTRACT(EPOCH FROM pr.merged_at - pr.created_at)) FILTER (WHERE pr.merged_at IS NOT NULL)
+        FROM teams t
+        LEFT JOIN users u ON u.team_name = t.team_name
+        LEFT JOIN pull_requests pr ON pr.author_id = u.user_id
+            AND ($1::timestamptz IS NULL OR pr.created_at >= $1)
+            AND ($2::timestamptz IS NULL OR pr.created_at < $2)
+        WHERE $3::text[] IS NULL OR t.team_name = ANY($3)
+        GROUP BY t.team_name
+        ORDER BY t.team_name
+    `, filter.From, filter.To, pq.Array(filter.TeamNames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.TeamStats
+		var avg sql.NullFloat64
+		if err := rows.Scan(&s.TeamName, &s.TotalPRs, &s.OpenPRs, &s.MergedPRs, &avg); err != nil {
+			return nil, err
+		}
+		if avg.Valid {
+			s.AvgTimeToMergeSeconds = &avg.Float64
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
+
+func (db *DB) GetAvgTimeToMerge(ctx context.Context, filter models.StatsFilter) (*float64, error) {
+	ctx, done := db.startQuery(ctx, "GetAvgTimeToMerge")
+	defer done()
+
+	var avg sql.NullFloat64
+	err := db.QueryRowContext(ctx, `
+        SELECT AVG(EXTRACT(EPOCH FROM pr.merged_at - pr.created_at))
+        FROM pull_requests pr
+        LEFT JOIN users u ON u.user_id = pr.author_id
+        WHERE pr.merged_at IS NOT NULL
+          AND ($1::timestamptz IS NULL OR pr.created_at >= $1)
+          AND ($2::timestamptz IS NULL OR pr.created_at < $2)
+          AND ($3::text[] IS NULL OR u.team_name = ANY($3))
+    `, filter.From, filter.To, pq.Array(filter.TeamNames)).Scan(&avg)
+	if err != nil {
+		return nil, err
+	}
+	if !avg.Valid {
+		return nil, nil
+	}
+	return &avg.Float64, nil
+}
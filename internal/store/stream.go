@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/models"
+)
+
+// OutboxChannel - канал NOTIFY, в который триггер outbox_events пишет seq новой записи
+const OutboxChannel = "outbox_events"
+
+// NewOutboxListener слушает OutboxChannel на отдельном соединении вне пула. После обрыва
+// соединение восстанавливается само, а в Notify приходит nil: уведомления за время обрыва потеряны.
+func NewOutboxListener(cfg config.DatabaseConfig, onEvent pq.EventCallbackType) (*pq.Listener, error) {
+	listener := pq.NewListener(connString(cfg), time.Second, 30*time.Second, onEvent)
+	if err := listener.Listen(OutboxChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+func (db *DB) GetOutboxEvent(ctx context.Context, seq int64) (*models.OutboxEvent, error) {
+	ctx, done := db.startQuery(ctx, "GetOutboxEvent")
+	defer done()
+
+	var event models.OutboxEvent
+	var payload []byte
+	err := db.QueryRowContext(ctx, `
+        SELECT seq, event_id, event_type, payload, created_at
+        FROM outbox_events
+        WHERE seq = $1
+    `, seq).Scan(&event.Seq, &event.EventID, &event.EventType, &payload, &event.CreatedAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	event.Payload = payload
+	return &event, nil
+}
+
+// userReviewEventFilter отбирает события ревьюера $1 и должно совпадать с stream.Recipients:
+// назначение при создании и мерж - для ревьюеров PR, переназначение - для снятого и нового ревьюера.
+const userReviewEventFilter = `
+            (event_type IN ($4, $5) AND payload->'data'->'pr'->'assigned_reviewers' ? $1::text)
+            OR (event_type = $6 AND $1::text IN (payload->'data'->>'old_reviewer_id', payload->'data'->>'new_reviewer_id'))`
+
+// ListUserReviewEvents возвращает события журнала после afterSeq, которые касаются ревьюера userID
+func (db *DB) ListUserReviewEvents(ctx context.Context, userID string, afterSeq int64, limit int) ([]models.OutboxEvent, error) {
+	ctx, done := db.startQuery(ctx, "ListUserReviewEvents")
+	defer done()
+
+	rows, err := db.QueryContext(ctx, `
+        SELECT seq, event_id, event_type, payload, created_at
+        FROM outbox_events
+        WHERE seq > $2 AND (`+userReviewEventFilter+`
+        )
+        ORDER BY seq
+        LIMIT $3
+    `, userID, afterSeq, limit, models.EventPRCreated, models.EventPRMerged, models.EventReassigned)
+	if err != nil {
+		return nil, err
+	}
+	return scanStreamEvents(rows)
+}
+
+// ListLateUserReviewEvents возвращает события ревьюера с seq между afterSeq и seq, которые могли
+// закоммититься после события seq: их транзакции не были завершены к его вставке.
+// afterSeq - курсор страницы, для первой страницы 0. Часть событий клиент уже мог получить,
+// поэтому повторы отбрасываются по seq. Если событие seq удалено по OUTBOX_RETENTION, перечитывать нечего.
+func (db *DB) ListLateUserReviewEvents(ctx context.Context, userID string, seq, afterSeq int64, limit int) ([]models.OutboxEvent, error) {
+	ctx, done := db.startQuery(ctx, "ListLateUserReviewEvents")
+	defer done()
+
+	rows, err := db.QueryContext(ctx, `
+        SELECT seq, event_id, event_type, payload, created_at
+        FROM outbox_events
+        WHERE seq < $2 AND seq > $7
+          AND txid >= (SELECT horizon FROM outbox_events WHERE seq = $2)
+          AND (`+userReviewEventFilter+`
+        )
+        ORDER BY seq
+        LIMIT $3
+    `, userID, seq, limit, models.EventPRCreated, models.EventPRMerged, models.EventReassigned, afterSeq)
+	if err != nil {
+		return nil, err
+	}
+	return scanStreamEvents(rows)
+}
+
+// GetSettledOutboxSeq возвращает seq, ниже которого ListLateUserReviewEvents для seq и следующих
+// событий уже ничего не вернёт: у всех видимых событий ниже него транзакции завершились до вставки seq
+func (db *DB) GetSettledOutboxSeq(ctx context.Context, seq int64) (int64, error) {
+	ctx, done := db.startQuery(ctx, "GetSettledOutboxSeq")
+	defer done()
+
+	var settled int64
+	err := db.QueryRowContext(ctx, `
+        SELECT COALESCE(MIN(seq), $1)
+        FROM outbox_events
+        WHERE txid >= (SELECT horizon FROM outbox_events WHERE seq = $1)
+    `, seq).Scan(&settled)
+	return settled, err
+}
+
+func scanStreamEvents(rows *sql.Rows) ([]models.OutboxEvent, error) {
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		var event models.OutboxEvent
+		var payload []byte
+		if err := rows.Scan(&event.Seq, &event.EventID, &event.EventType, &payload, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetLatestOutboxSeq - seq последней записи журнала; с него начинается поток без Last-Event-ID
+func (db *DB) GetLatestOutboxSeq(ctx context.Context) (int64, error) {
+	ctx, done := db.startQuery(ctx, "GetLatestOutboxSeq")
+	defer done()
+
+	var seq int64
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM outbox_events").Scan(&seq)
+	return seq, err
+}
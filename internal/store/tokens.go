@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+func (db *DB) CreateAPIToken(ctx context.Context, token *models.APIToken, tokenHash string) error {
+	ctx, done := db.startQuery(ctx, "CreateAPIToken")
+	defer done()
+
+	err := db.QueryRowContext(ctx, `
+        INSERT INTO api_tokens (token_id, name, role, user_id, token_hash)
+        VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+        RETURNING created_at
+    `, token.TokenID, token.Name, token.Role, token.UserID, tokenHash).Scan(&token.CreatedAt)
+	return translateError(err)
+}
+
+// EnsureAPIToken создаёт токен, если токена с таким хешем ещё нет
+func (db *DB) EnsureAPIToken(ctx context.Context, token *models.APIToken, tokenHash string) (bool, error) {
+	ctx, done := db.startQuery(ctx, "EnsureAPIToken")
+	defer done()
+
+	result, err := db.ExecContext(ctx, `
+        INSERT INTO api_tokens (token_id, name, role, user_id, token_hash)
+        VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+        ON CONFLICT (token_hash) DO NOTHING
+    `, token.TokenID, token.Name, token.Role, token.UserID, tokenHash)
+	if err != nil {
+		return false, translateError(err)
+	}
+
+	count, _ := result.RowsAffected()
+	return count > 0, nil
+}
+
+// GetActiveAPIToken ищет неотозванный токен по хешу и отмечает время использования
+// (не чаще раза в минуту, чтобы не писать в базу на каждый запрос)
+func (db *DB) GetActiveAPIToken(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	ctx, done := db.startQuery(ctx, "GetActiveAPIToken")
+	defer done()
+
+	var token models.APIToken
+	err := db.QueryRowContext(ctx, `
+        SELECT token_id, name, role, COALESCE(user_id, ''), created_at, last_used_at
+        FROM api_tokens
+        WHERE token_hash = $1 AND revoked_at IS NULL
+    `, tokenHash).Scan(&token.TokenID, &token.Name, &token.Role, &token.UserID, &token.CreatedAt, &token.LastUsedAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+        UPDATE api_tokens
+        SET last_used_at = CURRENT_TIMESTAMP
+        WHERE token_id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+    `, token.TokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (db *DB) ListAPITokens(ctx context.Context) ([]models.APIToken, error) {
+	ctx, done := db.startQuery(ctx, "ListAPITokens")
+	defer done()
+
+	rows, err := db.QueryContext(ctx, `
+        SELECT token_id, name, role, COALESCE(user_id, ''), created_at, last_used_at, revoked_at
+        FROM api_tokens
+        ORDER BY created_at, token_id
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		var token models.APIToken
+		if err := rows.Scan(&token.TokenID, &token.Name, &token.Role, &token.UserID, &token.CreatedAt, &token.LastUsedAt, &token.RevokedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (db *DB) RevokeAPIToken(ctx context.Context, tokenID string) (*models.APIToken, error) {
+	ctx, done := db.startQuery(ctx, "RevokeAPIToken")
+	defer done()
+
+	var token models.APIToken
+	err := db.QueryRowContext(ctx, `
+        UPDATE api_tokens
+        SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP)
+        WHERE token_id = $1
+        RETURNING token_id, name, role, COALESCE(user_id, ''), created_at, last_used_at, revoked_at
+    `, tokenID).Scan(&token.TokenID, &token.Name, &token.Role, &token.UserID, &token.CreatedAt, &token.LastUsedAt, &token.RevokedAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &token, nil
+}
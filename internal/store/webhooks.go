@@ -0,0 +1,218 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"antonvedaet/internship_task/internal/models"
+)
+
+func (db *DB) CreateWebhookSubscription(ctx context.Context, sub *models.WebhookSubscription, secret string) error {
+	ctx, done := db.startQuery(ctx, "CreateWebhookSubscription")
+	defer done()
+
+	err := db.QueryRowContext(ctx, `
+        INSERT INTO webhook_subscriptions (subscription_id, url, secret, events)
+        VALUES ($1, $2, $3, $4)
+        RETURNING is_active, created_at
+    `, sub.SubscriptionID, sub.URL, secret, pq.Array(sub.Events)).Scan(&sub.IsActive, &sub.CreatedAt)
+	return translateError(err)
+}
+
+func (db *DB) ListWebhookSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	ctx, done := db.startQuery(ctx, "ListWebhookSubscriptions")
+	defer done()
+
+	rows, err := db.QueryContext(ctx, `
+        SELECT subscription_id, url, events, is_active, created_at
+        FROM webhook_subscriptions
+        ORDER BY created_at, subscription_id
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.WebhookSubscription{}
+	for rows.Next() {
+		var sub models.WebhookSubscription
+		if err := rows.Scan(&sub.SubscriptionID, &sub.URL, pq.Array(&sub.Events), &sub.IsActive, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs, rows.Err()
+}
+
+// DeleteWebhookSubscription удаляет подписку вместе с её журналом доставок
+func (db *DB) DeleteWebhookSubscription(ctx context.Context, subscriptionID string) error {
+	ctx, done := db.startQuery(ctx, "DeleteWebhookSubscription")
+	defer done()
+
+	result, err := db.ExecContext(ctx, `
+        DELETE FROM webhook_subscriptions WHERE subscription_id = $1
+    `, subscriptionID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	count, _ := result.RowsAffected()
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// EnqueueWebhookEvent ставит событие в очередь каждой активной подписке, которая на него подписана.
+// Повторная постановка того же события (повтор публикации из outbox) ничего не добавляет.
+func (db *DB) EnqueueWebhookEvent(ctx context.Context, eventID, eventType string, payload json.RawMessage) (int, error) {
+	ctx, done := db.startQuery(ctx, "EnqueueWebhookEvent")
+	defer done()
+
+	result, err := db.ExecContext(ctx, `
+        INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+        SELECT subscription_id, $1::text, $2::text, $3::jsonb
+        FROM webhook_subscriptions
+        WHERE is_active AND (cardinality(events) = 0 OR $2::text = ANY(events))
+        ON CONFLICT (subscription_id, event_id) DO NOTHING
+    `, eventID, eventType, string(payload))
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	count, _ := result.RowsAffected()
+	return int(count), nil
+}
+
+// ClaimWebhookDeliveries забирает до limit готовых к отправке доставок и откладывает их на lease:
+// если процесс упадёт посреди отправки, доставка снова станет доступна после lease.
+// SKIP LOCKED позволяет нескольким репликам разбирать очередь, не мешая друг другу.
+func (db *DB) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookTask, error) {
+	ctx, done := db.startQuery(ctx, "ClaimWebhookDeliveries")
+	defer done()
+
+	rows, err := db.QueryContext(ctx, `
+        WITH due AS (
+            SELECT delivery_id
+            FROM webhook_deliveries
+            WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+            ORDER BY next_attempt_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        UPDATE webhook_deliveries d
+        SET next_attempt_at = CURRENT_TIMESTAMP + $2::float8 * INTERVAL '1 millisecond'
+        FROM due, webhook_subscriptions s
+        WHERE d.delivery_id = due.delivery_id AND s.subscription_id = d.subscription_id
+        RETURNING d.delivery_id, d.event_id, d.event_type, d.payload, d.attempts, s.url, s.secret
+    `, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []models.WebhookTask
+	for rows.Next() {
+		var task models.WebhookTask
+		if err := rows.Scan(&task.DeliveryID, &task.EventID, &task.EventType, &task.Payload, &task.Attempts, &task.URL, &task.Secret); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+func (db *DB) MarkWebhookDelivered(ctx context.Context, deliveryID int64, statusCode int) error {
+	ctx, done := db.startQuery(ctx, "MarkWebhookDelivered")
+	defer done()
+
+	_, err := db.ExecContext(ctx, `
+        UPDATE webhook_deliveries
+        SET status = 'delivered', attempts = attempts + 1, last_attempt_at = CURRENT_TIMESTAMP,
+            delivered_at = CURRENT_TIMESTAMP, last_status_code = $2, last_error = NULL
+        WHERE delivery_id = $1
+    `, deliveryID, statusCode)
+	return err
+}
+
+// MarkWebhookAttemptFailed записывает неудачную попытку. nextAttemptAt == nil
+// означает, что попытки исчерпаны и доставка переходит в failed.
+func (db *DB) MarkWebhookAttemptFailed(ctx context.Context, deliveryID int64, statusCode int, attemptErr string, nextAttemptAt *time.Time) error {
+	ctx, done := db.startQuery(ctx, "MarkWebhookAttemptFailed")
+	defer done()
+
+	_, err := db.ExecContext(ctx, `
+        UPDATE webhook_deliveries
+        SET status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+            attempts = attempts + 1, last_attempt_at = CURRENT_TIMESTAMP,
+            last_status_code = NULLIF($2, 0), last_error = $3,
+            next_attempt_at = COALESCE($4, next_attempt_at)
+        WHERE delivery_id = $1
+    `, deliveryID, statusCode, attemptErr, nextAttemptAt)
+	return err
+}
+
+// ListWebhookDeliveries возвращает журнал доставок, новые записи первыми
+func (db *DB) ListWebhookDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	ctx, done := db.startQuery(ctx, "ListWebhookDeliveries")
+	defer done()
+
+	query := `
+        SELECT delivery_id, subscription_id, event_id, event_type, payload, status, attempts,
+               CASE WHEN status = 'pending' THEN next_attempt_at END,
+               last_attempt_at, last_status_code, COALESCE(last_error, ''), created_at, delivered_at
+        FROM webhook_deliveries
+        WHERE true
+    `
+	var args []interface{}
+
+	if filter.SubscriptionID != "" {
+		args = append(args, filter.SubscriptionID)
+		query += fmt.Sprintf(" AND subscription_id = $%d", len(args))
+	}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	if filter.BeforeID > 0 {
+		args = append(args, filter.BeforeID)
+		query += fmt.Sprintf(" AND delivery_id < $%d", len(args))
+	}
+
+	query += " ORDER BY delivery_id DESC"
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var payload []byte
+		if err := rows.Scan(
+			&d.DeliveryID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,221 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+const (
+	// subscriptionBuffer - сколько событий может ждать медленного клиента; при переполнении
+	// поток закрывается, и клиент догоняет журнал через Last-Event-ID
+	subscriptionBuffer = 64
+	// listenerPingInterval - проверка соединения LISTEN, обрыв без трафика иначе не заметен
+	listenerPingInterval = 90 * time.Second
+)
+
+// Subscription - подписка одного SSE-потока на события ревьюера
+type Subscription struct {
+	UserID string
+	// Events получает события журнала, которые касаются UserID
+	Events chan models.OutboxEvent
+	// Resync сигнализирует, что уведомления могли быть потеряны и журнал нужно перечитать
+	Resync chan struct{}
+	// Done закрывается, когда хаб закрыл подписку: клиент не успевает читать или сервер останавливается
+	Done chan struct{}
+}
+
+// Hub получает уведомления LISTEN/NOTIFY о новых записях outbox и раздаёт события
+// подпискам этой реплики. Уведомления приходят от всех реплик, поэтому клиент
+// получает событие, на какой бы реплике оно ни было записано.
+type Hub struct {
+	db       *store.DB
+	listener *pq.Listener
+	logger   *slog.Logger
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewHub(db *store.DB, listener *pq.Listener, logger *slog.Logger) *Hub {
+	return &Hub{
+		db:       db,
+		listener: listener,
+		logger:   logger,
+		subs:     make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe после Close возвращает уже закрытую подписку: поток сразу завершится,
+// и клиент переподключится к другой реплике
+func (h *Hub) Subscribe(userID string) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{
+		UserID: userID,
+		Events: make(chan models.OutboxEvent, subscriptionBuffer),
+		Resync: make(chan struct{}, 1),
+		Done:   make(chan struct{}),
+	}
+	if h.closed {
+		close(sub.Done)
+		return sub
+	}
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(sub)
+}
+
+// Close закрывает все подписки и не принимает новые, чтобы остановка сервера
+// не ждала открытых потоков. Вызывается в начале http.Server.Shutdown.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		h.drop(sub)
+	}
+}
+
+// drop вызывается под h.mu
+func (h *Hub) drop(sub *Subscription) {
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.Done)
+	}
+}
+
+// Run читает уведомления до отмены ctx и закрывает listener
+func (h *Hub) Run(ctx context.Context) {
+	defer h.listener.Close()
+	h.logger.Info("event stream hub started", "channel", store.OutboxChannel)
+
+	ping := time.NewTicker(listenerPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.Close()
+			h.logger.Info("event stream hub stopped")
+			return
+		case <-ping.C:
+			go h.listener.Ping()
+		case n := <-h.listener.Notify:
+			if n == nil {
+				// соединение восстановлено, уведомления за время обрыва потеряны
+				h.resyncAll()
+				continue
+			}
+			h.dispatch(ctx, n.Extra)
+		}
+	}
+}
+
+func (h *Hub) dispatch(ctx context.Context, extra string) {
+	seq, err := strconv.ParseInt(extra, 10, 64)
+	if err != nil {
+		h.logger.WarnContext(ctx, "unexpected outbox notification", "payload", extra)
+		return
+	}
+
+	h.mu.Lock()
+	idle := len(h.subs) == 0
+	h.mu.Unlock()
+	if idle {
+		return
+	}
+
+	event, err := h.db.GetOutboxEvent(ctx, seq)
+	if err != nil {
+		// без события клиенты не узнают, что что-то пропустили, поэтому просим перечитать журнал
+		h.logger.ErrorContext(ctx, "load outbox event for stream", "seq", seq, "error", err)
+		h.resyncAll()
+		return
+	}
+
+	recipients := Recipients(*event)
+	if len(recipients) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if !slices.Contains(recipients, sub.UserID) {
+			continue
+		}
+		select {
+		case sub.Events <- *event:
+		default:
+			h.logger.WarnContext(ctx, "event stream subscriber is too slow, closing", "user_id", sub.UserID)
+			h.drop(sub)
+		}
+	}
+}
+
+func (h *Hub) resyncAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		select {
+		case sub.Resync <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Recipients возвращает ревьюеров, которым адресовано событие. Должно совпадать
+// с фильтром store.ListUserReviewEvents, по которому поток догоняет журнал.
+func Recipients(event models.OutboxEvent) []string {
+	var envelope struct {
+		Data struct {
+			PR *struct {
+				AssignedReviewers []string `json:"assigned_reviewers"`
+			} `json:"pr"`
+			OldReviewerID string `json:"old_reviewer_id"`
+			NewReviewerID string `json:"new_reviewer_id"`
+		} `json:"data"`
+	}
+
+	switch event.EventType {
+	case models.EventPRCreated, models.EventPRMerged, models.EventReassigned:
+	default:
+		return nil
+	}
+	if err := json.Unmarshal(event.Payload, &envelope); err != nil {
+		return nil
+	}
+
+	data := envelope.Data
+	if event.EventType == models.EventReassigned {
+		var recipients []string
+		for _, userID := range []string{data.OldReviewerID, data.NewReviewerID} {
+			if userID != "" {
+				recipients = append(recipients, userID)
+			}
+		}
+		return recipients
+	}
+	if data.PR == nil {
+		return nil
+	}
+	return data.PR.AssignedReviewers
+}
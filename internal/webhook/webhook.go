@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/delivery"
+	"antonvedaet/internship_task/internal/metrics"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Id"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign считает подпись, которую подписчик проверяет так же:
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+// Метка времени в подписи защищает от повторной отправки старого запроса.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewDispatcher разбирает очередь webhook_deliveries: отправляет подписанные запросы
+// и планирует повторы с экспоненциальной задержкой
+func NewDispatcher(db *store.DB, cfg config.WebhooksConfig, logger *slog.Logger) *delivery.Worker[models.WebhookTask] {
+	return delivery.NewWorker("webhook", &queue{db: db}, delivery.Config{
+		PollInterval: cfg.PollInterval,
+		BatchSize:    cfg.BatchSize,
+		Timeout:      cfg.Timeout,
+		MaxAttempts:  cfg.MaxAttempts,
+		BackoffBase:  cfg.BackoffBase,
+		BackoffMax:   cfg.BackoffMax,
+	}, logger)
+}
+
+type queue struct {
+	db *store.DB
+}
+
+func (q *queue) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookTask, error) {
+	return q.db.ClaimWebhookDeliveries(ctx, limit, lease)
+}
+
+func (q *queue) Task(task models.WebhookTask) delivery.Task {
+	return delivery.Task{ID: task.DeliveryID, Attempts: task.Attempts, Kind: task.EventType}
+}
+
+func (q *queue) NewRequest(ctx context.Context, task models.WebhookTask) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, task.URL, bytes.NewReader(task.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pr-reviewer-webhooks")
+	req.Header.Set(HeaderEvent, task.EventType)
+	req.Header.Set(HeaderEventID, task.EventID)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(task.DeliveryID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(task.Secret, timestamp, task.Payload))
+	return req, nil
+}
+
+func (q *queue) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	return q.db.MarkWebhookDelivered(ctx, id, statusCode)
+}
+
+func (q *queue) MarkAttemptFailed(ctx context.Context, id int64, statusCode int, lastError string, nextAttemptAt *time.Time) error {
+	return q.db.MarkWebhookAttemptFailed(ctx, id, statusCode, lastError, nextAttemptAt)
+}
+
+func (q *queue) Observe(_ delivery.Task, result string) {
+	metrics.WebhookDeliveries.WithLabelValues(result).Inc()
+}
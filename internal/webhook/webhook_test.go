@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"antonvedaet/internship_task/internal/config"
+	"antonvedaet/internship_task/internal/models"
+	"antonvedaet/internship_task/internal/store"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event_type":"pull_request.merged"}`)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		want      bool
+	}{
+		// printf '%s.%s' 1700000000 "$body" | openssl dgst -sha256 -hmac whsec_test_secret_1234
+		{"reference", "whsec_test_secret_1234", 1700000000, body, true},
+		{"other secret", "whsec_other_secret_5678", 1700000000, body, false},
+		{"other timestamp", "whsec_test_secret_1234", 1700000001, body, false},
+		{"other body", "whsec_test_secret_1234", 1700000000, []byte(`{"event_type":"pull_request.created"}`), false},
+	}
+
+	const want = "sha256=c2d6f973107dcf37c86edaeaef2569fc7879a4c4c4c439071d4b2fe1966e12a7"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.timestamp, tt.body); (got == want) != tt.want {
+				t.Errorf("Sign() = %s, match reference %v, want %v", got, got == want, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcherDrain(t *testing.T) {
+	const secret = "whsec_test_secret_1234"
+	payload := []byte(`{"event_type":"pull_request.merged"}`)
+
+	var (
+		mu      sync.Mutex
+		headers = map[string]http.Header{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers[r.URL.Path] = r.Header.Clone()
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/down":
+			http.Error(w, "upstream unavailable", http.StatusBadGateway)
+		case "/removed":
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	cfg := config.WebhooksConfig{
+		BatchSize:   10,
+		Timeout:     5 * time.Second,
+		MaxAttempts: 3,
+		BackoffBase: 10 * time.Second,
+		BackoffMax:  time.Hour,
+	}
+	d := NewDispatcher(store.Wrap(sqlDB, time.Second), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rows := sqlmock.NewRows([]string{"delivery_id", "event_id", "event_type", "payload", "attempts", "url", "secret"}).
+		AddRow(1, "evt_1", models.EventPRMerged, payload, 0, server.URL+"/ok", secret).
+		AddRow(2, "evt_1", models.EventPRMerged, payload, 1, server.URL+"/down", secret).
+		AddRow(3, "evt_1", models.EventPRMerged, payload, 0, server.URL+"/removed", secret).
+		AddRow(4, "evt_1", models.EventPRMerged, payload, 2, server.URL+"/down", secret)
+	// аренда - таймаут попытки и запас
+	mock.ExpectQuery("UPDATE webhook_deliveries d").
+		WithArgs(10, (cfg.Timeout + 30*time.Second).Milliseconds()).
+		WillReturnRows(rows)
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectExec("SET status = 'delivered'").WithArgs(1, http.StatusOK).WillReturnResult(sqlmock.NewResult(0, 1))
+	// вторая попытка из трёх: повтор через 20s
+	mock.ExpectExec("UPDATE webhook_deliveries\\s+SET status = CASE").
+		WithArgs(2, http.StatusBadGateway, "receiver returned 502 Bad Gateway: upstream unavailable", retryWithin(20*time.Second)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// 4xx не повторяется
+	mock.ExpectExec("UPDATE webhook_deliveries\\s+SET status = CASE").
+		WithArgs(3, http.StatusNotFound, sqlmock.AnyArg(), (*time.Time)(nil)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// попытки исчерпаны
+	mock.ExpectExec("UPDATE webhook_deliveries\\s+SET status = CASE").
+		WithArgs(4, http.StatusBadGateway, sqlmock.AnyArg(), (*time.Time)(nil)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	d.Drain(context.Background())
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	h := headers["/ok"]
+	if h == nil {
+		t.Fatal("delivery 1 was not sent")
+	}
+	timestamp, err := strconv.ParseInt(h.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("%s = %q: %v", HeaderTimestamp, h.Get(HeaderTimestamp), err)
+	}
+	if got, want := h.Get(HeaderSignature), Sign(secret, timestamp, payload); got != want {
+		t.Errorf("%s = %q, want %q", HeaderSignature, got, want)
+	}
+	if h.Get(HeaderEvent) != models.EventPRMerged || h.Get(HeaderEventID) != "evt_1" || h.Get(HeaderDelivery) != "1" {
+		t.Errorf("headers = %v, want event, event id and delivery id", h)
+	}
+}
+
+// retryWithin проверяет, что повтор назначен через delay от текущего момента
+type retryWithin time.Duration
+
+func (r retryWithin) Match(v driver.Value) bool {
+	at, ok := v.(time.Time)
+	if !ok {
+		return false
+	}
+	delay := time.Until(at)
+	return delay > time.Duration(r)-time.Minute && delay <= time.Duration(r)
+}
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_pr_reviewers ON pull_requests USING GIN (assigned_reviewers);
+CREATE INDEX IF NOT EXISTS idx_pr_created ON pull_requests(created_at, pull_request_id);
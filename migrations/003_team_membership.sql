@@ -0,0 +1,2 @@
+-- пользователь, удалённый из команды, остаётся в системе (на него ссылаются PR), но без команды
+ALTER TABLE users ALTER COLUMN team_name DROP NOT NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS required_reviewers INTEGER NOT NULL DEFAULT 2;
+
+-- переименование команды каскадно обновляет пользователей, удаление по-прежнему каскадно удаляет их
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_team_name_fkey;
+ALTER TABLE users ADD CONSTRAINT users_team_name_fkey
+    FOREIGN KEY (team_name) REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS parent_team VARCHAR(255)
+    REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE RESTRICT;
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS reviewer_fallback BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_team_parent ON teams(parent_team);
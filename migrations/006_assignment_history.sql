@@ -0,0 +1,21 @@
+-- история назначений ревьюверов: строка на каждое назначение, закрывается при замене или снятии
+CREATE TABLE IF NOT EXISTS review_assignments (
+    id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(255) NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    reviewer_id VARCHAR(255) NOT NULL,
+    assigned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reassigned_from VARCHAR(255),
+    unassigned_at TIMESTAMP WITH TIME ZONE,
+    replaced_by VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_assignment_reviewer ON review_assignments(reviewer_id, assigned_at);
+CREATE INDEX IF NOT EXISTS idx_assignment_pr ON review_assignments(pull_request_id);
+
+-- текущие назначения PR, созданных до появления истории
+INSERT INTO review_assignments (pull_request_id, reviewer_id, assigned_at)
+SELECT pr.pull_request_id, reviewer.id, pr.created_at
+FROM pull_requests pr, unnest(pr.assigned_reviewers) AS reviewer(id)
+WHERE NOT EXISTS (
+    SELECT 1 FROM review_assignments ra WHERE ra.pull_request_id = pr.pull_request_id
+);
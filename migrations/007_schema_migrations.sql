@@ -0,0 +1,8 @@
+-- версия схемы проверяется в /ready; каждая следующая миграция добавляет сюда свой номер
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO schema_migrations (version) VALUES (1), (2), (3), (4), (5), (6), (7)
+ON CONFLICT (version) DO NOTHING;
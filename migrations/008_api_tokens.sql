@@ -0,0 +1,15 @@
+-- токены API; в базе хранится только SHA-256 от токена, сам токен показывается один раз при создании
+CREATE TABLE IF NOT EXISTS api_tokens (
+    token_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('admin', 'user', 'service')),
+    user_id TEXT REFERENCES users(user_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    CHECK ((role = 'user') = (user_id IS NOT NULL))
+);
+
+INSERT INTO schema_migrations (version) VALUES (8)
+ON CONFLICT (version) DO NOTHING;
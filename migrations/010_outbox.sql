@@ -0,0 +1,23 @@
+-- outbox доменных событий: строка пишется в одной транзакции с изменением и
+-- живёт до публикации во все приёмники (published) или до исчерпания попыток (dead)
+CREATE TABLE IF NOT EXISTS outbox_events (
+    seq BIGSERIAL PRIMARY KEY,
+    event_id TEXT NOT NULL UNIQUE,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'published', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    published_sinks TEXT[] NOT NULL DEFAULT '{}',
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_due
+    ON outbox_events (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_events_status
+    ON outbox_events (status, seq);
+
+INSERT INTO schema_migrations (version) VALUES (10)
+ON CONFLICT (version) DO NOTHING;
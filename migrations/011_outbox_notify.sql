@@ -0,0 +1,16 @@
+-- каждая новая запись outbox будит слушателей всех реплик (LISTEN outbox_events);
+-- NOTIFY доставляется при коммите, payload - seq записи, само событие читается из таблицы
+CREATE OR REPLACE FUNCTION notify_outbox_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox_events', NEW.seq::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS outbox_events_notify ON outbox_events;
+CREATE TRIGGER outbox_events_notify
+    AFTER INSERT ON outbox_events
+    FOR EACH ROW EXECUTE FUNCTION notify_outbox_event();
+
+INSERT INTO schema_migrations (version) VALUES (11)
+ON CONFLICT (version) DO NOTHING;
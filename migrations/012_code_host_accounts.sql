@@ -0,0 +1,14 @@
+-- учётные записи на хостингах кода (GitHub, GitLab), связанные с пользователями сервиса;
+-- по ним вебхуки хостинга находят автора PR. Логины хранятся в нижнем регистре.
+CREATE TABLE IF NOT EXISTS code_host_accounts (
+    provider TEXT NOT NULL,
+    login TEXT NOT NULL,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, login)
+);
+
+CREATE INDEX IF NOT EXISTS idx_code_host_accounts_user ON code_host_accounts (user_id);
+
+INSERT INTO schema_migrations (version) VALUES (12)
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,47 @@
+-- каналы чат-уведомлений: incoming webhook Slack или Mattermost команды или пользователя;
+-- пустой kinds означает все виды уведомлений
+CREATE TABLE IF NOT EXISTS notification_channels (
+    channel_id TEXT PRIMARY KEY,
+    team_name VARCHAR(255) REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    user_id VARCHAR(255) REFERENCES users(user_id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    format TEXT NOT NULL CHECK (format IN ('slack', 'mattermost')),
+    kinds TEXT[] NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((team_name IS NULL) <> (user_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_channels_team ON notification_channels (team_name);
+CREATE INDEX IF NOT EXISTS idx_notification_channels_user ON notification_channels (user_id);
+
+-- очередь и журнал сообщений, как webhook_deliveries; payload - готовое тело запроса в чат
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    delivery_id BIGSERIAL PRIMARY KEY,
+    channel_id TEXT NOT NULL REFERENCES notification_channels(channel_id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL,
+    recipient_id VARCHAR(255) NOT NULL,
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    last_status_code INTEGER,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (channel_id, event_id, recipient_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_due
+    ON notification_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_channel
+    ON notification_deliveries (channel_id, delivery_id);
+
+-- о просроченном назначении сообщается один раз: отметка ставится в одной транзакции с событием.
+-- Поиск идёт от открытых PR (idx_pr_status) к их назначениям (idx_assignment_pr).
+ALTER TABLE review_assignments ADD COLUMN IF NOT EXISTS overdue_notified_at TIMESTAMP WITH TIME ZONE;
+
+INSERT INTO schema_migrations (version) VALUES (13)
+ON CONFLICT (version) DO NOTHING;
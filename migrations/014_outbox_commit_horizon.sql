@@ -0,0 +1,12 @@
+-- seq выдаётся при вставке, а видна запись становится при коммите, поэтому запись с меньшим seq
+-- может закоммититься позже записи с большим. txid - транзакция, записавшая событие, horizon -
+-- самая старая транзакция, ещё не завершённая на момент вставки: все транзакции младше horizon
+-- к этому моменту закоммичены, и их события уже разосланы. Поток событий ревьюера по seq
+-- из Last-Event-ID перечитывает события с txid >= horizon этой записи.
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS txid xid8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS horizon xid8 NOT NULL DEFAULT pg_snapshot_xmin(pg_current_snapshot());
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_txid ON outbox_events (txid);
+
+INSERT INTO schema_migrations (version) VALUES (14)
+ON CONFLICT (version) DO NOTHING;
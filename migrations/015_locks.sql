@@ -0,0 +1,11 @@
+-- блокировки работы, которая идёт вне транзакций (запросы к API хостингов кода), между репликами.
+-- Блокировка берётся и снимается короткими транзакциями и истекает сама, если реплика упала,
+-- не сняв её; holder не даёт снять блокировку, которую после истечения взял другой.
+CREATE TABLE IF NOT EXISTS locks (
+    lock_key TEXT PRIMARY KEY,
+    holder TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+INSERT INTO schema_migrations (version) VALUES (15)
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,9 @@
+// Package internshiptask отдаёт коду артефакты из корня репозитория.
+package internshiptask
+
+import _ "embed"
+
+// OpenAPISpec - спецификация API, по которой валидируются входящие запросы
+//
+//go:embed openapi.yml
+var OpenAPISpec []byte